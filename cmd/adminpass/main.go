@@ -0,0 +1,48 @@
+// Command adminpass prints the line that lists an administrator or
+// reviewer in the files cmd/server reads with -slack-admins,
+// -scholarship-reviewers and -cfp-reviewers.
+//
+// Usage:
+//
+//	adminpass name < password
+//
+// It reads the password from the first line of standard input and
+// prints the name and a salted hash of the password.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: adminpass name < password\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 || strings.ContainsAny(flag.Arg(0), " \t") {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && password == "" {
+		log.Fatal("adminpass: no password on standard input")
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		log.Fatal("adminpass: empty password")
+	}
+	hash, err := adminauth.Hash(password)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%s %s\n", flag.Arg(0), hash)
+}
@@ -0,0 +1,111 @@
+// Command archive keeps WARC copies of the external pages articles link
+// to, in a directory outside the repository.
+//
+// Usage:
+//
+//	archive [-dir dir] fetch [file ...]   archive links not archived yet
+//	archive [-dir dir] check              mark archived links that are gone
+//	archive [-dir dir] list               print every capture
+//
+// fetch works on every published article without files; run it when an
+// article is published. Each link is fetched only once. check requests
+// every archived link again; article pages offer the archived copy next
+// to the links it marks dead, served by cmd/server -archive.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/archive"
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+func main() {
+	dir := flag.String("dir", "/var/lib/gopheracademy/archive", "archive directory")
+	root := flag.String("root", ".", "repository root")
+	host := flag.String("host", "blog.gopheracademy.com", "site host, whose links are not archived")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: archive [flags] fetch [file ...]|check|list\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := archive.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch flag.Arg(0) {
+	case "fetch":
+		lib, err := content.Load(*root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		articles := lib.Published()
+		if flag.NArg() > 1 {
+			articles = nil
+			for _, file := range flag.Args()[1:] {
+				art, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+				if !ok {
+					log.Fatalf("%s: not an article", file)
+				}
+				articles = append(articles, art)
+			}
+		}
+		failed := 0
+		for _, art := range articles {
+			for _, link := range content.Links(art.Body()) {
+				if u, err := url.Parse(link); err != nil || strings.EqualFold(u.Hostname(), *host) || a.Has(link) {
+					continue
+				}
+				if err := a.Fetch(link, art.File); err != nil {
+					fmt.Printf("%s: %v\n", art.File, err)
+					failed++
+					continue
+				}
+				fmt.Printf("archived %s\n", link)
+			}
+			if err := a.Save(); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if failed > 0 {
+			fmt.Printf("%d links could not be archived; they are tried again next time\n", failed)
+		}
+	case "check":
+		died, revived, failing := a.Check()
+		for _, u := range died {
+			fmt.Printf("dead    %s\n", u)
+		}
+		for _, u := range revived {
+			fmt.Printf("revived %s\n", u)
+		}
+		for _, u := range failing {
+			fmt.Printf("failing %s (%d of %d)\n", u, a.Captures[u].Failures, archive.FailLimit)
+		}
+		if err := a.Save(); err != nil {
+			log.Fatal(err)
+		}
+	case "list":
+		for _, u := range a.URLs() {
+			c := a.Captures[u]
+			state := ""
+			if c.Dead {
+				state = " (dead)"
+			}
+			fmt.Printf("%s\t%s\t%s%s\n", c.Date.Format("2006-01-02"), c.Article, u, state)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,80 @@
+// Command build builds the sites declared in sites.toml: it runs Hugo
+// with each site's config, then generates what Hugo does not, the job
+// board of the site that sets jobs = true, into that site's publish
+// directory.
+//
+// Usage:
+//
+//	build [-hugo hugo] [-root .] [site ...]
+//
+// Without names it builds every site. Expired job postings drop off the
+// board at each build; rebuild the main site daily to take them down on
+// time.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/jobs"
+	"github.com/gopheracademy/gopheracademy-web/internal/sites"
+)
+
+func main() {
+	hugo := flag.String("hugo", "hugo", "hugo binary")
+	root := flag.String("root", ".", "repository root")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: build [flags] [site ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	m, err := sites.Load(filepath.Join(*root, sites.ManifestFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	known := make(map[string]bool)
+	for _, s := range m.Sites {
+		known[s.Name] = true
+	}
+	only := make(map[string]bool)
+	for _, name := range flag.Args() {
+		if !known[name] {
+			log.Fatalf("%s: no such site in %s", name, sites.ManifestFile)
+		}
+		only[name] = true
+	}
+	for _, s := range m.Sites {
+		if len(only) > 0 && !only[s.Name] {
+			continue
+		}
+		cmd := exec.Command(*hugo, "--config="+s.Output)
+		cmd.Dir = *root
+		cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Fatalf("building %s: %v", s.Name, err)
+		}
+		if !s.Jobs {
+			continue
+		}
+		c := m.Resolve(s)
+		out := c.PublishDir
+		if out == "" {
+			out = "public"
+		}
+		n, problems, err := jobs.Publish(*root, filepath.Join(*root, out), c.BaseURL, time.Now())
+		for _, p := range problems {
+			log.Printf("leaving out %s", strings.TrimPrefix(p.String(), *root+"/"))
+		}
+		if err != nil {
+			log.Fatalf("building the job board of %s: %v", s.Name, err)
+		}
+		fmt.Printf("%s: %d jobs\n", filepath.Join(*root, out, "jobs"), n)
+	}
+}
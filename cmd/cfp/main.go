@@ -0,0 +1,142 @@
+// Command cfp ranks the talk proposals taken by cmd/server and exports
+// the accepted talks to the conference schedule.
+//
+// Usage:
+//
+//	cfp [-root .] [-dir dir] rank                        print the ranking as CSV
+//	cfp [-root .] [-dir dir] decide proposal outcome ...  record decisions
+//	cfp [-root .] [-dir dir] export                      update data/schedule.toml
+//
+// rank orders the submitted proposals by their mean score, leaving out
+// the reviews flagged as conflicts of interest; with -anonymous it
+// leaves out the speakers. decide takes pairs of a proposal, by id or
+// its first six or more characters, and an outcome: accepted or
+// rejected. export adds the accepted talks to the schedule, updates
+// those already there while keeping their slots, and removes the talks
+// of proposals no longer accepted.
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/cfp"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	dir := flag.String("dir", "/var/lib/gopheracademy/cfp", "proposal directory")
+	anonymous := flag.Bool("anonymous", false, "rank: leave out the speakers")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: cfp [flags] rank|decide proposal outcome ...|export\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := cfp.LoadConfig(filepath.Join(*root, cfp.ConfigFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := cfp.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "rank":
+		ranked, err := cfp.Rank(store)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := csv.NewWriter(os.Stdout)
+		header := []string{"rank", "id", "title", "level", "score", "reviews", "conflicts", "decision"}
+		if !*anonymous {
+			header = append(header, "speakers", "owner")
+		}
+		w.Write(header)
+		for i, r := range ranked {
+			rank, score := strconv.Itoa(i+1), strconv.FormatFloat(r.Score, 'f', 2, 64)
+			if r.Reviews == 0 {
+				rank, score = "", ""
+			}
+			row := []string{rank, r.ID, r.Title, r.Level, score, strconv.Itoa(r.Reviews), strconv.Itoa(r.Conflicts), r.Decision.Outcome}
+			if !*anonymous {
+				row = append(row, r.SpeakerNames(), r.Owner)
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Fatal(err)
+		}
+	case "decide":
+		if len(args) == 0 || len(args)%2 != 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		for i := 0; i < len(args); i += 2 {
+			p, err := store.Find(args[i])
+			if err != nil {
+				log.Fatal(err)
+			}
+			outcome := args[i+1]
+			if outcome != cfp.Accepted && outcome != cfp.Rejected {
+				log.Fatalf("%s: outcome must be %s or %s", args[i], cfp.Accepted, cfp.Rejected)
+			}
+			if p.State != cfp.Submitted {
+				log.Fatalf("%s: %q is %s, not submitted", p.ID, p.Title, p.State)
+			}
+			if err := store.SaveDecision(p.ID, cfp.Decision{Outcome: outcome, Decided: time.Now()}); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s %q: %s\n", p.ID, p.Title, outcome)
+		}
+	case "export":
+		all, err := store.Proposals()
+		if err != nil {
+			log.Fatal(err)
+		}
+		var accepted []cfp.Proposal
+		for _, p := range all {
+			d, err := store.Decision(p.ID)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if d.Outcome == cfp.Accepted && p.State == cfp.Submitted {
+				accepted = append(accepted, p)
+			}
+		}
+		file := filepath.Join(*root, cfp.ScheduleFile)
+		sched, err := cfp.LoadSchedule(file)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if sched.Conference == "" {
+			sched.Conference = config.Conference
+		}
+		added, removed := sched.Merge(accepted, all)
+		if err := sched.Save(file); err != nil {
+			log.Fatal(err)
+		}
+		for _, t := range added {
+			fmt.Printf("added %q\n", t)
+		}
+		for _, t := range removed {
+			fmt.Printf("removed %q\n", t)
+		}
+		fmt.Printf("%s: %d talks\n", cfp.ScheduleFile, len(sched.Talks))
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,55 @@
+// Command components finds markdown that imitates the site's content
+// components, such as blockquotes opening with "Note:" or italic image
+// captions, and suggests the callout, figure, aside or footnote to use
+// instead.
+//
+// Usage:
+//
+//	components [file ...]
+//
+// Without files it checks every article, including upcoming ones. It
+// exits with status 1 if any are found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/components"
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 0 {
+		articles = nil
+		for _, file := range flag.Args() {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	total := 0
+	for _, a := range articles {
+		for _, f := range components.LintArticle(a) {
+			fmt.Println(f)
+			total++
+		}
+	}
+	if total > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,107 @@
+// Command deploy publishes a built site as an immutable release and
+// switches the live release atomically.
+//
+// Usage:
+//
+//	deploy [flags] push [dir]        upload dir (default public) and make it live
+//	deploy [flags] rollback          make the previous release live again
+//	deploy [flags] list              list the kept releases
+//	deploy [flags] checkout dir      materialize the live release under dir/current
+//
+// Releases are kept in a local directory (-local) or in an S3-compatible
+// bucket (-s3 endpoint/bucket, credentials from AWS_ACCESS_KEY_ID and
+// AWS_SECRET_ACCESS_KEY).
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/deploy"
+)
+
+func main() {
+	local := flag.String("local", "", "release store directory")
+	s3 := flag.String("s3", "", "release store bucket, as endpoint/bucket")
+	region := flag.String("region", "us-east-1", "S3 region")
+	keep := flag.Int("keep", 5, "number of previous releases to keep for rollback")
+	verbose := flag.Bool("v", false, "log every uploaded file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: deploy [flags] push [dir] | rollback | list | checkout dir\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	var store deploy.Store
+	switch {
+	case *local != "" && *s3 == "":
+		store = deploy.LocalStore{Dir: *local}
+	case *s3 != "" && *local == "":
+		i := strings.LastIndex(*s3, "/")
+		if i < 0 {
+			log.Fatalf("-s3 must be endpoint/bucket")
+		}
+		store = &deploy.S3Store{
+			Endpoint:  (*s3)[:i],
+			Bucket:    (*s3)[i+1:],
+			Region:    *region,
+			AccessKey: os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		}
+	default:
+		log.Fatal("exactly one of -local and -s3 is required")
+	}
+	d := &deploy.Deployer{Store: store, Keep: *keep}
+	if *verbose {
+		d.Log = log.Printf
+	}
+
+	switch flag.Arg(0) {
+	case "push":
+		dir := "public"
+		if flag.NArg() > 1 {
+			dir = flag.Arg(1)
+		}
+		r, c, err := d.Push(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("release %s live: %d added, %d changed, %d removed\n",
+			r.ID, len(c.Added), len(c.Changed), len(c.Removed))
+	case "rollback":
+		r, err := d.Rollback()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("release %s live, deployed %s\n", r.ID, r.Deployed.Format("2006-01-02 15:04:05"))
+	case "list":
+		h, err := d.History()
+		if err != nil {
+			log.Fatal(err)
+		}
+		live, _ := d.Live()
+		for i := len(h) - 1; i >= 0; i-- {
+			mark := " "
+			if h[i].ID == live {
+				mark = "*"
+			}
+			fmt.Printf("%s %s %s\n", mark, h[i].ID, h[i].Deployed.Format("2006-01-02 15:04:05"))
+		}
+	case "checkout":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		id, err := d.Checkout(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("release %s checked out\n", id)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
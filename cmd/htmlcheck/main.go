@@ -0,0 +1,49 @@
+// Command htmlcheck reports markup errors in every page of a rendered
+// site.
+//
+// Usage:
+//
+//	htmlcheck [-max n] [dir]
+//
+// dir defaults to public. It exits with status 1 when more than n
+// problems are found, so CI can hold the line while old pages are fixed
+// and lower the threshold as they are. n defaults to
+// htmlcheck.Threshold, which the package's tests hold the rendered
+// fixtures to.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/htmlcheck"
+)
+
+func main() {
+	max := flag.Int("max", htmlcheck.Threshold, "number of problems tolerated before failing")
+	quiet := flag.Bool("q", false, "print only the summary")
+	flag.Parse()
+	dir := "public"
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	problems, n, err := htmlcheck.CheckSite(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	pages := make(map[string]bool)
+	for _, p := range problems {
+		pages[p.File] = true
+		if !*quiet {
+			fmt.Println(p)
+		}
+	}
+	total := len(problems)
+	fmt.Printf("%d problems in %d of %d pages (threshold %d)\n", total, len(pages), n, *max)
+	if total > *max {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,71 @@
+// Command htmlpolicy checks the raw HTML in article bodies against the
+// site's policy: a safe set of elements and attributes, no third-party
+// scripts and no inline event handlers unless the article allowlists
+// them in its front matter.
+//
+// Usage:
+//
+//	htmlpolicy [-fix] [file ...]
+//
+// Without files it checks every article, including upcoming ones. It
+// exits with status 1 if any violations are found. -fix removes what the
+// policy does not accept and rewrites the files.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/htmlpolicy"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	fix := flag.Bool("fix", false, "remove disallowed HTML from the files")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 0 {
+		articles = nil
+		for _, file := range flag.Args() {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	total := 0
+	for _, a := range articles {
+		violations := htmlpolicy.CheckArticle(a)
+		if len(violations) == 0 {
+			continue
+		}
+		total += len(violations)
+		for _, v := range violations {
+			fmt.Println(v)
+		}
+		if *fix {
+			a.Meta.SetBody(htmlpolicy.Sanitize(a.Body(), htmlpolicy.ArticleAllow(a)))
+			if err := a.Save(*root); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+	if total > 0 {
+		if *fix {
+			fmt.Printf("%d violations removed\n", total)
+			return
+		}
+		os.Exit(1)
+	}
+}
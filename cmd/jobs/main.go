@@ -0,0 +1,89 @@
+// Command jobs checks the job postings in data/jobs and generates the
+// main site's job board from them.
+//
+// Usage:
+//
+//	jobs [-root .] check [file ...]
+//	jobs [-root .] [-config config-main.toml] [-out dir] build
+//
+// check reports malformed and expired postings, of every file or of the
+// files given, and exits 1 if there are any. build runs after Hugo has
+// built the main site and writes the board under /jobs/ in its publish
+// directory, leaving out the postings check would reject, so expired
+// postings drop off at the next build. cmd/build runs it along with
+// Hugo; build on its own regenerates the board of a built site.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/jobs"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	config := flag.String("config", "config-main.toml", "Hugo config of the main site")
+	out := flag.String("out", "", "publish directory (default the config's)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: jobs [flags] check [file ...]|build\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	now := time.Now()
+	switch flag.Arg(0) {
+	case "check":
+		dir := filepath.Join(*root, jobs.Dir)
+		all, problems, err := jobs.Load(dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, j := range all {
+			problems = append(problems, jobs.Check(dir, j, now)...)
+		}
+		only := make(map[string]bool)
+		for _, f := range flag.Args()[1:] {
+			only[filepath.ToSlash(filepath.Join(*root, jobs.Dir, filepath.Base(f)))] = true
+		}
+		n := 0
+		for _, p := range problems {
+			if len(only) == 0 || only[p.File] {
+				fmt.Println(p)
+				n++
+			}
+		}
+		if n > 0 {
+			os.Exit(1)
+		}
+	case "build":
+		c, err := content.ReadConfig(filepath.Join(*root, *config))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *out == "" {
+			*out = filepath.Join(*root, c.PublishDir)
+		}
+		n, problems, err := jobs.Publish(*root, *out, c.BaseURL, now)
+		for _, p := range problems {
+			log.Printf("leaving out %s", strings.TrimPrefix(p.String(), *root+"/"))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: %d jobs\n", filepath.Join(*out, "jobs"), n)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
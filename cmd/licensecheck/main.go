@@ -0,0 +1,65 @@
+// Command licensecheck checks the licensing metadata of the site: the
+// default licenses in the site configs, the license front matter of
+// articles, and the record in data/images.toml of where the images
+// under static/postimages come from.
+//
+// Usage:
+//
+//	licensecheck [-root .]
+//
+// It exits with status 1 if any problems are found, and notes how many
+// images still have no recorded provenance.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/license"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	bad := 0
+	for _, c := range lib.Configs {
+		if _, err := license.Defaults(c); err != nil {
+			fmt.Println(err)
+			bad++
+		}
+	}
+	var problems []license.Problem
+	for _, a := range lib.Articles {
+		_, ps := license.Of(a, license.Terms{})
+		problems = append(problems, ps...)
+	}
+
+	images, err := license.LoadImages(filepath.Join(*root, license.ImagesFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ps, unrecorded, err := license.CheckImages(*root, images)
+	if err != nil {
+		log.Fatal(err)
+	}
+	problems = append(problems, ps...)
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if unrecorded > 0 {
+		fmt.Fprintf(os.Stderr, "note: %d images in %s have no recorded provenance\n", unrecorded, license.ImageDir)
+	}
+	if bad+len(problems) > 0 {
+		os.Exit(1)
+	}
+}
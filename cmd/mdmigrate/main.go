@@ -0,0 +1,112 @@
+// Command mdmigrate renders every article with Blackfriday, the markdown
+// engine the content was written for, and with Goldmark, and reports
+// the articles that render differently, by kind of difference.
+//
+// Usage:
+//
+//	mdmigrate [-v] [-unsafe] [-class name] [file ...]
+//
+// -v prints each difference with the old and new HTML. -unsafe renders
+// raw HTML with Goldmark, as Hugo does with
+// markup.goldmark.renderer.unsafe set. -class limits the report to one
+// class, such as table or "raw html".
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/mdmigrate"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	verbose := flag.Bool("v", false, "print every difference")
+	unsafe := flag.Bool("unsafe", false, "let Goldmark pass raw HTML through")
+	class := flag.String("class", "", "report only differences of this class")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 0 {
+		articles = nil
+		for _, file := range flag.Args() {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	render := mdmigrate.New(*unsafe)
+	total := make(map[string]int)
+	changed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, a := range articles {
+		body := a.Body()
+		newHTML, err := render(body)
+		if err != nil {
+			log.Fatalf("%s: %v", a.File, err)
+		}
+		hunks, err := mdmigrate.Compare(mdmigrate.Old(body), newHTML)
+		if err != nil {
+			log.Fatalf("%s: %v", a.File, err)
+		}
+		if *class != "" {
+			var kept []mdmigrate.Hunk
+			for _, h := range hunks {
+				if h.Class == *class {
+					kept = append(kept, h)
+				}
+			}
+			hunks = kept
+		}
+		if len(hunks) == 0 {
+			continue
+		}
+		changed++
+		counts := mdmigrate.Summary(hunks)
+		var parts []string
+		for _, c := range mdmigrate.Classes(counts) {
+			total[c] += counts[c]
+			parts = append(parts, fmt.Sprintf("%s %d", c, counts[c]))
+		}
+		fmt.Fprintf(w, "%s\t%s\n", a.File, strings.Join(parts, ", "))
+		if *verbose {
+			w.Flush()
+			for _, h := range hunks {
+				fmt.Printf("  [%s]\n  - %s\n  + %s\n", h.Class, clip(h.Old), clip(h.New))
+			}
+		}
+	}
+	w.Flush()
+
+	var parts []string
+	for _, c := range mdmigrate.Classes(total) {
+		parts = append(parts, fmt.Sprintf("%s %d", c, total[c]))
+	}
+	fmt.Printf("%d of %d articles render differently", changed, len(articles))
+	if len(parts) > 0 {
+		fmt.Printf(": %s", strings.Join(parts, ", "))
+	}
+	fmt.Println()
+}
+
+// clip shortens s for display.
+func clip(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if len(s) > 300 {
+		return s[:300] + "…"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+// Command media fills in the media directives of articles from the
+// providers' oEmbed endpoints, caching responses in data/oembed and
+// thumbnails in static/media.
+//
+// Usage:
+//
+//	media [-offline] [file ...]
+//
+// Without files it works on every article. With -offline only cached
+// responses are used; directives without one keep rendering as plain
+// links, and are listed.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/media"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	offline := flag.Bool("offline", false, "use cached responses only")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 0 {
+		articles = nil
+		for _, file := range flag.Args() {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	f := &media.Fetcher{
+		Fixtures: filepath.Join(*root, "data", "oembed"),
+		Static:   filepath.Join(*root, "static"),
+		Offline:  *offline,
+	}
+	for _, a := range articles {
+		body, missing, err := media.Sync(a.Body(), f)
+		if err != nil {
+			log.Fatalf("%s: %v", a.File, err)
+		}
+		for _, u := range missing {
+			fmt.Printf("%s: nothing cached for %s, rendered as a link\n", a.File, u)
+		}
+		if bytes.Equal(body, a.Body()) {
+			continue
+		}
+		a.Meta.SetBody(body)
+		if err := a.Save(*root); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("updated %s\n", a.File)
+	}
+}
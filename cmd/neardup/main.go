@@ -0,0 +1,135 @@
+// Command neardup finds near-duplicate articles: articles in the
+// repository that copy each other, and submissions that copy posts
+// published elsewhere.
+//
+// Usage:
+//
+//	neardup [-root .] [-threshold 0.5] repo
+//	neardup [-corpus dir] fetch url ...
+//	neardup [-root .] [-corpus dir] [-threshold 0.5] upcoming [file ...]
+//
+// repo compares every pair of articles, published, draft and upcoming,
+// leaving out articles and their translations. fetch adds external
+// posts, such as the earlier posts of a submission's author on their
+// own blog, to the corpus, a web archive directory outside the
+// repository; HTML, markdown and text files saved in it by hand are
+// read too, and -corpus can point at cmd/archive's directory to compare
+// against the pages articles link to. upcoming compares the submissions
+// in upcoming/, or the files given, with the corpus.
+//
+// Pairs whose prose or code similarity reaches the threshold are
+// printed with both, and neardup then exits with status 1.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/archive"
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/neardup"
+	"github.com/gopheracademy/gopheracademy-web/internal/translations"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	corpus := flag.String("corpus", "/var/lib/gopheracademy/corpus", "directory of external posts")
+	threshold := flag.Float64("threshold", 0.5, "least prose or code similarity reported, from 0 to 1")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: neardup [flags] repo|fetch url ...|upcoming [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var matches []neardup.Match
+	switch flag.Arg(0) {
+	case "repo":
+		lib, err := content.Load(*root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var docs []*neardup.Doc
+		keys := make(map[*neardup.Doc]string)
+		for _, a := range lib.Articles {
+			d := neardup.FromArticle(a)
+			docs = append(docs, d)
+			keys[d] = a.Meta.String(translations.KeyParam)
+		}
+		matches = neardup.Pairs(docs, *threshold, func(a, b *neardup.Doc) bool {
+			return keys[a] != "" && keys[a] == keys[b]
+		})
+	case "fetch":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		a, err := archive.Open(*corpus)
+		if err != nil {
+			log.Fatal(err)
+		}
+		failed := 0
+		for _, u := range flag.Args()[1:] {
+			if a.Has(u) {
+				continue
+			}
+			if err := a.Fetch(u, "corpus"); err != nil {
+				fmt.Println(err)
+				failed++
+				continue
+			}
+			fmt.Printf("fetched %s\n", u)
+		}
+		if err := a.Save(); err != nil {
+			log.Fatal(err)
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+		return
+	case "upcoming":
+		lib, err := content.Load(*root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		articles := lib.Upcoming()
+		if flag.NArg() > 1 {
+			articles = nil
+			for _, file := range flag.Args()[1:] {
+				a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+				if !ok {
+					log.Fatalf("%s: not an article", file)
+				}
+				articles = append(articles, a)
+			}
+		}
+		var docs []*neardup.Doc
+		for _, a := range articles {
+			docs = append(docs, neardup.FromArticle(a))
+		}
+		external, err := neardup.LoadCorpus(*corpus)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(external) == 0 {
+			log.Printf("%s: the corpus is empty; add posts with neardup fetch", *corpus)
+		}
+		matches = neardup.Against(docs, external, *threshold)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, m := range matches {
+		fmt.Printf("%s\t%s\tprose %.2f\tcode %.2f\n", m.A.Name, m.B.Name, m.Prose, m.Code)
+	}
+	if len(matches) > 0 {
+		os.Exit(1)
+	}
+}
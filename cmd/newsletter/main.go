@@ -0,0 +1,252 @@
+// Command newsletter builds email digests of new articles and mails them
+// to subscribers.
+//
+// Usage:
+//
+//	newsletter [-series name] [-since date] issue     build an issue
+//	newsletter [-smtp host:port] [-to file] send id   mail an issue
+//	newsletter [-subscribers dir] send id             mail an issue to subscribers
+//	newsletter bounces                                list bouncing addresses
+//
+// issue collects the articles published since the previous issue of the
+// same scope (every article, or the series given with -series), renders
+// them, archives the issue under static/newsletter/<id>/ and records it
+// in data/newsletter.toml; commit both. Without a previous issue, it
+// covers the last week unless -since is given.
+//
+// send mails an archived issue to the confirmed subscribers of its scope
+// kept in the -subscribers directory by cmd/server, each message with
+// its own unsubscribe link signed with the NEWSLETTER_SECRET environment
+// variable, or else to the addresses in the -to file, one per line.
+// Messages go out in batches of -batch messages with -pause between batches.
+// Addresses the server refuses are recorded as bounces in the -state
+// directory; an address refused permanently, or temporarily three times
+// in a row, is not mailed again. send can be run again after a failure:
+// addresses that have the issue are skipped. The SMTP_USER and
+// SMTP_PASSWORD environment variables, if set, authenticate to the
+// server.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/newsletter"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	series := flag.String("series", "", "build an issue of this series only")
+	since := flag.String("since", "", "cover articles published after this date (2006-01-02) instead of since the last issue")
+	subject := flag.String("subject", "", "issue subject")
+	stateDir := flag.String("state", "/var/lib/gopheracademy/newsletter", "delivery state directory")
+	server := flag.String("smtp", "localhost:25", "SMTP server")
+	from := flag.String("from", "Gopher Academy <newsletter@gopheracademy.com>", "sender")
+	to := flag.String("to", "", "file of recipient addresses, one per line")
+	subscribers := flag.String("subscribers", "", "subscriber directory kept by cmd/server")
+	batch := flag.Int("batch", 50, "messages per SMTP connection")
+	pause := flag.Duration("pause", 10*time.Second, "pause between batches")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: newsletter [flags] issue|send id|bounces\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	logFile := filepath.Join(*root, filepath.FromSlash(newsletter.LogFile))
+	issues, err := newsletter.LoadLog(logFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch flag.Arg(0) {
+	case "issue":
+		lib, err := content.Load(*root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		now := time.Now().UTC()
+		start := issues.Last(*series)
+		if *since != "" {
+			if start, err = time.Parse("2006-01-02", *since); err != nil {
+				log.Fatalf("bad -since: %v", err)
+			}
+		} else if start.IsZero() {
+			start = now.AddDate(0, 0, -7)
+		}
+		articles := newsletter.Select(lib, *series, start, now)
+		if len(articles) == 0 {
+			fmt.Printf("no articles published since %s\n", start.Format("2006-01-02"))
+			return
+		}
+		is := newsletter.Issue{
+			ID:      newsletter.NewID(*series, now),
+			Date:    now,
+			Series:  *series,
+			Subject: *subject,
+		}
+		if _, ok := issues.Lookup(is.ID); ok {
+			log.Fatalf("issue %s exists already", is.ID)
+		}
+		if is.Subject == "" {
+			is.Subject = defaultSubject(*series, len(articles), now)
+		}
+		for _, a := range articles {
+			is.Articles = append(is.Articles, a.URL())
+		}
+		cfg := lib.Configs[0]
+		bodies, err := newsletter.Render(is, articles, cfg.Title, cfg.BaseURL)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := newsletter.Archive(*root, is.ID, bodies); err != nil {
+			log.Fatal(err)
+		}
+		issues.Issues = append(issues.Issues, is)
+		if err := issues.Save(logFile); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("issue %s: %d articles, archived at %s\n", is.ID, len(articles), newsletter.WebPath(is.ID))
+
+	case "send":
+		if flag.NArg() != 2 || (*to == "") == (*subscribers == "") {
+			log.Fatal("usage: newsletter -to file|-subscribers dir send id")
+		}
+		is, ok := issues.Lookup(flag.Arg(1))
+		if !ok {
+			log.Fatalf("no issue %s", flag.Arg(1))
+		}
+		bodies, err := newsletter.ReadArchive(*root, is.ID)
+		if err != nil {
+			log.Fatal(err)
+		}
+		cfg, err := content.ReadConfig(filepath.Join(*root, content.ConfigFiles[0]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		var recipients []newsletter.Recipient
+		if *subscribers != "" {
+			recipients, err = subscribed(*subscribers, is, cfg.BaseURL)
+		} else {
+			recipients, err = readRecipients(*to)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		state, err := newsletter.OpenState(*stateDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		hostname, _ := os.Hostname()
+		m := &newsletter.Mailer{
+			Addr:        *server,
+			From:        *from,
+			Batch:       *batch,
+			Pause:       *pause,
+			Hostname:    hostname,
+			Header:      map[string]string{"List-Id": "Gopher Academy newsletter <newsletter.gopheracademy.com>"},
+			Unsubscribe: strings.TrimSuffix(cfg.BaseURL, "/") + newsletter.SubscribePath,
+		}
+		if user := os.Getenv("SMTP_USER"); user != "" {
+			host, _, _ := net.SplitHostPort(*server)
+			m.Auth = smtp.PlainAuth("", user, os.Getenv("SMTP_PASSWORD"), host)
+		}
+		r, err := m.Deliver(is, bodies, recipients, state)
+		fmt.Printf("issue %s: %d sent, %d bounced, %d skipped\n", is.ID, r.Sent, r.Bounced, r.Skipped)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+	case "bounces":
+		state, err := newsletter.OpenState(*stateDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, addr := range state.Bounced() {
+			b := state.Bounces[addr]
+			kind := fmt.Sprintf("soft %d", b.Soft)
+			if b.Hard {
+				kind = "hard"
+			}
+			suppressed := ""
+			if b.Suppressed() {
+				suppressed = " (suppressed)"
+			}
+			fmt.Printf("%s\t%s\t%s\t%s%s\n", b.Last.Format("2006-01-02"), addr, kind, b.Reason, suppressed)
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+func defaultSubject(series string, n int, date time.Time) string {
+	what := "new articles"
+	if n == 1 {
+		what = "a new article"
+	}
+	if series != "" {
+		return fmt.Sprintf("%s: %s", series, what)
+	}
+	return fmt.Sprintf("Gopher Academy, %s: %s", date.Format("January 2"), what)
+}
+
+// subscribed returns the confirmed subscribers to the scope of an issue,
+// with their unsubscribe links.
+func subscribed(dir string, is newsletter.Issue, base string) ([]newsletter.Recipient, error) {
+	secret := os.Getenv("NEWSLETTER_SECRET")
+	if secret == "" {
+		return nil, fmt.Errorf("-subscribers needs NEWSLETTER_SECRET set")
+	}
+	store, err := subscribe.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	scope := subscribe.All
+	if is.Series != "" {
+		scope = subscribe.SeriesScope(content.Urlize(is.Series))
+	}
+	signer := subscribe.Signer{Key: []byte(secret)}
+	var out []newsletter.Recipient
+	for _, sub := range store.Confirmed(scope) {
+		out = append(out, newsletter.Recipient{
+			Address:     sub.Email,
+			Unsubscribe: signer.UnsubscribeURL(base, sub.Email, sub.Scope),
+		})
+	}
+	return out, nil
+}
+
+// readRecipients reads the addresses in file, one per line, skipping
+// blank lines and # comments.
+func readRecipients(file string) ([]newsletter.Recipient, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var out []newsletter.Recipient
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		out = append(out, newsletter.Recipient{Address: line})
+	}
+	return out, sc.Err()
+}
@@ -0,0 +1,90 @@
+// Command pageweight reports the load cost of every page of a rendered
+// site and checks it against the budgets in perf-budgets.toml.
+//
+// Usage:
+//
+//	pageweight [-budgets perf-budgets.toml] [-v] [dir]
+//
+// dir defaults to public. It exits with status 1 if any page is over
+// budget.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+	"github.com/gopheracademy/gopheracademy-web/internal/weight"
+)
+
+func main() {
+	budgets := flag.String("budgets", "perf-budgets.toml", "budget file")
+	verbose := flag.Bool("v", false, "list every resource of each page")
+	flag.Parse()
+	dir := "public"
+	if flag.NArg() > 0 {
+		dir = flag.Arg(0)
+	}
+
+	b, err := weight.LoadBudgets(*budgets)
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, err := site.Load(dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	a := &weight.Analyzer{Dir: dir}
+	var reports []*weight.Report
+	for _, p := range m.Pages {
+		r, err := a.Analyze(p)
+		if err != nil {
+			log.Fatalf("%s: %v", p.File, err)
+		}
+		reports = append(reports, r)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "page\ttype\ttotal\thtml\tcss\tjs\timage\tfont\treqs\tblocking\texternal\t")
+	var over []string
+	for _, r := range reports {
+		fmt.Fprintf(w, "%s\t%s\t%d\t", r.Page.URL, r.Type, r.Bytes(""))
+		for _, k := range weight.Kinds {
+			fmt.Fprintf(w, "%d\t", r.Bytes(k))
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t\n", r.Requests(), len(r.Blocking()), len(r.External()))
+		for _, v := range b.For(r.Type).Check(r) {
+			over = append(over, fmt.Sprintf("%s: %s", r.Page.URL, v))
+		}
+	}
+	w.Flush()
+
+	if *verbose {
+		for _, r := range reports {
+			fmt.Printf("\n%s\n", r.Page.URL)
+			for _, res := range r.Resources {
+				size := fmt.Sprint(res.Size)
+				if res.External {
+					size = "external"
+				}
+				if res.Blocking {
+					size += ", render-blocking"
+				}
+				fmt.Printf("  %-5s %s (%s)\n", res.Kind, res.URL, size)
+			}
+			for _, d := range r.Duplicates {
+				fmt.Printf("  loaded twice: %s\n", d)
+			}
+		}
+	}
+
+	if len(over) > 0 {
+		fmt.Printf("\n%d budget violations:\n%s\n", len(over), strings.Join(over, "\n"))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,55 @@
+// Command reflinks checks the reference-style links of articles: it
+// reports references to undefined labels, definitions that are never
+// used, labels defined twice with different targets, and references
+// inside code spans.
+//
+// Usage:
+//
+//	reflinks [file ...]
+//
+// Without files it checks every article, including upcoming ones. It
+// exits with status 1 if any problems are found.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/reflinks"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	flag.Parse()
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 0 {
+		articles = nil
+		for _, file := range flag.Args() {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	total := 0
+	for _, a := range articles {
+		for _, p := range reflinks.CheckArticle(a) {
+			fmt.Println(p)
+			total++
+		}
+	}
+	if total > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,73 @@
+// Command reprocheck builds the site twice and reports every file that
+// differs between the builds, with the likely cause.
+//
+// Usage:
+//
+//	reprocheck [-hugo hugo] [-config config.toml] [-keep]
+//	reprocheck dir1 dir2
+//
+// With two directories it compares existing builds instead. It exits
+// with status 1 if the builds differ.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/repro"
+)
+
+func main() {
+	os.Exit(run())
+}
+
+func run() int {
+	hugo := flag.String("hugo", "hugo", "hugo binary")
+	config := flag.String("config", "config.toml", "site config to build")
+	keep := flag.Bool("keep", false, "keep the build directories")
+	flag.Parse()
+
+	var a, b string
+	switch flag.NArg() {
+	case 0:
+		tmp, err := os.MkdirTemp("", "reprocheck-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *keep {
+			log.Printf("builds kept in %s", tmp)
+		} else {
+			defer os.RemoveAll(tmp)
+		}
+		a, b = filepath.Join(tmp, "a"), filepath.Join(tmp, "b")
+		for _, dir := range []string{a, b} {
+			cmd := exec.Command(*hugo, "--config="+*config, "--destination="+dir)
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				log.Fatalf("building into %s: %v", dir, err)
+			}
+		}
+	case 2:
+		a, b = flag.Arg(0), flag.Arg(1)
+	default:
+		flag.Usage()
+		return 2
+	}
+
+	diffs, err := repro.Compare(a, b)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, d := range diffs {
+		fmt.Println(d)
+	}
+	if len(diffs) > 0 {
+		fmt.Printf("%d files differ\n", len(diffs))
+		return 1
+	}
+	return 0
+}
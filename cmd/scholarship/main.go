@@ -0,0 +1,155 @@
+// Command scholarship ranks the scholarship applications taken by
+// cmd/server and mails the decisions.
+//
+// Usage:
+//
+//	scholarship [flags] rank                           print the ranking as CSV
+//	scholarship [flags] decide applicant outcome ...   record decisions
+//	scholarship [flags] notify                         mail the decisions not yet sent
+//	scholarship [flags] delete applicant               remove an application
+//
+// rank orders the applications by the mean of their reviews' scores
+// against the rubric in data/scholarship.toml; with -anonymous it leaves
+// out names and addresses, for sharing with the reviewers.
+//
+// decide takes pairs of an applicant, by label ("Applicant 3f2a9c" or
+// "3f2a9c"), id or email address, and an outcome: accepted, waitlisted
+// or declined. notify mails each applicant with a decision the template
+// for its outcome through -smtp; with -n it prints the mails instead,
+// and with -sink it sends them to an SMTP server of its own and prints
+// the messages as that server received them, headers and all. Neither
+// marks the decisions as mailed.
+// delete removes an application with its attachments and reviews, as
+// when an applicant withdraws or asks to be forgotten.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/scholarship"
+	"github.com/gopheracademy/gopheracademy-web/internal/smtpsink"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	dir := flag.String("dir", "/var/lib/gopheracademy/scholarship", "application directory")
+	anonymous := flag.Bool("anonymous", false, "rank: leave out names and addresses")
+	smtpAddr := flag.String("smtp", "localhost:25", "notify: SMTP server")
+	from := flag.String("from", "GopherCon Scholarship <scholarship@gopheracademy.com>", "notify: sender")
+	dryRun := flag.Bool("n", false, "notify: print the mails instead of sending them")
+	useSink := flag.Bool("sink", false, "notify: send the mails to an in-process SMTP server and print what it receives")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: scholarship [flags] rank|decide applicant outcome ...|notify|delete applicant\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config, err := scholarship.LoadConfig(filepath.Join(*root, scholarship.ConfigFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := scholarship.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	args := flag.Args()[1:]
+	switch flag.Arg(0) {
+	case "rank":
+		ranked, err := scholarship.Rank(config, store)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := scholarship.WriteCSV(os.Stdout, ranked, *anonymous); err != nil {
+			log.Fatal(err)
+		}
+	case "decide":
+		if len(args) == 0 || len(args)%2 != 0 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		for i := 0; i < len(args); i += 2 {
+			a, err := store.Find(args[i])
+			if err != nil {
+				log.Fatal(err)
+			}
+			outcome := args[i+1]
+			if !slices.Contains(scholarship.Outcomes, outcome) {
+				log.Fatalf("%s: outcome must be one of %v", args[i], scholarship.Outcomes)
+			}
+			old, err := store.Decision(a.ID)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !old.Notified.IsZero() && old.Outcome != outcome {
+				log.Fatalf("%s was already mailed that the application was %s", a.Label(), old.Outcome)
+			}
+			if err := store.SaveDecision(a.ID, scholarship.Decision{Outcome: outcome, Decided: time.Now(), Notified: old.Notified}); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("%s: %s\n", a.Label(), outcome)
+		}
+	case "notify":
+		var mailer subscribe.Mailer = &subscribe.SMTP{Addr: *smtpAddr, From: *from}
+		var sink *smtpsink.Sink
+		switch {
+		case *dryRun:
+			mailer = printer{}
+		case *useSink:
+			sink, err = smtpsink.Start()
+			if err != nil {
+				log.Fatal(err)
+			}
+			mailer = &subscribe.SMTP{Addr: sink.Addr(), From: *from}
+		}
+		record := !*dryRun && !*useSink
+		sent, undecided, err := scholarship.Notify(config, store, mailer, record)
+		if sink != nil {
+			sink.Close()
+			for _, m := range sink.Messages() {
+				fmt.Printf("%s\n\n", bytes.TrimRight(m.Data, "\n"))
+			}
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if record {
+			fmt.Printf("%d mailed, %d without a decision\n", sent, undecided)
+		}
+	case "delete":
+		if len(args) != 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		a, err := store.Find(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := store.Delete(a.ID); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("deleted %s\n", a.Label())
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// printer is a Mailer printing the mails instead of sending them.
+type printer struct{}
+
+func (printer) Send(to, subject string, text []byte) error {
+	fmt.Printf("To: %s\nSubject: %s\n\n%s\n", to, subject, text)
+	return nil
+}
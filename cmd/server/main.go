@@ -0,0 +1,238 @@
+// Command server serves a published Hugo site. It sits behind nginx,
+// which hands it every request that does not match a file on disk.
+//
+// Usage:
+//
+//	server [-addr :8080] [-dir public] [-root .] [-host blog.gopheracademy.com] [-archive dir]
+//	       [-subscribers dir -smtp host:port] [-slack dir -slack-admins file]
+//	       [-scholarship dir -scholarship-reviewers file] [-cfp dir -cfp-reviewers file]
+//
+// Besides the site it serves the article short links recorded in
+// data/shortlinks.toml under /s/, saving their hit counts every minute,
+// and oEmbed descriptions of the articles under root at /oembed, for
+// their pages on the blog and the main site alike. With -archive it
+// replays the web archive kept by cmd/archive under /archive/. With
+// -subscribers it takes newsletter signups from the footer form at
+// /subscribe/, mailing confirmation links through the -smtp server; the
+// NEWSLETTER_SECRET environment variable holds the key the links are
+// signed with, and must match the one cmd/newsletter uses.
+//
+// With -slack it takes requests to join the Gophers Slack at /slack/
+// and queues them for the administrators listed in the -slack-admins
+// file, one "name password-hash" line each from cmd/adminpass, at
+// /slack/admin/. SLACK_INVITE_SECRET signs the form's challenges and verification
+// links, and SLACK_TOKEN is the Slack API token invites are sent with;
+// -slack-fake only logs the invites.
+//
+// With -scholarship it takes applications for the scholarship described
+// in data/scholarship.toml at /scholarship/, and serves the reviewers
+// listed in the -scholarship-reviewers file, in the same format as the
+// Slack administrators, at /scholarship/review/.
+//
+// With -cfp it runs the call for papers described in data/cfp.toml at
+// /cfp/, where speakers sign in with links mailed to them, and its
+// review at /cfp/review/ for the reviewers in the -cfp-reviewers file,
+// whose lines may end with the addresses and @domains of the speakers
+// each must not review. CFP_SECRET signs the sign-in links and
+// cookies.
+//
+// The admin and review pages are only served over TLS or to the local
+// machine, never through nginx, which serves the site over plain HTTP;
+// reach them through an SSH tunnel to -addr.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+	"github.com/gopheracademy/gopheracademy-web/internal/archive"
+	"github.com/gopheracademy/gopheracademy-web/internal/cfp"
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/notfound"
+	"github.com/gopheracademy/gopheracademy-web/internal/oembed"
+	"github.com/gopheracademy/gopheracademy-web/internal/scholarship"
+	"github.com/gopheracademy/gopheracademy-web/internal/shortlink"
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+	"github.com/gopheracademy/gopheracademy-web/internal/slackinvite"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "listen address")
+	dir := flag.String("dir", "public", "published site directory")
+	root := flag.String("root", ".", "repository root, for article metadata")
+	host := flag.String("host", "blog.gopheracademy.com", "site host name used for search")
+	links := flag.String("shortlinks", "data/shortlinks.toml", "short link data file")
+	hits := flag.String("hits", "shortlink-hits.json", "short link hit counts file")
+	archiveDir := flag.String("archive", "", "web archive directory kept by cmd/archive")
+	subscribers := flag.String("subscribers", "", "newsletter subscriber directory")
+	smtpAddr := flag.String("smtp", "localhost:25", "SMTP server for confirmation mails")
+	from := flag.String("from", "Gopher Academy <newsletter@gopheracademy.com>", "sender of confirmation mails")
+	slackDir := flag.String("slack", "", "Gophers Slack invite request directory")
+	slackAdmins := flag.String("slack-admins", "", "file of Gophers Slack administrators")
+	slackTeam := flag.String("slack-team", "gophers", "Slack team to invite to")
+	slackFake := flag.Bool("slack-fake", false, "log Slack invites instead of sending them")
+	scholarshipDir := flag.String("scholarship", "", "scholarship application directory")
+	scholarshipReviewers := flag.String("scholarship-reviewers", "", "file of scholarship reviewers")
+	cfpDir := flag.String("cfp", "", "call for papers proposal directory")
+	cfpReviewers := flag.String("cfp-reviewers", "", "file of call for papers reviewers")
+	flag.Parse()
+
+	m, err := site.Load(*dir)
+	if err != nil {
+		log.Fatalf("loading site manifest: %v", err)
+	}
+	log.Printf("indexed %d articles from %s", len(m.Articles()), *dir)
+
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatalf("loading content: %v", err)
+	}
+
+	store, err := shortlink.Load(*links)
+	if err != nil {
+		log.Fatalf("loading short links: %v", err)
+	}
+	sh := shortlink.NewHandler(store, "http://"+*host)
+	if err := sh.LoadHits(*hits); err != nil {
+		log.Fatalf("loading short link hits: %v", err)
+	}
+	go func() {
+		for range time.Tick(time.Minute) {
+			if err := sh.SaveHits(*hits); err != nil {
+				log.Printf("saving short link hits: %v", err)
+			}
+		}
+	}()
+
+	mux := http.NewServeMux()
+	mux.Handle(shortlink.Prefix, sh)
+	oh := oembed.NewHandler(lib, "http://"+*host)
+	for _, name := range content.ConfigFiles {
+		c, err := content.ReadConfig(filepath.Join(*root, name))
+		if err != nil {
+			log.Fatalf("loading site config: %v", err)
+		}
+		oh.Aliases = append(oh.Aliases, c.BaseURL)
+	}
+	mux.Handle(oembed.Path, oh)
+	if *archiveDir != "" {
+		a, err := archive.Open(*archiveDir)
+		if err != nil {
+			log.Fatalf("opening web archive: %v", err)
+		}
+		log.Printf("replaying %d archived pages from %s", len(a.Captures), *archiveDir)
+		mux.Handle(archive.Prefix, &archive.Handler{Archive: a})
+	}
+	if *subscribers != "" {
+		secret := os.Getenv("NEWSLETTER_SECRET")
+		if secret == "" {
+			log.Fatal("-subscribers needs NEWSLETTER_SECRET set")
+		}
+		store, err := subscribe.Open(*subscribers)
+		if err != nil {
+			log.Fatalf("opening subscribers: %v", err)
+		}
+		mailer := &subscribe.SMTP{Addr: *smtpAddr, From: *from}
+		mux.Handle(subscribe.Prefix, subscribe.NewHandler(store, subscribe.Signer{Key: []byte(secret)}, mailer, "http://"+*host, lib))
+	}
+	if *slackDir != "" {
+		secret := os.Getenv("SLACK_INVITE_SECRET")
+		if secret == "" {
+			log.Fatal("-slack needs SLACK_INVITE_SECRET set")
+		}
+		store, err := slackinvite.Open(*slackDir)
+		if err != nil {
+			log.Fatalf("opening Slack invite requests: %v", err)
+		}
+		admins, err := readAdmins(*slackAdmins)
+		if err != nil {
+			log.Fatalf("reading Slack administrators: %v", err)
+		}
+		var client slackinvite.Client = slackinvite.NewSlack(*slackTeam, os.Getenv("SLACK_TOKEN"))
+		if *slackFake {
+			client = &slackinvite.Fake{}
+		}
+		mailer := &subscribe.SMTP{Addr: *smtpAddr, From: "Gophers Slack <slack@gopheracademy.com>"}
+		mux.Handle(slackinvite.Prefix, slackinvite.NewHandler(store, client, mailer, []byte(secret), "http://"+*host, admins))
+	}
+	if *scholarshipDir != "" {
+		config, err := scholarship.LoadConfig(filepath.Join(*root, scholarship.ConfigFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		store, err := scholarship.Open(*scholarshipDir)
+		if err != nil {
+			log.Fatalf("opening scholarship applications: %v", err)
+		}
+		reviewers, err := readAdmins(*scholarshipReviewers)
+		if err != nil {
+			log.Fatalf("reading scholarship reviewers: %v", err)
+		}
+		mailer := &subscribe.SMTP{Addr: *smtpAddr, From: "GopherCon Scholarship <scholarship@gopheracademy.com>"}
+		mux.Handle(scholarship.Prefix, scholarship.NewHandler(store, config, mailer, reviewers))
+	}
+	if *cfpDir != "" {
+		secret := os.Getenv("CFP_SECRET")
+		if secret == "" {
+			log.Fatal("-cfp needs CFP_SECRET set")
+		}
+		config, err := cfp.LoadConfig(filepath.Join(*root, cfp.ConfigFile))
+		if err != nil {
+			log.Fatal(err)
+		}
+		store, err := cfp.Open(*cfpDir)
+		if err != nil {
+			log.Fatalf("opening proposals: %v", err)
+		}
+		reviewers := map[string]cfp.Reviewer{}
+		if *cfpReviewers != "" {
+			if reviewers, err = cfp.ReadReviewers(*cfpReviewers); err != nil {
+				log.Fatalf("reading CFP reviewers: %v", err)
+			}
+		}
+		mailer := &subscribe.SMTP{Addr: *smtpAddr, From: "Gopher Academy CFP <cfp@gopheracademy.com>"}
+		mux.Handle(cfp.Prefix, cfp.NewHandler(store, config, mailer, []byte(secret), "http://"+*host, reviewers))
+	}
+	mux.Handle("/", notfound.NewHandler(*dir, m, *host))
+
+	log.Printf("listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// readAdmins reads a file of "name password-hash" lines, as the Slack
+// administrators and the scholarship reviewers are listed in.
+func readAdmins(file string) (map[string]string, error) {
+	admins := make(map[string]string)
+	if file == "" {
+		return admins, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s: bad line %q", file, sc.Text())
+		}
+		if !adminauth.Valid(fields[1]) {
+			return nil, fmt.Errorf("%s: %s: password is not hashed with cmd/adminpass", file, fields[0])
+		}
+		admins[fields[0]] = fields[1]
+	}
+	return admins, sc.Err()
+}
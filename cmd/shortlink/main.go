@@ -0,0 +1,100 @@
+// Command shortlink maintains the permanent short codes of published
+// articles, recorded in data/shortlinks.toml.
+//
+// Usage:
+//
+//	shortlink sync               assign codes to newly published articles
+//	shortlink list               print every code and its article
+//	shortlink qr -out dir        write <code>.png and <code>.svg QR codes
+//
+// Run sync before publishing and commit the updated data file.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/shortlink"
+)
+
+func main() {
+	data := flag.String("data", "data/shortlinks.toml", "short link data file")
+	root := flag.String("root", ".", "repository root")
+	base := flag.String("base", "http://blog.gopheracademy.com", "site URL encoded into QR codes")
+	out := flag.String("out", "qr", "output directory for qr")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: shortlink [flags] sync|list|qr\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	s, err := shortlink.Load(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch flag.Arg(0) {
+	case "sync":
+		lib, err := content.Load(*root)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var paths []string
+		for _, a := range lib.Published() {
+			paths = append(paths, a.URL())
+		}
+		added, retired := s.Sync(paths, time.Now())
+		for _, l := range added {
+			fmt.Printf("added   %s%s -> %s\n", shortlink.Prefix, l.Code, l.Path)
+		}
+		for _, l := range retired {
+			fmt.Printf("retired %s%s -> %s\n", shortlink.Prefix, l.Code, l.Path)
+		}
+		if err := s.Save(*data); err != nil {
+			log.Fatal(err)
+		}
+	case "list":
+		for _, l := range s.Links {
+			state := ""
+			if l.Retired {
+				state = " (retired)"
+			}
+			fmt.Printf("%s%s\t%s%s\n", shortlink.Prefix, l.Code, l.Path, state)
+		}
+	case "qr":
+		if err := os.MkdirAll(*out, 0755); err != nil {
+			log.Fatal(err)
+		}
+		for _, l := range s.Links {
+			if l.Retired {
+				continue
+			}
+			url := *base + shortlink.Prefix + l.Code
+			png, err := shortlink.PNG(url)
+			if err != nil {
+				log.Fatal(err)
+			}
+			svg, err := shortlink.SVG(url)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(*out, l.Code+".png"), png, 0644); err != nil {
+				log.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(*out, l.Code+".svg"), svg, 0644); err != nil {
+				log.Fatal(err)
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
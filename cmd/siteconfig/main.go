@@ -0,0 +1,66 @@
+// Command siteconfig generates the Hugo configs of both sites from
+// sites.toml, or checks that the committed configs match it.
+//
+// Usage:
+//
+//	siteconfig [-manifest sites.toml] generate
+//	siteconfig [-manifest sites.toml] check
+//
+// check exits with status 1 if a config was edited by hand.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/sites"
+)
+
+func main() {
+	manifest := flag.String("manifest", sites.ManifestFile, "site manifest")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: siteconfig [flags] generate|check\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	m, err := sites.Load(*manifest)
+	if err != nil {
+		log.Fatal(err)
+	}
+	root := filepath.Dir(*manifest)
+	switch flag.Arg(0) {
+	case "generate":
+		files, err := m.Generate(*manifest)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for name, b := range files {
+			if err := os.WriteFile(filepath.Join(root, name), b, 0644); err != nil {
+				log.Fatal(err)
+			}
+		}
+	case "check":
+		mismatches, err := m.Check(root, *manifest)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, mm := range mismatches {
+			fmt.Println(mm)
+		}
+		if len(mismatches) > 0 {
+			fmt.Println("run `go run ./cmd/siteconfig generate` after editing", *manifest)
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
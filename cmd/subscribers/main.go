@@ -0,0 +1,81 @@
+// Command subscribers manages the newsletter subscriber list kept by
+// cmd/server.
+//
+// Usage:
+//
+//	subscribers [-dir dir] export [email]   print subscriptions as CSV
+//	subscribers [-dir dir] delete email     remove every record of an address
+//	subscribers [-dir dir] expire           drop unconfirmed signups older than two days
+//
+// export prints every subscription, or those of one address, as when
+// someone asks for the data held about them. delete answers a request to
+// be forgotten: it removes the address from the subscriber list and from
+// the newsletter's delivery records in the -state directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/newsletter"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+func main() {
+	dir := flag.String("dir", "/var/lib/gopheracademy/subscribers", "subscriber directory")
+	stateDir := flag.String("state", "/var/lib/gopheracademy/newsletter", "newsletter delivery state directory")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: subscribers [flags] export [email]|delete email|expire\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	store, err := subscribe.Open(*dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	switch flag.Arg(0) {
+	case "export":
+		if err := store.Export(os.Stdout, flag.Arg(1)); err != nil {
+			log.Fatal(err)
+		}
+
+	case "delete":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		email := flag.Arg(1)
+		n, err := store.Delete(email)
+		if err != nil {
+			log.Fatal(err)
+		}
+		state, err := newsletter.OpenState(*stateDir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		state.Forget(email)
+		if err := state.Save(); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("deleted %d subscriptions and the delivery records of %s\n", n, email)
+
+	case "expire":
+		n, err := store.Expire(time.Now(), 48*time.Hour)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("expired %d unconfirmed signups\n", n)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,110 @@
+// Command translations reports the articles that have translations and
+// which of the translations are stale, and marks translations up to
+// date once they have been brought in line with their originals.
+//
+// Usage:
+//
+//	translations [-root .] report
+//	translations [-root .] stamp file ...
+//
+// report lists each translated article with its translations, marking
+// the stale ones, whose original changed after they were stamped, and
+// then the translations that cannot be matched up with an original. It
+// exits with status 1 if there are any of the latter. stamp records in
+// each translation given the hash of its original as it is now.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/sites"
+	"github.com/gopheracademy/gopheracademy-web/internal/translations"
+)
+
+func main() {
+	root := flag.String("root", ".", "repository root")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: translations [flags] report|stamp file ...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	m, err := sites.Load(filepath.Join(*root, sites.ManifestFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	languages := make(map[string]bool)
+	for code := range m.Languages {
+		languages[code] = true
+	}
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	groups, problems := translations.Groups(lib, m.DefaultLanguage, languages)
+
+	switch flag.Arg(0) {
+	case "report":
+		stale := 0
+		for _, g := range groups {
+			fmt.Printf("%s\t%s\t%s\n", g.Key, m.DefaultLanguage, g.Original.File)
+			for _, t := range g.Translations {
+				status := "up to date"
+				if t.Stale {
+					status = "stale"
+					stale++
+				}
+				fmt.Printf("\t%s\t%s\t%s\n", t.Language, t.Article.File, status)
+			}
+		}
+		fmt.Printf("%d translated articles, %d stale translations\n", len(groups), stale)
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+	case "stamp":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		for _, file := range flag.Args()[1:] {
+			file = filepath.ToSlash(filepath.Clean(file))
+			g, t, ok := find(groups, file)
+			if !ok {
+				log.Fatalf("%s: not a translation of an article", file)
+			}
+			if err := translations.Stamp(g, t); err != nil {
+				log.Fatalf("%s: %v", file, err)
+			}
+			if err := t.Article.Save(*root); err != nil {
+				log.Fatal(err)
+			}
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// find returns the translation stored in file and its group.
+func find(groups []translations.Group, file string) (translations.Group, translations.Translation, bool) {
+	for _, g := range groups {
+		for _, t := range g.Translations {
+			if t.Article.File == file {
+				return g, t, true
+			}
+		}
+	}
+	return translations.Group{}, translations.Translation{}, false
+}
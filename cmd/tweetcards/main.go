@@ -0,0 +1,126 @@
+// Command tweetcards replaces embedded tweets in articles with static
+// quote cards rendered from data/tweets.toml.
+//
+// Usage:
+//
+//	tweetcards capture [file ...]   cache the tweets embedded in articles
+//	tweetcards render [file ...]    replace embeds with cards
+//
+// Without files both work on every article. render exits with status 1
+// if an embedded tweet is not cached; run capture first, or add it to
+// the data file by hand.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/tweets"
+)
+
+func main() {
+	data := flag.String("data", "data/tweets.toml", "tweet cache")
+	root := flag.String("root", ".", "repository root")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: tweetcards [flags] capture|render [file ...]\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	c, err := tweets.Load(*data)
+	if err != nil {
+		log.Fatal(err)
+	}
+	lib, err := content.Load(*root)
+	if err != nil {
+		log.Fatal(err)
+	}
+	articles := lib.Articles
+	if flag.NArg() > 1 {
+		articles = nil
+		for _, file := range flag.Args()[1:] {
+			a, ok := lib.Article(filepath.ToSlash(filepath.Clean(file)))
+			if !ok {
+				log.Fatalf("%s: not an article", file)
+			}
+			articles = append(articles, a)
+		}
+	}
+
+	switch flag.Arg(0) {
+	case "capture":
+		for _, a := range articles {
+			embeds, err := tweets.Find(a.Body())
+			if err != nil {
+				log.Fatalf("%s: %v", a.File, err)
+			}
+			for _, e := range embeds {
+				if c.Add(e.Tweet) {
+					fmt.Printf("cached %s from %s\n", e.Tweet.URL(), a.File)
+				}
+			}
+		}
+		if err := c.Save(*data); err != nil {
+			log.Fatal(err)
+		}
+	case "render":
+		failed := false
+		for _, a := range articles {
+			body, missing, err := tweets.Render(a.Body(), c)
+			if err != nil {
+				log.Fatalf("%s: %v", a.File, err)
+			}
+			for _, id := range missing {
+				fmt.Printf("%s: tweet %s is not in %s\n", a.File, id, *data)
+				failed = true
+			}
+			if string(body) == string(a.Body()) {
+				continue
+			}
+			a.Meta.SetBody(body)
+			if err := dropScriptHost(a.Meta); err != nil {
+				log.Fatalf("%s: %v", a.File, err)
+			}
+			if err := a.Save(*root); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("rendered %s\n", a.File)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// dropScriptHost removes the embed script host from an article's script
+// allowlist once its embeds are gone.
+func dropScriptHost(fm *content.FrontMatter) error {
+	var hosts []string
+	found := false
+	for _, h := range fm.Strings("allowscripts") {
+		if h == tweets.ScriptHost {
+			found = true
+			continue
+		}
+		hosts = append(hosts, h)
+	}
+	switch {
+	case !found:
+		return nil
+	case len(hosts) == 0:
+		fm.Delete("allowscripts")
+		return nil
+	}
+	return fm.Set("allowscripts", hosts)
+}
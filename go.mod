@@ -0,0 +1,11 @@
+module github.com/gopheracademy/gopheracademy-web
+
+go 1.27.1
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/russross/blackfriday v1.6.0
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/net v0.59.0
+	rsc.io/qr v0.2.0
+)
@@ -0,0 +1,107 @@
+// Package adminauth checks the passwords of the people who use the
+// server's admin pages: the Slack administrators and the scholarship and
+// call for papers reviewers.
+//
+// Passwords are stored as salted PBKDF2-SHA256 hashes, written
+//
+//	pbkdf2-sha256$<iterations>$<salt>$<key>
+//
+// with the salt and key in unpadded base64, as cmd/adminpass prints them.
+// The admin pages use HTTP basic authentication, so they are only served
+// to requests that reach the server privately: over TLS, or from the
+// local machine without passing through nginx, which serves the site over
+// plain HTTP.
+package adminauth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Iterations is the PBKDF2 iteration count of new hashes.
+const Iterations = 600000
+
+const (
+	scheme  = "pbkdf2-sha256"
+	saltLen = 16
+	keyLen  = 32
+)
+
+var b64 = base64.RawStdEncoding
+
+// Hash returns the stored form of password, with a fresh salt.
+func Hash(password string) (string, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key, err := pbkdf2.Key(sha256.New, password, salt, Iterations, keyLen)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{scheme, strconv.Itoa(Iterations), b64.EncodeToString(salt), b64.EncodeToString(key)}, "$"), nil
+}
+
+// parse splits a stored hash into its iteration count, salt and key.
+func parse(hash string) (iter int, salt, key []byte, ok bool) {
+	f := strings.Split(hash, "$")
+	if len(f) != 4 || f[0] != scheme {
+		return 0, nil, nil, false
+	}
+	iter, err := strconv.Atoi(f[1])
+	if err != nil || iter < 1 {
+		return 0, nil, nil, false
+	}
+	if salt, err = b64.DecodeString(f[2]); err != nil || len(salt) == 0 {
+		return 0, nil, nil, false
+	}
+	if key, err = b64.DecodeString(f[3]); err != nil || len(key) == 0 {
+		return 0, nil, nil, false
+	}
+	return iter, salt, key, true
+}
+
+// Valid reports whether hash is in the stored form Hash returns.
+func Valid(hash string) bool {
+	_, _, _, ok := parse(hash)
+	return ok
+}
+
+// Check reports whether password matches the stored hash.
+func Check(hash, password string) bool {
+	iter, salt, key, ok := parse(hash)
+	if !ok {
+		return false
+	}
+	got, err := pbkdf2.Key(sha256.New, password, salt, iter, len(key))
+	return err == nil && subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// Private reports whether r reached the server privately: over TLS, or
+// straight from the local machine, as through an SSH tunnel. Requests
+// relayed by nginx come from the loopback address too, but carry the
+// X-Real-IP header it sets.
+func Private(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback() && r.Header.Get("X-Real-IP") == ""
+}
+
+// Refuse answers a request for an admin page that did not arrive
+// privately.
+func Refuse(w http.ResponseWriter) {
+	http.Error(w, "This page is only served over TLS or to the local machine.", http.StatusForbidden)
+}
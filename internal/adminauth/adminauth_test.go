@@ -0,0 +1,87 @@
+package adminauth
+
+import (
+	"crypto/tls"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	h1, err := Hash("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h2, err := Hash("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Errorf("two hashes of one password are both %s; the salt is not fresh", h1)
+	}
+	if !strings.HasPrefix(h1, "pbkdf2-sha256$600000$") || !Valid(h1) {
+		t.Errorf("Hash = %s", h1)
+	}
+	for _, h := range []string{h1, h2} {
+		if !Check(h, "hunter2") {
+			t.Errorf("Check(%s, right password) = false", h)
+		}
+		if Check(h, "hunter3") || Check(h, "") {
+			t.Errorf("Check(%s, wrong password) = true", h)
+		}
+	}
+}
+
+func TestCheckMalformed(t *testing.T) {
+	// The unsalted hex SHA-256 of "hunter2", as admins used to be listed.
+	old := "f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7"
+	for _, h := range []string{
+		"",
+		old,
+		"pbkdf2-sha256$0$c2FsdA$a2V5",
+		"pbkdf2-sha256$x$c2FsdA$a2V5",
+		"pbkdf2-sha256$1$$a2V5",
+		"pbkdf2-sha256$1$c2FsdA$",
+		"pbkdf2-sha256$1$c2FsdA$!!",
+		"pbkdf2-sha1$1$c2FsdA$a2V5",
+		"pbkdf2-sha256$1$c2FsdA$a2V5$",
+	} {
+		if Valid(h) {
+			t.Errorf("Valid(%q) = true", h)
+		}
+		if Check(h, "hunter2") {
+			t.Errorf("Check(%q) = true", h)
+		}
+	}
+}
+
+func TestPrivate(t *testing.T) {
+	tests := []struct {
+		name   string
+		remote string
+		realIP string
+		tls    bool
+		want   bool
+	}{
+		{"remote over HTTP", "192.0.2.1:1234", "", false, false},
+		{"remote over TLS", "192.0.2.1:1234", "", true, true},
+		{"through nginx", "127.0.0.1:1234", "192.0.2.1", false, false},
+		{"through nginx from the machine", "127.0.0.1:1234", "127.0.0.1", false, false},
+		{"local", "127.0.0.1:1234", "", false, true},
+		{"local IPv6", "[::1]:1234", "", false, true},
+		{"remote claiming a real IP", "192.0.2.1:1234", "127.0.0.1", false, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/slack/admin/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if tt.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		if got := Private(r); got != tt.want {
+			t.Errorf("%s: Private = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
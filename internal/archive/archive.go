@@ -0,0 +1,295 @@
+// Package archive keeps copies of the external pages articles link to,
+// so that readers can still see what an article referred to after the
+// page is gone.
+//
+// Each link is fetched once, when the article is published, and stored
+// as WARC request and response records in a .warc.gz file per article.
+// The archive lives outside the repository; its index.json records where
+// each capture is and whether the live page has since disappeared.
+// Handler replays captures, and lists the dead links so that article
+// pages can offer the archived copy next to them.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// IndexFile is the name of the index in the archive directory.
+const IndexFile = "index.json"
+
+// Capture is an archived copy of a URL.
+type Capture struct {
+	// File is the WARC file holding the capture, relative to the archive.
+	File string `json:"file"`
+	// Offset is where the response record starts in File.
+	Offset int64     `json:"offset"`
+	Date   time.Time `json:"date"`
+	Status int       `json:"status"`
+	// Article is the article the link was first archived for.
+	Article string `json:"article"`
+	// Dead is set when the live URL is gone.
+	Dead    bool      `json:"dead,omitempty"`
+	Checked time.Time `json:"checked,omitempty"`
+	// Failures counts the checks in a row that got no answer, or a
+	// server error, from the live URL.
+	Failures int `json:"failures,omitempty"`
+}
+
+// FailLimit is the number of checks in a row a live URL may fail to
+// answer before it is taken to be gone. A single failure may be the
+// network's, or ours.
+const FailLimit = 3
+
+// MaxBody is the largest response body Fetch archives.
+const MaxBody = 10 << 20
+
+// Archive is a directory of WARC files and their index.
+type Archive struct {
+	Dir string
+	// Captures maps each archived URL to its capture.
+	Captures map[string]*Capture
+	Client   *http.Client
+	// UserAgent is sent with every request.
+	UserAgent string
+}
+
+// Open reads the archive in dir, creating the directory if needed.
+func Open(dir string) (*Archive, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	a := &Archive{
+		Dir:       dir,
+		Captures:  make(map[string]*Capture),
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		UserAgent: "gopheracademy-archiver (+http://blog.gopheracademy.com/)",
+	}
+	b, err := os.ReadFile(filepath.Join(dir, IndexFile))
+	if os.IsNotExist(err) {
+		return a, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &a.Captures); err != nil {
+		return nil, fmt.Errorf("%s: %v", IndexFile, err)
+	}
+	return a, nil
+}
+
+// Save writes the index, replacing the old one atomically.
+func (a *Archive) Save() error {
+	b, err := json.MarshalIndent(a.Captures, "", "\t")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(a.Dir, IndexFile)
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Has reports whether url is archived.
+func (a *Archive) Has(url string) bool {
+	return a.Captures[url] != nil
+}
+
+// Fetch archives url for an article, unless it is archived already.
+// Only successful responses are kept; Fetch returns an error for any
+// other, so that the link is tried again next time.
+func (a *Archive) Fetch(url, article string) error {
+	if a.Has(url) {
+		return nil
+	}
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", a.UserAgent)
+	reqDump, err := httputil.DumpRequestOut(req, false)
+	if err != nil {
+		return err
+	}
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, MaxBody))
+		return fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxBody+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > MaxBody {
+		return fmt.Errorf("%s: body larger than %d bytes", url, MaxBody)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	respDump, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := warcName(article)
+	f, err := os.OpenFile(filepath.Join(a.Dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() == 0 {
+		rec := NewRecord("warcinfo", "", now, "application/warc-fields",
+			[]byte("software: gopheracademy-web archive\r\nformat: WARC File Format 1.0\r\narticle: "+article+"\r\n"))
+		if err := WriteRecord(f, rec); err != nil {
+			return err
+		}
+	}
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	response := NewRecord("response", url, now, "application/http; msgtype=response", respDump)
+	request := NewRecord("request", url, now, "application/http; msgtype=request", reqDump)
+	request.Header.Set("WARC-Concurrent-To", response.Header.Get("WARC-Record-ID"))
+	if err := WriteRecord(f, response); err != nil {
+		return err
+	}
+	if err := WriteRecord(f, request); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	a.Captures[url] = &Capture{File: name, Offset: offset, Date: now, Status: resp.StatusCode, Article: article}
+	return nil
+}
+
+// warcName returns the WARC file name for an article file, e.g.
+// "advent-2014-delve.warc.gz" for content/advent-2014/delve.md.
+func warcName(article string) string {
+	name := strings.TrimSuffix(article, filepath.Ext(article))
+	name = strings.TrimPrefix(name, "content/")
+	return strings.ReplaceAll(name, "/", "-") + ".warc.gz"
+}
+
+// Response returns the archived response for url.
+func (a *Archive) Response(url string) (*http.Response, *Capture, error) {
+	c := a.Captures[url]
+	if c == nil {
+		return nil, nil, os.ErrNotExist
+	}
+	f, err := os.Open(filepath.Join(a.Dir, c.File))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(c.Offset, io.SeekStart); err != nil {
+		return nil, nil, err
+	}
+	rec, err := ReadRecord(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s@%d: %v", c.File, c.Offset, err)
+	}
+	if rec.Type() != "response" || rec.Header.Get("WARC-Target-URI") != url {
+		return nil, nil, fmt.Errorf("%s@%d: not the response for %s", c.File, c.Offset, url)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp, c, nil
+}
+
+// Check requests every archived URL and marks the ones that are gone:
+// those answering 404 or 410, or failing to answer FailLimit checks in
+// a row. Server errors count as failures to answer. It returns the URLs
+// that changed state, and those that failed this check without being
+// marked gone yet.
+func (a *Archive) Check() (died, revived, failing []string) {
+	for _, url := range a.URLs() {
+		c := a.Captures[url]
+		dead, known := a.gone(url)
+		c.Checked = time.Now()
+		if known {
+			c.Failures = 0
+		} else {
+			c.Failures++
+			if c.Failures < FailLimit {
+				failing = append(failing, url)
+				continue
+			}
+			dead = true
+		}
+		switch {
+		case dead && !c.Dead:
+			died = append(died, url)
+		case !dead && c.Dead:
+			revived = append(revived, url)
+		}
+		c.Dead = dead
+	}
+	return died, revived, failing
+}
+
+// gone requests url and reports whether the page is gone. known is
+// false if the request failed or the server answered with an error, when
+// there is no telling.
+func (a *Archive) gone(url string) (dead, known bool) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return false, false
+	}
+	req.Header.Set("User-Agent", a.UserAgent)
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return false, false
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return true, true
+	case resp.StatusCode >= 500:
+		return false, false
+	}
+	return false, true
+}
+
+// URLs returns the archived URLs, sorted.
+func (a *Archive) URLs() []string {
+	urls := make([]string, 0, len(a.Captures))
+	for u := range a.Captures {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// Dead returns the archived URLs whose live page is gone, sorted.
+func (a *Archive) Dead() []string {
+	var urls []string
+	for _, u := range a.URLs() {
+		if a.Captures[u].Dead {
+			urls = append(urls, u)
+		}
+	}
+	return urls
+}
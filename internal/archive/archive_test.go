@@ -0,0 +1,243 @@
+package archive
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// origin is a site whose pages can be taken down.
+type origin struct {
+	mu   sync.Mutex
+	down map[string]int // status served instead of the page
+}
+
+func (o *origin) setStatus(path string, status int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.down[path] = status
+}
+
+func (o *origin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	o.mu.Lock()
+	status := o.down[r.URL.Path]
+	o.mu.Unlock()
+	if status != 0 {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	switch r.URL.Path {
+	case "/post":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><body class=\"post\"><h1>Post</h1><script>alert(1)</script></body></html>")
+	case "/data.txt":
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, "plain text")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newArchive(t *testing.T) (*Archive, *origin, *httptest.Server) {
+	o := &origin{down: make(map[string]int)}
+	srv := httptest.NewServer(o)
+	t.Cleanup(srv.Close)
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/post", "/data.txt"} {
+		if err := a.Fetch(srv.URL+p, "content/advent-2014/delve.md"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return a, o, srv
+}
+
+func TestFetchRoundTrip(t *testing.T) {
+	a, _, srv := newArchive(t)
+	if err := a.Fetch(srv.URL+"/missing", "content/advent-2014/delve.md"); err == nil {
+		t.Error("fetching a 404 succeeded")
+	}
+	if a.Has(srv.URL + "/missing") {
+		t.Error("404 archived")
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Open(a.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := b.URLs(), []string{srv.URL + "/data.txt", srv.URL + "/post"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("reopened archive has %v, want %v", got, want)
+	}
+	resp, c, err := b.Response(srv.URL + "/post")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "<h1>Post</h1>") {
+		t.Errorf("replayed %d %q", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("replayed Content-Type %q", ct)
+	}
+	if c.File != "advent-2014-delve.warc.gz" || c.Article != "content/advent-2014/delve.md" {
+		t.Errorf("capture %+v", c)
+	}
+
+	// Both captures went to the article's file: a warcinfo record, then
+	// a response and a request for each.
+	f, err := os.Open(filepath.Join(a.Dir, c.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var types []string
+	for {
+		rec, err := ReadRecord(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		types = append(types, rec.Type())
+	}
+	if want := []string{"warcinfo", "response", "request", "response", "request"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("records %v, want %v", types, want)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	a, o, srv := newArchive(t)
+	post, data := srv.URL+"/post", srv.URL+"/data.txt"
+
+	o.setStatus("/post", http.StatusInternalServerError)
+	o.setStatus("/data.txt", http.StatusGone)
+	died, revived, failing := a.Check()
+	if !reflect.DeepEqual(died, []string{data}) || len(revived) > 0 || !reflect.DeepEqual(failing, []string{post}) {
+		t.Errorf("Check = died %v, revived %v, failing %v; want %s died and %s failing", died, revived, failing, data, post)
+	}
+	if got := a.Dead(); !reflect.DeepEqual(got, []string{data}) {
+		t.Errorf("Dead = %v, want a server error taken as temporary", got)
+	}
+
+	o.setStatus("/data.txt", 0)
+	o.setStatus("/post", http.StatusNotFound)
+	died, revived, failing = a.Check()
+	if !reflect.DeepEqual(died, []string{post}) || !reflect.DeepEqual(revived, []string{data}) || len(failing) > 0 {
+		t.Errorf("Check = died %v, revived %v, failing %v; want %s died and %s revived", died, revived, failing, post, data)
+	}
+	if a.Captures[post].Failures != 0 {
+		t.Errorf("an answer left %d failures counted", a.Captures[post].Failures)
+	}
+
+	// With the site down, data is taken to be gone only once it has
+	// failed FailLimit checks in a row.
+	srv.Close()
+	for i := 1; i < FailLimit; i++ {
+		died, revived, failing = a.Check()
+		if len(died) > 0 || len(revived) > 0 || len(failing) != 2 {
+			t.Errorf("site down, check %d: died %v, revived %v, failing %v", i, died, revived, failing)
+		}
+		if got := a.Dead(); !reflect.DeepEqual(got, []string{post}) {
+			t.Errorf("site down, check %d: dead %v", i, got)
+		}
+	}
+	died, _, failing = a.Check()
+	if !reflect.DeepEqual(died, []string{data}) || len(failing) > 0 || len(a.Dead()) != 2 {
+		t.Errorf("site down, check %d: died %v, failing %v, dead %v", FailLimit, died, failing, a.Dead())
+	}
+}
+
+func TestFetchLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.Copy(w, io.LimitReader(zeros{}, MaxBody+1))
+	}))
+	defer srv.Close()
+	a, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Fetch(srv.URL+"/big", "content/advent-2014/delve.md"); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("fetching a body over MaxBody: err %v", err)
+	}
+	if a.Has(srv.URL + "/big") {
+		t.Error("body over MaxBody archived")
+	}
+}
+
+// zeros reads as an endless run of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestHandler(t *testing.T) {
+	a, o, srv := newArchive(t)
+	post := srv.URL + "/post"
+	o.setStatus("/post", http.StatusNotFound)
+	a.Check()
+	h := &Handler{Archive: a}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", CopyURL(post), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("replay: status %d", w.Code)
+	}
+	want := map[string]string{
+		"Content-Security-Policy": "sandbox",
+		"X-Robots-Tag":            "noindex",
+		"Content-Type":            "text/html; charset=utf-8",
+		"Link":                    "<" + post + ">; rel=\"original\"",
+		"Memento-Datetime":        a.Captures[post].Date.UTC().Format(http.TimeFormat),
+	}
+	for k, v := range want {
+		if got := w.Header().Get(k); got != v {
+			t.Errorf("replay: %s %q, want %q", k, got, v)
+		}
+	}
+	body := w.Body.String()
+	if !strings.HasPrefix(body, `<html><body class="post"><div style=`) || !strings.Contains(body, "Archived copy of <a href=\""+post+"\">") {
+		t.Errorf("replay: no banner at the top of the body: %s", body)
+	}
+
+	// Pages other than HTML get the sandbox too, and no banner.
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", CopyURL(srv.URL+"/data.txt"), nil))
+	if w.Header().Get("Content-Security-Policy") != "sandbox" || w.Body.String() != "plain text" {
+		t.Errorf("replay of text: CSP %q, body %q", w.Header().Get("Content-Security-Policy"), w.Body)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", CopyURL(srv.URL+"/never"), nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("replay of an unarchived URL: status %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", Prefix+"dead.json", nil))
+	var dead map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &dead); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dead, map[string]string{post: CopyURL(post)}) {
+		t.Errorf("dead.json = %v", dead)
+	}
+}
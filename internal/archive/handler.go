@@ -0,0 +1,85 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Prefix is the URL path the archive is served under.
+const Prefix = "/archive/"
+
+// Handler replays archived pages at /archive/?url=<url> and lists the
+// dead links at /archive/dead.json, mapped to their archived copies.
+//
+// Archived pages are served with a sandbox Content-Security-Policy, so
+// that their scripts run, if at all, in an origin of their own rather
+// than the site's.
+type Handler struct {
+	Archive *Archive
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case Prefix:
+		h.replay(w, r)
+	case Prefix + "dead.json":
+		dead := make(map[string]string)
+		for _, u := range h.Archive.Dead() {
+			dead[u] = CopyURL(u)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=3600")
+		json.NewEncoder(w).Encode(dead)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// CopyURL returns the path the archived copy of target is served at.
+func CopyURL(target string) string {
+	return Prefix + "?url=" + url.QueryEscape(target)
+}
+
+var bodyRE = regexp.MustCompile(`(?i)<body[^>]*>`)
+
+func (h *Handler) replay(w http.ResponseWriter, r *http.Request) {
+	target := r.FormValue("url")
+	resp, c, err := h.Archive.Response(target)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if ct := resp.Header.Get("Content-Type"); strings.HasPrefix(ct, "text/html") {
+		banner := fmt.Sprintf(`<div style="padding:8px;background:#ffd;border-bottom:1px solid #cc9;font:14px sans-serif">`+
+			`Archived copy of <a href="%s">%s</a> taken on %s by Gopher Academy.</div>`,
+			html.EscapeString(target), html.EscapeString(target), c.Date.Format("January 2, 2006"))
+		if loc := bodyRE.FindIndex(body); loc != nil {
+			body = append(body[:loc[1]:loc[1]], append([]byte(banner), body[loc[1]:]...)...)
+		} else {
+			body = append([]byte(banner), body...)
+		}
+	}
+	for _, k := range []string{"Content-Type", "Last-Modified"} {
+		if v := resp.Header.Get(k); v != "" {
+			w.Header().Set(k, v)
+		}
+	}
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Robots-Tag", "noindex")
+	w.Header().Set("Memento-Datetime", c.Date.UTC().Format(http.TimeFormat))
+	w.Header().Set("Link", fmt.Sprintf("<%s>; rel=\"original\"", target))
+	http.ServeContent(w, r, "", c.Date, bytes.NewReader(body))
+}
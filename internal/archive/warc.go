@@ -0,0 +1,123 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// Record is a WARC record.
+type Record struct {
+	// Header holds the WARC named fields, such as WARC-Type and
+	// WARC-Target-URI.
+	Header textproto.MIMEHeader
+	Block  []byte
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// NewRecord returns a record of the given type with a fresh record id.
+func NewRecord(typ, uri string, date time.Time, contentType string, block []byte) *Record {
+	h := make(textproto.MIMEHeader)
+	h.Set("WARC-Type", typ)
+	h.Set("WARC-Record-ID", newID())
+	h.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	if uri != "" {
+		h.Set("WARC-Target-URI", uri)
+	}
+	if contentType != "" {
+		h.Set("Content-Type", contentType)
+	}
+	return &Record{Header: h, Block: block}
+}
+
+// newID returns a WARC record id, a random UUID URN.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// headerOrder is the order the fields of a record are written in, with
+// the spelling the WARC specification uses; Header holds them in
+// canonical MIME form.
+var headerOrder = []string{"WARC-Type", "WARC-Record-ID", "WARC-Date", "WARC-Target-URI", "WARC-Concurrent-To", "Content-Type"}
+
+// WriteRecord writes r to w as a gzip member of its own, the usual form
+// of .warc.gz files, so that each record can be read on its own from its
+// offset.
+func WriteRecord(w io.Writer, r *Record) error {
+	var buf bytes.Buffer
+	buf.WriteString("WARC/1.0\r\n")
+	written := make(map[string]bool)
+	for _, k := range headerOrder {
+		for _, v := range r.Header.Values(k) {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+		written[textproto.CanonicalMIMEHeaderKey(k)] = true
+	}
+	for k, vs := range r.Header {
+		if written[k] || k == "Content-Length" {
+			continue
+		}
+		for _, v := range vs {
+			fmt.Fprintf(&buf, "%s: %s\r\n", k, v)
+		}
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(r.Block))
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadRecord reads the record written by WriteRecord at the start of r,
+// to the end of its gzip member. If r is an io.ByteReader, nothing past
+// the member is read, so that the next record can be read from r.
+func ReadRecord(r io.Reader) (*Record, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	zr.Multistream(false)
+	br := bufio.NewReader(zr)
+	version, err := br.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if version != "WARC/1.0\r\n" {
+		return nil, fmt.Errorf("archive: not a WARC/1.0 record")
+	}
+	h, err := textproto.NewReader(br).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("archive: bad Content-Length %q", h.Get("Content-Length"))
+	}
+	block := make([]byte, n)
+	if _, err := io.ReadFull(br, block); err != nil {
+		return nil, err
+	}
+	// Read the rest of the member, which checks its checksum.
+	if _, err := io.Copy(io.Discard, br); err != nil {
+		return nil, err
+	}
+	return &Record{Header: h, Block: block}, nil
+}
@@ -0,0 +1,135 @@
+// Package cfp runs the call for papers of the conference the main site
+// promotes.
+//
+// Speakers sign in with a link mailed to them, so there are no
+// passwords to keep, and write proposals: a title, an abstract, a level
+// and the bios of up to a few speakers. A proposal is a draft, which
+// only its speaker sees, until it is submitted; either can be edited
+// until the call closes. Reviewers score submitted proposals without
+// seeing who proposed them: the review pages show neither the speakers'
+// names nor their bios. A reviewer never sees the proposals of speakers
+// their line in the reviewers file lists as conflicts, and can flag a
+// conflict on any other proposal they recognize, which sets their
+// score aside. The organizers rank the proposals, record decisions and
+// export the accepted talks to the schedule (see cmd/cfp).
+package cfp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+)
+
+// ConfigFile is the call's configuration, relative to the repository
+// root.
+const ConfigFile = "data/cfp.toml"
+
+// Config describes the call for papers.
+type Config struct {
+	Name string `toml:"name"`
+	// Conference is the schedule's conference name.
+	Conference string `toml:"conference"`
+	Contact    string `toml:"contact"`
+	// Opens and Closes bound the time proposals can be submitted and
+	// edited in.
+	Opens  time.Time `toml:"opens"`
+	Closes time.Time `toml:"closes"`
+	Levels []string  `toml:"levels"`
+	// AbstractWords and BioWords are the longest abstract and speaker
+	// bio accepted, in words.
+	AbstractWords int `toml:"abstract_words"`
+	BioWords      int `toml:"bio_words"`
+	// MaxSpeakers is the most speakers a talk can have.
+	MaxSpeakers int `toml:"max_speakers"`
+	// MaxScore is the best score; reviewers score from 1.
+	MaxScore int `toml:"max_score"`
+}
+
+// LoadConfig reads and checks the configuration in file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if _, err := toml.Decode(string(b), c); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	switch {
+	case len(c.Levels) == 0:
+		return nil, fmt.Errorf("%s: no levels", file)
+	case c.MaxScore < 2:
+		return nil, fmt.Errorf("%s: max_score must be at least 2", file)
+	case c.MaxSpeakers < 1:
+		return nil, fmt.Errorf("%s: max_speakers must be at least 1", file)
+	case !c.Closes.After(c.Opens):
+		return nil, fmt.Errorf("%s: the call closes before it opens", file)
+	}
+	return c, nil
+}
+
+// Open reports whether proposals can be submitted and edited at now.
+func (c *Config) Open(now time.Time) bool {
+	return !now.Before(c.Opens) && now.Before(c.Closes)
+}
+
+// Reviewer is a member of the program committee.
+type Reviewer struct {
+	Name string
+	// Password is the hash of the reviewer's password, as
+	// adminauth.Hash stores it.
+	Password string
+	// Conflicts are the email addresses, and domains written "@domain",
+	// of speakers whose proposals the reviewer must not review: their
+	// colleagues, their employer, themselves.
+	Conflicts []string
+}
+
+// Conflicted reports whether the reviewer has a declared conflict with
+// the speaker at email.
+func (r Reviewer) Conflicted(email string) bool {
+	email = strings.ToLower(email)
+	for _, c := range r.Conflicts {
+		c = strings.ToLower(c)
+		if email == c || strings.HasPrefix(c, "@") && strings.HasSuffix(email, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadReviewers reads the reviewers from a file of lines
+//
+//	name password-hash [conflict ...]
+//
+// as the other services list their administrators, with the reviewer's
+// conflicts added. cmd/adminpass prints the first two fields.
+func ReadReviewers(file string) (map[string]Reviewer, error) {
+	reviewers := make(map[string]Reviewer)
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%s: bad line %q", file, sc.Text())
+		}
+		if !adminauth.Valid(fields[1]) {
+			return nil, fmt.Errorf("%s: %s: password is not hashed with cmd/adminpass", file, fields[0])
+		}
+		reviewers[fields[0]] = Reviewer{Name: fields[0], Password: fields[1], Conflicts: fields[2:]}
+	}
+	return reviewers, sc.Err()
+}
@@ -0,0 +1,647 @@
+package cfp
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+// Prefix is the URL path the handler is served under.
+const Prefix = "/cfp/"
+
+// Token actions of the sign-in links and session cookies.
+const (
+	signinAction  = "cfp-signin"
+	sessionAction = "cfp-session"
+)
+
+const cookieName = "cfp_session"
+
+// Handler serves the speakers' pages at Prefix and the reviewers' pages
+// at Prefix+"review/".
+type Handler struct {
+	Store     *Store
+	Config    *Config
+	Mailer    subscribe.Mailer
+	Signer    subscribe.Signer
+	Reviewers map[string]Reviewer
+	// Base is the site URL sign-in links are made under.
+	Base string
+	// LinkTTL is how long a sign-in link stays valid, Session how long
+	// a speaker stays signed in, and Wait the least time between two
+	// sign-in mails to an address.
+	LinkTTL, Session, Wait time.Duration
+
+	mu     sync.Mutex
+	mailed map[string]time.Time
+}
+
+// NewHandler returns a Handler whose links and cookies are signed with
+// key.
+func NewHandler(store *Store, config *Config, mailer subscribe.Mailer, key []byte, base string, reviewers map[string]Reviewer) *Handler {
+	return &Handler{
+		Store:     store,
+		Config:    config,
+		Mailer:    mailer,
+		Signer:    subscribe.Signer{Key: key},
+		Reviewers: reviewers,
+		Base:      strings.TrimSuffix(base, "/"),
+		LinkTTL:   time.Hour,
+		Session:   30 * 24 * time.Hour,
+		Wait:      5 * time.Minute,
+		mailed:    make(map[string]time.Time),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	switch path := r.URL.Path; {
+	case path == Prefix:
+		h.home(w, r)
+	case path == Prefix+"signin" && r.Method == "POST":
+		h.signin(w, r)
+	case path == Prefix+"auth":
+		h.auth(w, r)
+	case path == Prefix+"signout" && r.Method == "POST":
+		http.SetCookie(w, &http.Cookie{Name: cookieName, Path: Prefix, MaxAge: -1})
+		http.Redirect(w, r, Prefix, http.StatusSeeOther)
+	case path == Prefix+"edit":
+		h.edit(w, r)
+	case path == Prefix+"review/":
+		if rev, ok := h.authenticate(w, r); ok {
+			h.list(w, rev)
+		}
+	case strings.HasPrefix(path, Prefix+"review/"):
+		if rev, ok := h.authenticate(w, r); ok {
+			h.review(w, r, rev, strings.TrimPrefix(path, Prefix+"review/"))
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// speaker returns the address of the signed-in speaker, or "".
+func (h *Handler) speaker(r *http.Request) string {
+	c, err := r.Cookie(cookieName)
+	if err != nil {
+		return ""
+	}
+	email, _, err := h.Signer.Verify(sessionAction, c.Value, time.Now())
+	if err != nil {
+		return ""
+	}
+	return email
+}
+
+// csrf returns the token the forms of a speaker or reviewer carry, so
+// that other sites cannot post them on their behalf.
+func (h *Handler) csrf(who string) string {
+	m := hmac.New(sha256.New, h.Signer.Key)
+	m.Write([]byte("cfp-csrf\n" + who))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func (h *Handler) checkCSRF(w http.ResponseWriter, r *http.Request, who string) bool {
+	if !hmac.Equal([]byte(r.FormValue("csrf")), []byte(h.csrf(who))) {
+		http.Error(w, "Forbidden; reload the page and try again.", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func (h *Handler) message(w http.ResponseWriter, status int, title, msg string) {
+	h.render(w, status, "message", map[string]any{"Title": title, "Message": msg})
+}
+
+func (h *Handler) internalError(w http.ResponseWriter, err error) {
+	log.Printf("cfp: %v", err)
+	http.Error(w, "Something went wrong; please try again later.", http.StatusInternalServerError)
+}
+
+func (h *Handler) home(w http.ResponseWriter, r *http.Request) {
+	email := h.speaker(r)
+	data := map[string]any{
+		"Title":  h.Config.Name,
+		"Config": h.Config,
+		"Open":   h.Config.Open(time.Now()),
+		"Email":  email,
+	}
+	if email != "" {
+		props, err := h.Store.Owned(email)
+		if err != nil {
+			h.internalError(w, err)
+			return
+		}
+		data["Proposals"] = props
+		data["CSRF"] = h.csrf(email)
+	}
+	h.render(w, http.StatusOK, "home", data)
+}
+
+// signin mails a sign-in link. The answer is the same for every address.
+func (h *Handler) signin(w http.ResponseWriter, r *http.Request) {
+	addr, err := mail.ParseAddress(r.FormValue("email"))
+	if err != nil {
+		h.message(w, http.StatusBadRequest, "Sign in", "Please enter a valid email address.")
+		return
+	}
+	now := time.Now()
+	email := strings.ToLower(addr.Address)
+	h.mu.Lock()
+	send := now.Sub(h.mailed[email]) >= h.Wait
+	if send {
+		h.mailed[email] = now
+	}
+	for k, t := range h.mailed {
+		if now.Sub(t) >= h.Wait {
+			delete(h.mailed, k)
+		}
+	}
+	h.mu.Unlock()
+	if send {
+		link := h.Base + Prefix + "auth?t=" + h.Signer.Token(signinAction, email, "", now.Add(h.LinkTTL))
+		text := fmt.Sprintf("To sign in to the %s, follow this link within %d minutes:\n\n%s\n\nIf you did not ask to sign in, ignore this mail.\n",
+			h.Config.Name, int(h.LinkTTL.Minutes()), link)
+		if err := h.Mailer.Send(email, "Sign in to the "+h.Config.Name, []byte(text)); err != nil {
+			log.Printf("cfp: mailing %s: %v", email, err)
+			http.Error(w, "The sign-in mail could not be sent; please try again later.", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.message(w, http.StatusOK, "Check your inbox", "We have sent a sign-in link to "+email+".")
+}
+
+func (h *Handler) auth(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	email, _, err := h.Signer.Verify(signinAction, r.FormValue("t"), now)
+	if err != nil {
+		h.message(w, http.StatusBadRequest, "Link expired", "This sign-in link is invalid or has expired. Please ask for a new one.")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieName,
+		Value:    h.Signer.Token(sessionAction, email, "", now.Add(h.Session)),
+		Path:     Prefix,
+		MaxAge:   int(h.Session.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, Prefix, http.StatusSeeOther)
+}
+
+// edit shows a proposal of the signed-in speaker, or a new one, and
+// saves, submits or withdraws it on POST.
+func (h *Handler) edit(w http.ResponseWriter, r *http.Request) {
+	email := h.speaker(r)
+	if email == "" {
+		http.Redirect(w, r, Prefix, http.StatusSeeOther)
+		return
+	}
+	now := time.Now()
+	p := Proposal{Owner: email, State: Draft, Speakers: []Speaker{{Email: email}}}
+	if id := r.FormValue("id"); id != "" {
+		var err error
+		p, err = h.Store.Get(id)
+		if err != nil || !strings.EqualFold(p.Owner, email) {
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("cfp: %v", err)
+			}
+			http.NotFound(w, r)
+			return
+		}
+	}
+	problem := ""
+	if r.Method == "POST" {
+		if !h.checkCSRF(w, r, email) {
+			return
+		}
+		if !h.Config.Open(now) {
+			h.message(w, http.StatusForbidden, "The call is closed", "Proposals can no longer be changed.")
+			return
+		}
+		h.read(&p, r)
+		action := r.FormValue("action")
+		switch action {
+		case "withdraw":
+			if p.State == Submitted {
+				p.State = Withdrawn
+			}
+		case "submit":
+			problem = h.check(p)
+		default:
+			if p.Title == "" {
+				problem = "Please give your talk a title."
+			}
+		}
+		if problem == "" {
+			if action == "submit" {
+				p.State, p.Submitted = Submitted, now
+			}
+			if p.Created.IsZero() {
+				p.Created = now
+			}
+			p.Updated = now
+			if err := h.Store.Save(&p); err != nil {
+				h.internalError(w, err)
+				return
+			}
+			http.Redirect(w, r, Prefix, http.StatusSeeOther)
+			return
+		}
+	}
+	for len(p.Speakers) < h.Config.MaxSpeakers {
+		p.Speakers = append(p.Speakers, Speaker{})
+	}
+	status := http.StatusOK
+	if problem != "" {
+		status = http.StatusBadRequest
+	}
+	h.render(w, status, "edit", map[string]any{
+		"Title":    "Your proposal",
+		"Config":   h.Config,
+		"Open":     h.Config.Open(now),
+		"Proposal": p,
+		"Problem":  problem,
+		"CSRF":     h.csrf(email),
+	})
+}
+
+// read copies the form into p.
+func (h *Handler) read(p *Proposal, r *http.Request) {
+	p.Title = strings.TrimSpace(r.FormValue("title"))
+	p.Abstract = strings.TrimSpace(r.FormValue("abstract"))
+	p.Level = r.FormValue("level")
+	p.Notes = strings.TrimSpace(r.FormValue("notes"))
+	p.Speakers = nil
+	for i := 1; i <= h.Config.MaxSpeakers; i++ {
+		n := strconv.Itoa(i)
+		s := Speaker{
+			Name:  strings.TrimSpace(r.FormValue("speaker-name-" + n)),
+			Email: strings.TrimSpace(r.FormValue("speaker-email-" + n)),
+			Bio:   strings.TrimSpace(r.FormValue("speaker-bio-" + n)),
+		}
+		if i == 1 {
+			s.Email = p.Owner
+		}
+		if i == 1 || s != (Speaker{}) {
+			p.Speakers = append(p.Speakers, s)
+		}
+	}
+}
+
+// check returns what keeps p from being submitted, or "".
+func (h *Handler) check(p Proposal) string {
+	words := func(s string) int { return len(strings.Fields(s)) }
+	switch {
+	case p.Title == "" || p.Abstract == "":
+		return "Please fill in the title and the abstract."
+	case !slices.Contains(h.Config.Levels, p.Level):
+		return "Please choose the level of your talk."
+	case h.Config.AbstractWords > 0 && words(p.Abstract) > h.Config.AbstractWords:
+		return fmt.Sprintf("The abstract has %d words; please keep it to %d.", words(p.Abstract), h.Config.AbstractWords)
+	}
+	for _, s := range p.Speakers {
+		if s.Name == "" || s.Bio == "" {
+			return "Please give the name and bio of every speaker."
+		}
+		if s.Email != "" {
+			if _, err := mail.ParseAddress(s.Email); err != nil {
+				return s.Email + " is not a valid email address."
+			}
+		}
+		if h.Config.BioWords > 0 && words(s.Bio) > h.Config.BioWords {
+			return fmt.Sprintf("The bio of %s has %d words; please keep it to %d.", s.Name, words(s.Bio), h.Config.BioWords)
+		}
+	}
+	return ""
+}
+
+// authenticate returns the reviewer making r, after asking for
+// credentials if there is none. Requests that did not arrive privately
+// are refused.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) (Reviewer, bool) {
+	if !adminauth.Private(r) {
+		adminauth.Refuse(w)
+		return Reviewer{}, false
+	}
+	name, password, ok := r.BasicAuth()
+	if ok {
+		if rev, found := h.Reviewers[name]; found && adminauth.Check(rev.Password, password) {
+			return rev, true
+		}
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="CFP review"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return Reviewer{}, false
+}
+
+// conflicted reports whether rev has a declared conflict with a speaker
+// of p.
+func conflicted(rev Reviewer, p Proposal) bool {
+	return slices.ContainsFunc(p.Emails(), rev.Conflicted)
+}
+
+// listEntry is a line of the reviewer's list.
+type listEntry struct {
+	Proposal
+	Reviews int
+	Mine    *Review
+}
+
+// list shows the submitted proposals the reviewer may review, those
+// they have yet to review first, then those with the fewest reviews.
+func (h *Handler) list(w http.ResponseWriter, rev Reviewer) {
+	props, err := h.Store.Proposals(Submitted)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	var entries []listEntry
+	hidden := 0
+	for _, p := range props {
+		if conflicted(rev, p) {
+			hidden++
+			continue
+		}
+		reviews, err := h.Store.Reviews(p.ID)
+		if err != nil {
+			h.internalError(w, err)
+			return
+		}
+		e := listEntry{Proposal: p, Reviews: len(reviews)}
+		for i := range reviews {
+			if reviews[i].Reviewer == rev.Name {
+				e.Mine = &reviews[i]
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.Mine == nil) != (b.Mine == nil) {
+			return a.Mine == nil
+		}
+		return a.Reviews < b.Reviews
+	})
+	h.render(w, http.StatusOK, "list", map[string]any{
+		"Title":    h.Config.Name + ": review",
+		"Reviewer": rev.Name,
+		"Entries":  entries,
+		"Hidden":   hidden,
+	})
+}
+
+// review shows a proposal without its speakers, with the reviewer's
+// review, and saves the review on POST.
+func (h *Handler) review(w http.ResponseWriter, r *http.Request, rev Reviewer, id string) {
+	p, err := h.Store.Get(id)
+	if err != nil || p.State != Submitted || conflicted(rev, p) {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("cfp: %v", err)
+		}
+		http.NotFound(w, r)
+		return
+	}
+	reviews, err := h.Store.Reviews(p.ID)
+	if err != nil {
+		h.internalError(w, err)
+		return
+	}
+	mine := Review{Reviewer: rev.Name}
+	for _, old := range reviews {
+		if old.Reviewer == rev.Name {
+			mine = old
+		}
+	}
+	problem := ""
+	if r.Method == "POST" {
+		if !h.checkCSRF(w, r, "review\n"+rev.Name) {
+			return
+		}
+		mine = Review{
+			Reviewer: rev.Name,
+			Comment:  strings.TrimSpace(r.FormValue("comment")),
+			Conflict: r.FormValue("conflict") != "",
+			Updated:  time.Now(),
+		}
+		if !mine.Conflict {
+			mine.Score, err = strconv.Atoi(r.FormValue("score"))
+			if err != nil || mine.Score < 1 || mine.Score > h.Config.MaxScore {
+				problem = fmt.Sprintf("Please give a score from 1 to %d, or flag a conflict of interest.", h.Config.MaxScore)
+			}
+		}
+		if problem == "" {
+			if err := h.Store.SaveReview(p.ID, mine); err != nil {
+				h.internalError(w, err)
+				return
+			}
+			http.Redirect(w, r, Prefix+"review/", http.StatusSeeOther)
+			return
+		}
+	}
+	status := http.StatusOK
+	if problem != "" {
+		status = http.StatusBadRequest
+	}
+	var scores []int
+	for i := 1; i <= h.Config.MaxScore; i++ {
+		scores = append(scores, i)
+	}
+	h.render(w, status, "review", map[string]any{
+		"Title":    p.Title,
+		"Proposal": p,
+		"Abstract": paragraphs(p.Abstract),
+		"Review":   mine,
+		"Scores":   scores,
+		"Problem":  problem,
+		"CSRF":     h.csrf("review\n" + rev.Name),
+	})
+}
+
+// paragraphs splits text at blank lines.
+func paragraphs(text string) []string {
+	var out []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+func (h *Handler) render(w http.ResponseWriter, status int, name string, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("cfp: %v", err)
+	}
+}
+
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <meta name="robots" content="noindex">
+  <link href="/css/bootstrap.min.css" rel="stylesheet">
+  <link href="/css/hc.css" rel="stylesheet">
+</head>
+<body>
+  <div class="container">
+    <div id="article-body">
+      <div class="article-title">{{.Title}}</div>
+{{end}}
+
+{{define "foot"}}
+    </div>
+  </div>
+</body>
+</html>
+{{end}}
+
+{{define "message"}}{{template "head" .}}
+      <p>{{.Message}}</p>
+      <p><a href="/cfp/">Back to the call for papers</a></p>
+{{template "foot" .}}{{end}}
+
+{{define "home"}}{{template "head" .}}
+      <p>Propose a talk for {{.Config.Conference}}. The call is open from
+      {{.Config.Opens.Format "January 2"}} to {{.Config.Closes.Format "January 2, 2006"}};
+      until then you can save drafts and edit your proposals, submitted or not.
+      Reviewers read your title and abstract without your name or bio.</p>
+      {{- if not .Email}}
+      <form method="post" action="/cfp/signin" class="form-inline">
+        <input type="email" name="email" class="form-control" placeholder="you@example.com" required>
+        <button class="btn btn-default" type="submit">Mail me a sign-in link</button>
+      </form>
+      {{- else}}
+      <p class="meta">Signed in as {{.Email}}.</p>
+      {{- if .Proposals}}
+      <table class="table">
+        <tr><th>Talk</th><th>Level</th><th>State</th><th>Updated</th></tr>
+        {{- range .Proposals}}
+        <tr>
+          <td><a href="/cfp/edit?id={{.ID}}">{{.Title}}</a></td>
+          <td>{{.Level}}</td>
+          <td>{{.State}}</td>
+          <td>{{.Updated.Format "2006-01-02 15:04"}}</td>
+        </tr>
+        {{- end}}
+      </table>
+      {{- else}}
+      <p>You have no proposals yet.</p>
+      {{- end}}
+      {{- if .Open}}
+      <p><a class="btn btn-primary" href="/cfp/edit">New proposal</a></p>
+      {{- else}}
+      <p>The call is closed.</p>
+      {{- end}}
+      <form method="post" action="/cfp/signout"><button class="btn btn-link" type="submit">Sign out</button></form>
+      {{- end}}
+      {{- with .Config.Contact}}
+      <p class="meta">Questions? Write to <a href="mailto:{{.}}">{{.}}</a>.</p>
+      {{- end}}
+{{template "foot" .}}{{end}}
+
+{{define "edit"}}{{template "head" .}}
+      {{- with .Proposal}}
+      <p class="meta">{{if .ID}}This proposal is {{.State}}.{{else}}New proposal.{{end}}
+      <a href="/cfp/">All your proposals</a></p>
+      {{- end}}
+      {{- with .Problem}}
+      <p class="alert alert-warning">{{.}}</p>
+      {{- end}}
+      <form method="post" action="/cfp/edit">
+        <input type="hidden" name="csrf" value="{{.CSRF}}">
+        <input type="hidden" name="id" value="{{.Proposal.ID}}">
+        <input type="text" name="title" class="form-control" placeholder="Title" value="{{.Proposal.Title}}" required>
+        <select name="level" class="form-control">
+          <option value="">Level</option>
+          {{- range .Config.Levels}}
+          <option{{if eq . $.Proposal.Level}} selected{{end}}>{{.}}</option>
+          {{- end}}
+        </select>
+        <textarea name="abstract" class="form-control" rows="10"
+          placeholder="Abstract{{with .Config.AbstractWords}}, at most {{.}} words{{end}}. Leave out who you are: reviewers see only this.">{{.Proposal.Abstract}}</textarea>
+        {{- range $i, $s := .Proposal.Speakers}}
+        <h4>Speaker {{add $i 1}}{{if $i}} (optional){{end}}</h4>
+        <input type="text" name="speaker-name-{{add $i 1}}" class="form-control" placeholder="Name" value="{{$s.Name}}">
+        {{- if $i}}
+        <input type="email" name="speaker-email-{{add $i 1}}" class="form-control" placeholder="Email" value="{{$s.Email}}">
+        {{- end}}
+        <textarea name="speaker-bio-{{add $i 1}}" class="form-control" rows="4"
+          placeholder="Bio{{with $.Config.BioWords}}, at most {{.}} words{{end}}">{{$s.Bio}}</textarea>
+        {{- end}}
+        <textarea name="notes" class="form-control" rows="3" placeholder="Notes for the organizers, not shown to reviewers">{{.Proposal.Notes}}</textarea>
+        {{- if .Open}}
+        <button class="btn btn-default" name="action" value="save">Save draft</button>
+        <button class="btn btn-primary" name="action" value="submit">{{if eq .Proposal.State "submitted"}}Save{{else}}Submit{{end}}</button>
+        {{- if eq .Proposal.State "submitted"}}
+        <button class="btn btn-link" name="action" value="withdraw">Withdraw</button>
+        {{- end}}
+        {{- end}}
+      </form>
+{{template "foot" .}}{{end}}
+
+{{define "list"}}{{template "head" .}}
+      <p class="meta">Signed in as {{.Reviewer}}. Speakers are hidden.
+      {{- with .Hidden}} {{.}} proposals are hidden because of your declared conflicts.{{end}}</p>
+      {{- if .Entries}}
+      <table class="table">
+        <tr><th>Talk</th><th>Level</th><th>Reviews</th><th>Your review</th></tr>
+        {{- range .Entries}}
+        <tr>
+          <td><a href="/cfp/review/{{.ID}}">{{.Title}}</a></td>
+          <td>{{.Level}}</td>
+          <td>{{.Reviews}}</td>
+          <td>{{with .Mine}}{{if .Conflict}}conflict{{else}}{{.Score}}{{end}}{{else}}not reviewed{{end}}</td>
+        </tr>
+        {{- end}}
+      </table>
+      {{- else}}
+      <p>No proposals to review yet.</p>
+      {{- end}}
+{{template "foot" .}}{{end}}
+
+{{define "review"}}{{template "head" .}}
+      <p class="meta">{{.Proposal.Level}}. <a href="/cfp/review/">All proposals</a></p>
+      {{- range .Abstract}}
+      <p>{{.}}</p>
+      {{- end}}
+      <h3>Your review</h3>
+      {{- with .Problem}}
+      <p class="alert alert-warning">{{.}}</p>
+      {{- end}}
+      <form method="post">
+        <input type="hidden" name="csrf" value="{{.CSRF}}">
+        <p>
+          {{- range .Scores}}
+          <label class="radio-inline"><input type="radio" name="score" value="{{.}}"{{if eq . $.Review.Score}} checked{{end}}> {{.}}</label>
+          {{- end}}
+        </p>
+        <textarea name="comment" class="form-control" rows="4" placeholder="Comments for the organizers">{{.Review.Comment}}</textarea>
+        <label><input type="checkbox" name="conflict" value="yes"{{if .Review.Conflict}} checked{{end}}>
+          I have a conflict of interest: I recognize this proposal as one from a colleague, a friend or myself.</label>
+        <button class="btn btn-primary" type="submit">Save review</button>
+      </form>
+{{template "foot" .}}{{end}}
+`))
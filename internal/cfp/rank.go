@@ -0,0 +1,55 @@
+package cfp
+
+import "sort"
+
+// Ranked is a submitted proposal with its standing.
+type Ranked struct {
+	Proposal
+	// Score is the mean score of the reviews without a conflict.
+	Score     float64
+	Reviews   int
+	Conflicts int
+	Decision  Decision
+}
+
+// Rank returns the submitted proposals ordered by their mean score,
+// best first; proposals without scores come last, oldest first.
+func Rank(s *Store) ([]Ranked, error) {
+	props, err := s.Proposals(Submitted)
+	if err != nil {
+		return nil, err
+	}
+	ranked := make([]Ranked, len(props))
+	for i, p := range props {
+		reviews, err := s.Reviews(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		d, err := s.Decision(p.ID)
+		if err != nil {
+			return nil, err
+		}
+		r := Ranked{Proposal: p, Decision: d}
+		for _, rev := range reviews {
+			switch {
+			case rev.Conflict:
+				r.Conflicts++
+			case rev.Score > 0:
+				r.Score += float64(rev.Score)
+				r.Reviews++
+			}
+		}
+		if r.Reviews > 0 {
+			r.Score /= float64(r.Reviews)
+		}
+		ranked[i] = r
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if (a.Reviews == 0) != (b.Reviews == 0) {
+			return b.Reviews == 0
+		}
+		return a.Score > b.Score
+	})
+	return ranked, nil
+}
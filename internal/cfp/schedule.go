@@ -0,0 +1,125 @@
+package cfp
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ScheduleFile is the conference schedule, relative to the repository
+// root. The main site lists its talks (layouts-main/partials/schedule.html).
+const ScheduleFile = "data/schedule.toml"
+
+// Schedule is the conference schedule.
+type Schedule struct {
+	Conference string `toml:"conference"`
+	Talks      []Talk `toml:"talk"`
+}
+
+// Talk is a talk of the schedule. Talks from the call for papers have
+// the proposal's id; others, such as keynotes, are added by hand with
+// any other id.
+type Talk struct {
+	ID       string        `toml:"id"`
+	Title    string        `toml:"title"`
+	Level    string        `toml:"level,omitempty"`
+	Abstract string        `toml:"abstract,omitempty"`
+	Speakers []TalkSpeaker `toml:"speaker"`
+	// Start, Minutes and Room are filled in by hand and kept when the
+	// talks are exported again.
+	Start   time.Time `toml:"start,omitempty"`
+	Minutes int       `toml:"minutes,omitzero"`
+	Room    string    `toml:"room,omitempty"`
+}
+
+// TalkSpeaker is a speaker as the schedule shows them.
+type TalkSpeaker struct {
+	Name string `toml:"name"`
+	Bio  string `toml:"bio,omitempty"`
+}
+
+// LoadSchedule reads the schedule in file. A missing file is an empty
+// schedule.
+func LoadSchedule(file string) (*Schedule, error) {
+	s := &Schedule{}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(b), s); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return s, nil
+}
+
+// Save writes the schedule to file, talks in order of their start,
+// unscheduled talks last.
+func (s *Schedule) Save(file string) error {
+	sort.SliceStable(s.Talks, func(i, j int) bool {
+		a, b := s.Talks[i], s.Talks[j]
+		if a.Start.IsZero() != b.Start.IsZero() {
+			return b.Start.IsZero()
+		}
+		if !a.Start.Equal(b.Start) {
+			return a.Start.Before(b.Start)
+		}
+		return a.Title < b.Title
+	})
+	var buf bytes.Buffer
+	buf.WriteString("# Talks of the conference schedule on www.gopheracademy.com. The accepted\n# talks of the call for papers are added by cmd/cfp export; set their\n# start, minutes and room here, and add keynotes and breaks by hand.\n\n")
+	if err := toml.NewEncoder(&buf).Encode(s); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// Merge brings the talks from the call for papers up to date: it adds
+// or updates a talk for each accepted proposal, keeping the slot already
+// set, and removes the talks of the other proposals in all, as after a
+// speaker cancels. It returns the titles of the talks added and removed.
+func (s *Schedule) Merge(accepted, all []Proposal) (added, removed []string) {
+	isAccepted := make(map[string]bool)
+	for _, p := range accepted {
+		isAccepted[p.ID] = true
+	}
+	fromCFP := make(map[string]bool)
+	for _, p := range all {
+		fromCFP[p.ID] = true
+	}
+	var talks []Talk
+	index := make(map[string]int)
+	for _, t := range s.Talks {
+		if fromCFP[t.ID] && !isAccepted[t.ID] {
+			removed = append(removed, t.Title)
+			continue
+		}
+		index[t.ID] = len(talks)
+		talks = append(talks, t)
+	}
+	for _, p := range accepted {
+		t := Talk{ID: p.ID}
+		if i, ok := index[p.ID]; ok {
+			t = talks[i]
+		}
+		t.Title, t.Level, t.Abstract = p.Title, p.Level, p.Abstract
+		t.Speakers = nil
+		for _, sp := range p.Speakers {
+			t.Speakers = append(t.Speakers, TalkSpeaker{Name: sp.Name, Bio: sp.Bio})
+		}
+		if i, ok := index[p.ID]; ok {
+			talks[i] = t
+			continue
+		}
+		added = append(added, t.Title)
+		talks = append(talks, t)
+	}
+	s.Talks = talks
+	return added, removed
+}
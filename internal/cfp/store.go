@@ -0,0 +1,320 @@
+package cfp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Proposal states.
+const (
+	Draft     = "draft"
+	Submitted = "submitted"
+	Withdrawn = "withdrawn"
+)
+
+// Decision outcomes.
+const (
+	Accepted = "accepted"
+	Rejected = "rejected"
+)
+
+// Proposal is a talk proposal.
+type Proposal struct {
+	ID string `json:"id"`
+	// Owner is the address of the speaker who signed in to write it.
+	Owner    string    `json:"owner"`
+	Title    string    `json:"title"`
+	Abstract string    `json:"abstract"`
+	Level    string    `json:"level"`
+	Speakers []Speaker `json:"speakers"`
+	// Notes are for the organizers, not the reviewers.
+	Notes     string    `json:"notes,omitempty"`
+	State     string    `json:"state"`
+	Created   time.Time `json:"created"`
+	Updated   time.Time `json:"updated"`
+	Submitted time.Time `json:"submitted,omitzero"`
+}
+
+// Speaker is one of the speakers of a talk.
+type Speaker struct {
+	Name string `json:"name"`
+	// Email is optional for speakers other than the owner.
+	Email string `json:"email,omitempty"`
+	Bio   string `json:"bio"`
+}
+
+// Emails returns the addresses of the proposal's speakers.
+func (p Proposal) Emails() []string {
+	emails := []string{p.Owner}
+	for _, s := range p.Speakers {
+		if s.Email != "" && !strings.EqualFold(s.Email, p.Owner) {
+			emails = append(emails, s.Email)
+		}
+	}
+	return emails
+}
+
+// SpeakerNames returns the names of the speakers joined with "and".
+func (p Proposal) SpeakerNames() string {
+	var names []string
+	for _, s := range p.Speakers {
+		names = append(names, s.Name)
+	}
+	return strings.Join(names, " and ")
+}
+
+// Review is one reviewer's review of a proposal.
+type Review struct {
+	Reviewer string `json:"reviewer"`
+	Score    int    `json:"score,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	// Conflict is set by reviewers who recognize a proposal they should
+	// not judge; their score is then left out.
+	Conflict bool      `json:"conflict,omitempty"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Decision is the organizers' decision on a proposal.
+type Decision struct {
+	Outcome string    `json:"outcome"`
+	Decided time.Time `json:"decided"`
+}
+
+// Store keeps the proposals in a directory with one subdirectory per
+// proposal:
+//
+//	<id>/proposal.json   the proposal, written by the server
+//	<id>/reviews.json    its reviews, written by the server
+//	<id>/decision.json   the decision, written by cmd/cfp
+//
+// Every file is replaced atomically and read afresh on each call, so the
+// server and cmd/cfp can use the store at the same time.
+type Store struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir}, nil
+}
+
+func validID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func notFound(id string) error {
+	return fmt.Errorf("cfp: no proposal %q: %w", id, os.ErrNotExist)
+}
+
+func (s *Store) path(id string, elem ...string) string {
+	return filepath.Join(append([]string{s.Dir, id}, elem...)...)
+}
+
+// readJSON decodes file into v. A missing file leaves v alone.
+func readJSON(file string, v any) error {
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
+
+// writeJSON replaces file with the encoding of v.
+func writeJSON(file string, v any) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Save stores p, giving it an ID if it has none.
+func (s *Store) Save(p *Proposal) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.ID == "" {
+		var id [8]byte
+		rand.Read(id[:])
+		p.ID = hex.EncodeToString(id[:])
+		if err := os.MkdirAll(s.path(p.ID), 0700); err != nil {
+			return err
+		}
+	} else if !validID(p.ID) {
+		return notFound(p.ID)
+	}
+	return writeJSON(s.path(p.ID, "proposal.json"), p)
+}
+
+// Get returns the proposal with the given id, or an error wrapping
+// os.ErrNotExist.
+func (s *Store) Get(id string) (Proposal, error) {
+	var p Proposal
+	if !validID(id) {
+		return p, notFound(id)
+	}
+	b, err := os.ReadFile(s.path(id, "proposal.json"))
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(b, &p); err != nil {
+		return p, fmt.Errorf("%s: %v", id, err)
+	}
+	return p, nil
+}
+
+// Proposals returns the proposals in one of the states, or all of them
+// if no state is given, oldest first.
+func (s *Store) Proposals(states ...string) ([]Proposal, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var out []Proposal
+	for _, e := range entries {
+		if !e.IsDir() || !validID(e.Name()) {
+			continue
+		}
+		p, err := s.Get(e.Name())
+		if errors.Is(err, os.ErrNotExist) {
+			continue // being created
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(states) == 0 || contains(states, p.State) {
+			out = append(out, p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Owned returns the proposals written by email, oldest first.
+func (s *Store) Owned(email string) ([]Proposal, error) {
+	all, err := s.Proposals()
+	if err != nil {
+		return nil, err
+	}
+	var out []Proposal
+	for _, p := range all {
+		if strings.EqualFold(p.Owner, email) {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// Reviews returns the reviews of the proposal, by reviewer.
+func (s *Store) Reviews(id string) ([]Review, error) {
+	if !validID(id) {
+		return nil, notFound(id)
+	}
+	var reviews []Review
+	if err := readJSON(s.path(id, "reviews.json"), &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// SaveReview records r, replacing the reviewer's earlier review of the
+// proposal.
+func (s *Store) SaveReview(id string, r Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	reviews, err := s.Reviews(id)
+	if err != nil {
+		return err
+	}
+	out := []Review{r}
+	for _, old := range reviews {
+		if old.Reviewer != r.Reviewer {
+			out = append(out, old)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Reviewer < out[j].Reviewer })
+	return writeJSON(s.path(id, "reviews.json"), out)
+}
+
+// Decision returns the decision on the proposal; its Outcome is "" if
+// none has been made.
+func (s *Store) Decision(id string) (Decision, error) {
+	var d Decision
+	if !validID(id) {
+		return d, notFound(id)
+	}
+	return d, readJSON(s.path(id, "decision.json"), &d)
+}
+
+// SaveDecision records the decision on the proposal.
+func (s *Store) SaveDecision(id string, d Decision) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return writeJSON(s.path(id, "decision.json"), d)
+}
+
+// Find returns the proposal whose id is key or starts with key, which
+// must then be at least six characters long.
+func (s *Store) Find(key string) (Proposal, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if validID(key) {
+		return s.Get(key)
+	}
+	all, err := s.Proposals()
+	if err != nil {
+		return Proposal{}, err
+	}
+	var found []Proposal
+	for _, p := range all {
+		if len(key) >= 6 && strings.HasPrefix(p.ID, key) {
+			found = append(found, p)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Proposal{}, notFound(key)
+	case 1:
+		return found[0], nil
+	}
+	return Proposal{}, fmt.Errorf("cfp: %q matches %d proposals", key, len(found))
+}
@@ -0,0 +1,129 @@
+// Package components finds markdown that imitates one of the site's
+// content components and suggests the component instead:
+//
+//	{{% callout type="note" %}} ... {{% /callout %}}   also warning and tip
+//	{{< figure src="..." caption="..." credit="..." >}}
+//	{{% aside %}} ... {{% /aside %}}
+//	text[^1] ... [^1]: the footnote
+//
+// The imitations are blockquotes and paragraphs that open with "Note:" or
+// "Warning:", an italic line under an image standing in for its caption,
+// paragraphs that open with "Side note:", and footnotes numbered by hand.
+// The shortcodes render the same way in both layouts.
+package components
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Finding is ad-hoc markup that a component should replace.
+type Finding struct {
+	File string
+	Line int
+	// Component is the component to use: callout, figure, aside or
+	// footnote.
+	Component string
+	Message   string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", f.File, f.Line, f.Component, f.Message)
+}
+
+var (
+	calloutRE  = regexp.MustCompile(`(?i)^(>\s*)?(?:\*\*|__|\*|_)?(note|warning|caution|important|tip|heads up|editor'?s note)\s*(?:\*\*|__|\*|_)?\s*[:!]`)
+	imageRE    = regexp.MustCompile(`^\s*(?:!\[[^\]]*\]\([^)]*\)|<img\s[^>]*>)\s*$`)
+	italicRE   = regexp.MustCompile(`^\s*(?:\*[^*\s][^*]*\*|_[^_\s][^_]*_|<(em|i|small)>.*</(?:em|i|small)>)\s*$`)
+	asideRE    = regexp.MustCompile(`(?i)^\(?(?:\*\*|__|\*|_)?(side ?note|aside|as an aside)\s*(?:\*\*|__|\*|_)?\s*[:,]`)
+	noteMarkRE = regexp.MustCompile(`[^\s\]](?:\[(\d{1,2})\]|<sup>\s*(\d{1,2}|\*)\s*</sup>)`)
+	noteDefRE  = regexp.MustCompile(`^\s*(?:\[(\d{1,2})\]|<sup>\s*(\d{1,2}|\*)\s*</sup>|(\d{1,2}|\*)\))[^:(\[]`)
+)
+
+// calloutType maps the word that opens an imitated callout to its type.
+func calloutType(word string) string {
+	switch strings.ToLower(word) {
+	case "warning", "caution", "important":
+		return "warning"
+	case "tip":
+		return "tip"
+	}
+	return "note"
+}
+
+// Lint returns the imitated components in a markdown body. file and
+// line, the line the body starts on, position them.
+func Lint(file string, line int, body []byte) []Finding {
+	var out []Finding
+	add := func(i int, component, format string, args ...any) {
+		out = append(out, Finding{File: file, Line: line + i, Component: component, Message: fmt.Sprintf(format, args...)})
+	}
+
+	lines := strings.Split(string(content.MaskCode(body)), "\n")
+	blank := func(i int) bool {
+		return i < 0 || i >= len(lines) || strings.TrimSpace(lines[i]) == ""
+	}
+	marks := make(map[string]int)
+	for i, l := range lines {
+		trimmed := strings.TrimSpace(l)
+		// starts is true on the first line of a paragraph or blockquote.
+		starts := blank(i - 1)
+		if strings.HasPrefix(trimmed, ">") && i > 0 && !strings.HasPrefix(strings.TrimSpace(lines[i-1]), ">") {
+			starts = true
+		}
+		if m := calloutRE.FindStringSubmatch(trimmed); starts && m != nil {
+			kind := "paragraph"
+			if m[1] != "" {
+				kind = "blockquote"
+			}
+			add(i, "callout", "%s starting with %q; use {{%% callout type=%q %%}}", kind, m[2], calloutType(m[2]))
+			continue
+		}
+		if starts && asideRE.MatchString(trimmed) {
+			add(i, "aside", "side note written as a paragraph; use {{%% aside %%}}")
+			continue
+		}
+		if imageRE.MatchString(l) {
+			next := i + 1
+			if blank(next) {
+				next++
+			}
+			if next < len(lines) && italicRE.MatchString(lines[next]) && blank(next+1) {
+				add(i, "figure", "italic caption under an image; use {{< figure src=... caption=... credit=... >}}")
+			}
+			continue
+		}
+		if starts {
+			if m := noteDefRE.FindStringSubmatch(l); m != nil {
+				n := m[1] + m[2] + m[3]
+				if _, ok := marks[n]; ok {
+					add(i, "footnote", "footnote %s numbered by hand; use [^%s] and [^%s]: in markdown", n, footnoteLabel(n), footnoteLabel(n))
+					delete(marks, n)
+					continue
+				}
+			}
+		}
+		for _, m := range noteMarkRE.FindAllStringSubmatch(l, -1) {
+			n := m[1] + m[2]
+			if _, ok := marks[n]; !ok {
+				marks[n] = i
+			}
+		}
+	}
+	return out
+}
+
+func footnoteLabel(n string) string {
+	if n == "*" {
+		return "note"
+	}
+	return n
+}
+
+// LintArticle lints an article body.
+func LintArticle(a *content.Article) []Finding {
+	return Lint(a.File, a.Meta.BodyLine(), a.Body())
+}
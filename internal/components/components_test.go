@@ -0,0 +1,145 @@
+package components
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRegexps(t *testing.T) {
+	tests := []struct {
+		name string
+		re   *regexp.Regexp
+		in   string
+		want bool
+	}{
+		{"callout", calloutRE, "Note: run go vet first.", true},
+		{"callout", calloutRE, "**Warning**: this deletes files.", true},
+		{"callout", calloutRE, "> _Tip!_ Use gofmt.", true},
+		{"callout", calloutRE, "Editor's note: updated in 2015.", true},
+		{"callout", calloutRE, "heads up: the API changed", true},
+		{"callout", calloutRE, "- Note: items in a list are left alone", false},
+		{"callout", calloutRE, "1. Warning: so are numbered ones", false},
+		{"callout", calloutRE, "Notes on the design follow.", false},
+		{"callout", calloutRE, "Note that the map is not sorted.", false},
+		{"callout", calloutRE, "As a note: only at the start.", false},
+
+		{"image", imageRE, "![Gopher](/images/gopher.png)", true},
+		{"image", imageRE, `  <img src="/images/gopher.png" alt="Gopher">`, true},
+		{"image", imageRE, "See ![Gopher](/images/gopher.png) here.", false},
+
+		{"italic", italicRE, "*The gopher, by Renée French*", true},
+		{"italic", italicRE, "_Figure 1: the pipeline_", true},
+		{"italic", italicRE, "<small>Photo by the author</small>", true},
+		{"italic", italicRE, "**Bold is not a caption**", false},
+		{"italic", italicRE, "*Emphasis* followed by prose.", false},
+		{"italic", italicRE, "* a list item *", false},
+
+		{"aside", asideRE, "Side note: channels are typed.", true},
+		{"aside", asideRE, "(Aside, this is unrelated.)", true},
+		{"aside", asideRE, "**As an aside**: hi", true},
+		{"aside", asideRE, "Asides are rare.", false},
+
+		{"mark", noteMarkRE, "is fast[1] and small", true},
+		{"mark", noteMarkRE, "is fast<sup>2</sup>.", true},
+		{"mark", noteMarkRE, "is fast<sup>*</sup>.", true},
+		{"mark", noteMarkRE, "a list [1] with a space", false},
+		{"mark", noteMarkRE, "a[123] has three digits", false},
+		{"mark", noteMarkRE, "see [the docs][1]", false},
+
+		{"definition", noteDefRE, "[1] Go Blog, 2014.", true},
+		{"definition", noteDefRE, "<sup>2</sup> Measured on a laptop.", true},
+		{"definition", noteDefRE, "*) Not counting tests.", true},
+		{"definition", noteDefRE, "[1]: https://golang.org/", false},
+		{"definition", noteDefRE, "[1](https://golang.org/) is a link", false},
+		{"definition", noteDefRE, "[1][2] is a reference", false},
+		{"definition", noteDefRE, "Item [1] is not at the start", false},
+	}
+	for _, tt := range tests {
+		if got := tt.re.MatchString(tt.in); got != tt.want {
+			t.Errorf("%s %q: match %v, want %v", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		body []string
+		want []string // "line component"
+	}{
+		{
+			name: "callout paragraph and blockquote",
+			body: []string{"Note: first.", "", "Prose.", "> **Warning**: quoted."},
+			want: []string{"10 callout", "13 callout"},
+		},
+		{
+			name: "callout word within a paragraph",
+			body: []string{"Some prose", "Note: on the second line."},
+		},
+		{
+			name: "note inside a list",
+			body: []string{"Steps:", "", "- Note: the first step", "- Warning: the second"},
+		},
+		{
+			name: "note inside code",
+			body: []string{"```", "Note: printed by the tool", "```"},
+		},
+		{
+			name: "caption under an image",
+			body: []string{"![Gopher](/images/gopher.png)", "*The gopher*", "", "Prose."},
+			want: []string{"10 figure"},
+		},
+		{
+			name: "caption after a blank line",
+			body: []string{"![Gopher](/images/gopher.png)", "", "_The gopher_"},
+			want: []string{"10 figure"},
+		},
+		{
+			name: "italic line not under an image",
+			body: []string{"Prose.", "", "*An emphasised line.*", "", "More prose."},
+		},
+		{
+			name: "italic line continuing a paragraph under an image",
+			body: []string{"![Gopher](/images/gopher.png)", "*The gopher*", "and more text"},
+		},
+		{
+			name: "aside",
+			body: []string{"Side note: this is long."},
+			want: []string{"10 aside"},
+		},
+		{
+			name: "footnote by hand",
+			body: []string{"Go is fast[1] and small<sup>2</sup>.", "", "[1] Benchmarks.", "", "<sup>2</sup> Binaries."},
+			want: []string{"12 footnote", "14 footnote"},
+		},
+		{
+			name: "array indexing in prose",
+			body: []string{"Then a[1] holds the second element.", "", "[1]: https://golang.org/ref/spec#Index_expressions"},
+		},
+		{
+			name: "array indexing and a list",
+			body: []string{"Then a[1] holds it.", "", "1) First", "2) Second"},
+			want: []string{"12 footnote"},
+		},
+		{
+			name: "numbered paragraph without a mark",
+			body: []string{"Prose.", "", "[2] is a list marker here."},
+		},
+		{
+			name: "mark inside code",
+			body: []string{"Use `a[1]` to index.", "", "[1] Not a footnote."},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, f := range Lint("post.md", 10, []byte(strings.Join(tt.body, "\n")+"\n")) {
+			got = append(got, fmt.Sprintf("%d %s", f.Line, f.Component))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
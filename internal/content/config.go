@@ -0,0 +1,49 @@
+package content
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigFiles are the Hugo configs of the two sites built from the
+// repository: the blog and the main site.
+var ConfigFiles = []string{"config.toml", "config-main.toml"}
+
+// Config is a Hugo site config.
+type Config struct {
+	// File is the config file name.
+	File            string            `toml:"-"`
+	BaseURL         string            `toml:"baseurl"`
+	LanguageCode    string            `toml:"languageCode"`
+	Title           string            `toml:"title"`
+	ContentDir      string            `toml:"contentdir"`
+	LayoutDir       string            `toml:"layoutdir"`
+	PublishDir      string            `toml:"publishdir"`
+	DisqusShortname string            `toml:"disqusShortname"`
+	Taxonomies      map[string]string `toml:"taxonomies"`
+	Params          map[string]any    `toml:"params"`
+}
+
+// ReadConfig reads a Hugo config, filling in Hugo's defaults for the
+// directories it does not set.
+func ReadConfig(file string) (*Config, error) {
+	c := &Config{File: filepath.Base(file)}
+	if _, err := toml.DecodeFile(file, c); err != nil {
+		if _, ok := err.(toml.ParseError); ok {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		return nil, err
+	}
+	if c.ContentDir == "" {
+		c.ContentDir = "content"
+	}
+	if c.LayoutDir == "" {
+		c.LayoutDir = "layouts"
+	}
+	if c.PublishDir == "" {
+		c.PublishDir = "public"
+	}
+	return c, nil
+}
@@ -0,0 +1,283 @@
+// Package content loads the articles of the site: the markdown files
+// under content/ and the submissions waiting in upcoming/, with their
+// +++ TOML front matter, and the authors and series they belong to.
+//
+// It is shared by every tool that reads or edits content, so that they
+// agree on how files map to URLs and how metadata is read, and so that
+// edits to front matter go through FrontMatter, which leaves everything
+// it was not asked to change untouched.
+package content
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UpcomingDir holds submitted articles that are not published yet.
+const UpcomingDir = "upcoming"
+
+// Article is a content file.
+type Article struct {
+	// File is the path relative to the repository root, with forward
+	// slashes, e.g. "content/advent-2014/delve.md".
+	File string
+	// Section is the directory below the content root, e.g. "advent-2014",
+	// or "" for top-level articles.
+	Section string
+	// Slug is the file name without extension.
+	Slug      string
+	Title     string
+	LinkTitle string
+	Date      time.Time
+	Authors   []string
+	Series    []string
+	Tags      []string
+	Draft     bool
+	// Upcoming is set for submissions under upcoming/.
+	Upcoming bool
+	// Meta is the full front matter, for keys without a field above and
+	// for editing.
+	Meta *FrontMatter
+}
+
+// URL returns the path Hugo publishes the article at.
+func (a *Article) URL() string {
+	return path.Clean("/"+path.Join(a.Section, a.Slug)) + "/"
+}
+
+// Body returns the markdown after the front matter.
+func (a *Article) Body() []byte {
+	return a.Meta.Body()
+}
+
+// Image returns the picture that represents the article in previews:
+// the image set in front matter, or else the first image of the body.
+func (a *Article) Image() string {
+	if img := a.Meta.String("image"); img != "" {
+		return img
+	}
+	return FirstImage(a.Body())
+}
+
+// Summary returns the opening of the article as plain text, at most n
+// bytes long.
+func (a *Article) Summary(n int) string {
+	if d := a.Meta.String("description"); d != "" {
+		return d
+	}
+	return Summary(a.Body(), n)
+}
+
+// Published reports whether the article is rendered on the site.
+func (a *Article) Published() bool {
+	return !a.Upcoming && !a.Draft
+}
+
+// Save writes the article, with any front matter changes, back to disk
+// under root.
+func (a *Article) Save(root string) error {
+	return os.WriteFile(filepath.Join(root, filepath.FromSlash(a.File)), a.Meta.Bytes(), 0644)
+}
+
+// Author is a contributor and the articles they wrote.
+type Author struct {
+	Name     string
+	Articles []*Article
+}
+
+// Series is a named set of articles, such as an advent calendar.
+type Series struct {
+	Name string
+	// Slug is the name as Hugo urlizes it for /series/<slug>/.
+	Slug     string
+	Articles []*Article
+}
+
+// Urlize converts a taxonomy term to its URL form, as Hugo does.
+func Urlize(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), "-"))
+}
+
+// ReadArticle reads the content file at file, relative to root. dir is
+// the content directory the file lives in.
+func ReadArticle(root, dir, file string) (*Article, error) {
+	b, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, err
+	}
+	fm, err := ParseFile(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	rel := strings.TrimPrefix(strings.TrimPrefix(file, dir), "/")
+	section := path.Dir(rel)
+	if section == "." {
+		section = ""
+	}
+	a := &Article{
+		File:      file,
+		Section:   section,
+		Slug:      strings.TrimSuffix(path.Base(rel), path.Ext(rel)),
+		Title:     fm.String("title"),
+		LinkTitle: fm.String("linktitle"),
+		Date:      fm.Time("date"),
+		Authors:   fm.Strings("author"),
+		Series:    fm.Strings("series"),
+		Tags:      fm.Strings("tags"),
+		Draft:     fm.Bool("draft"),
+		Upcoming:  dir == UpcomingDir,
+		Meta:      fm,
+	}
+	if slug := fm.String("slug"); slug != "" {
+		a.Slug = slug
+	}
+	return a, nil
+}
+
+// Library is every article of the repository, with the configs of the
+// sites built from them.
+type Library struct {
+	Root    string
+	Configs []*Config
+	// Articles are sorted by date, then file.
+	Articles []*Article
+	// Authors and Series are sorted by name.
+	Authors []*Author
+	Series  []*Series
+}
+
+// Load reads the site configs under root, then every markdown file with
+// front matter in their content directories and in upcoming/. Files
+// without front matter, such as section READMEs, are skipped.
+func Load(root string) (*Library, error) {
+	lib := &Library{Root: root}
+	dirs := []string{}
+	for _, name := range ConfigFiles {
+		c, err := ReadConfig(filepath.Join(root, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		lib.Configs = append(lib.Configs, c)
+		dirs = appendNew(dirs, c.ContentDir)
+	}
+	if len(dirs) == 0 {
+		dirs = []string{"content"}
+	}
+	dirs = appendNew(dirs, UpcomingDir)
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(filepath.Join(root, dir), func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || filepath.Ext(p) != ".md" {
+				return err
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				return err
+			}
+			a, err := ReadArticle(root, dir, filepath.ToSlash(rel))
+			if err != nil {
+				return err
+			}
+			if a.Meta.present {
+				lib.Articles = append(lib.Articles, a)
+			}
+			return nil
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	sort.SliceStable(lib.Articles, func(i, j int) bool {
+		a, b := lib.Articles[i], lib.Articles[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.File < b.File
+	})
+	lib.index()
+	return lib, nil
+}
+
+func appendNew(list []string, s string) []string {
+	for _, x := range list {
+		if x == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
+
+// index builds the author and series lists.
+func (lib *Library) index() {
+	authors := make(map[string]*Author)
+	series := make(map[string]*Series)
+	for _, a := range lib.Articles {
+		for _, name := range a.Authors {
+			if authors[name] == nil {
+				authors[name] = &Author{Name: name}
+				lib.Authors = append(lib.Authors, authors[name])
+			}
+			authors[name].Articles = append(authors[name].Articles, a)
+		}
+		for _, name := range a.Series {
+			if series[name] == nil {
+				series[name] = &Series{Name: name, Slug: Urlize(name)}
+				lib.Series = append(lib.Series, series[name])
+			}
+			series[name].Articles = append(series[name].Articles, a)
+		}
+	}
+	sort.Slice(lib.Authors, func(i, j int) bool { return lib.Authors[i].Name < lib.Authors[j].Name })
+	sort.Slice(lib.Series, func(i, j int) bool { return lib.Series[i].Name < lib.Series[j].Name })
+}
+
+// Published returns the articles rendered on the site, oldest first.
+func (lib *Library) Published() []*Article {
+	var out []*Article
+	for _, a := range lib.Articles {
+		if a.Published() {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Upcoming returns the submitted articles not yet published.
+func (lib *Library) Upcoming() []*Article {
+	var out []*Article
+	for _, a := range lib.Articles {
+		if a.Upcoming {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// Article returns the article stored in file, relative to the root.
+func (lib *Library) Article(file string) (*Article, bool) {
+	for _, a := range lib.Articles {
+		if a.File == file {
+			return a, true
+		}
+	}
+	return nil, false
+}
+
+// ByURL returns the published article served at url.
+func (lib *Library) ByURL(url string) (*Article, bool) {
+	for _, a := range lib.Articles {
+		if a.Published() && a.URL() == url {
+			return a, true
+		}
+	}
+	return nil, false
+}
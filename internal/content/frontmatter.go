@@ -0,0 +1,379 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Delim is the line that opens and closes TOML front matter.
+const Delim = "+++"
+
+// FrontMatter is the TOML metadata block of a content file, kept as
+// lines so that writing it back reproduces the original byte for byte,
+// with comments, key order and formatting intact. Only the values that
+// are set are rewritten.
+type FrontMatter struct {
+	lines []line
+	// values holds the decoded front matter.
+	values map[string]any
+	body   []byte
+	// present is set if the file had front matter or a key was set.
+	present bool
+	crlf    bool
+	// orig is the unmodified file, returned by Bytes until a change.
+	orig  []byte
+	dirty bool
+}
+
+// line is a front matter line, or a multi-line value with its key line.
+type line struct {
+	text string
+	// key is the top-level key the line assigns, if any.
+	key string
+	// table is set for table headers; keys after one are not top-level.
+	table bool
+}
+
+// ParseFile splits a content file into front matter and body. A file
+// without front matter gets an empty FrontMatter and its whole contents
+// as body.
+func ParseFile(b []byte) (*FrontMatter, error) {
+	fm := &FrontMatter{values: make(map[string]any), orig: b}
+	fm.crlf = bytes.Contains(b, []byte("\r\n"))
+	s := strings.ReplaceAll(string(b), "\r\n", "\n")
+	if !strings.HasPrefix(s, Delim+"\n") {
+		fm.body = b
+		return fm, nil
+	}
+	fm.present = true
+	rest := s[len(Delim)+1:]
+	end := strings.Index(rest, "\n"+Delim)
+	if end < 0 {
+		if !strings.HasPrefix(rest, Delim) {
+			return nil, fmt.Errorf("front matter is not closed with %s", Delim)
+		}
+		end = -1
+	}
+	var block string
+	if end >= 0 {
+		block = rest[:end]
+		rest = rest[end+1+len(Delim):]
+	} else {
+		rest = rest[len(Delim):]
+	}
+	rest = strings.TrimPrefix(rest, "\n")
+	fm.body = []byte(rest)
+	if fm.crlf {
+		fm.body = []byte(strings.ReplaceAll(rest, "\n", "\r\n"))
+	}
+
+	if _, err := toml.Decode(block, &fm.values); err != nil {
+		return nil, err
+	}
+	fm.lines = splitLines(block)
+	return fm, nil
+}
+
+// splitLines groups the front matter into lines, joining values that span
+// several lines to the line of their key.
+func splitLines(block string) []line {
+	var out []line
+	inTable := false
+	raw := strings.Split(block, "\n")
+	if block == "" {
+		raw = nil
+	}
+	for i := 0; i < len(raw); i++ {
+		text := raw[i]
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case strings.HasPrefix(trimmed, "["):
+			inTable = true
+			out = append(out, line{text: text, table: true})
+			continue
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			out = append(out, line{text: text})
+			continue
+		}
+		key := ""
+		if eq := strings.Index(text, "="); eq > 0 && !inTable {
+			key = strings.Trim(strings.TrimSpace(text[:eq]), `"`)
+		}
+		for open(text) && i+1 < len(raw) {
+			i++
+			text += "\n" + raw[i]
+		}
+		out = append(out, line{text: text, key: key})
+	}
+	return out
+}
+
+// open reports whether a value is unfinished at the end of text: an
+// array with unclosed brackets or a multi-line string.
+func open(text string) bool {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch c := text[i]; c {
+		case '#':
+			// Skip to the end of the line.
+			for i < len(text) && text[i] != '\n' {
+				i++
+			}
+		case '"', '\'':
+			if strings.HasPrefix(text[i:], strings.Repeat(string(c), 3)) {
+				end := strings.Index(text[i+3:], strings.Repeat(string(c), 3))
+				if end < 0 {
+					return true
+				}
+				i += 3 + end + 2
+				continue
+			}
+			for i++; i < len(text) && text[i] != c && text[i] != '\n'; i++ {
+				if c == '"' && text[i] == '\\' {
+					i++
+				}
+			}
+		case '[':
+			depth++
+		case ']':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+// Bytes returns the file with its front matter and body.
+func (fm *FrontMatter) Bytes() []byte {
+	if !fm.dirty {
+		return fm.orig
+	}
+	var buf bytes.Buffer
+	if fm.present {
+		buf.WriteString(Delim + "\n")
+		for _, l := range fm.lines {
+			buf.WriteString(l.text + "\n")
+		}
+		buf.WriteString(Delim + "\n")
+	}
+	head := buf.Bytes()
+	if fm.crlf {
+		head = bytes.ReplaceAll(head, []byte("\n"), []byte("\r\n"))
+	}
+	return append(head, fm.body...)
+}
+
+// Body returns the content after the front matter.
+func (fm *FrontMatter) Body() []byte {
+	return fm.body
+}
+
+// BodyLine returns the line of the file the body starts on, counting
+// from 1.
+func (fm *FrontMatter) BodyLine() int {
+	b := fm.Bytes()
+	return 1 + bytes.Count(b[:len(b)-len(fm.body)], []byte("\n"))
+}
+
+// SetBody replaces the content after the front matter.
+func (fm *FrontMatter) SetBody(b []byte) {
+	fm.body = b
+	fm.dirty = true
+}
+
+// Keys returns the top-level keys in file order.
+func (fm *FrontMatter) Keys() []string {
+	var keys []string
+	for _, l := range fm.lines {
+		if l.key != "" {
+			keys = append(keys, l.key)
+		}
+	}
+	return keys
+}
+
+// Get returns the decoded value of a top-level key.
+func (fm *FrontMatter) Get(key string) (any, bool) {
+	v, ok := fm.values[key]
+	return v, ok
+}
+
+// String returns a string value, or "" if key is missing or not a string.
+func (fm *FrontMatter) String(key string) string {
+	s, _ := fm.values[key].(string)
+	return s
+}
+
+// Strings returns a string array value. A single string is returned as a
+// one element slice.
+func (fm *FrontMatter) Strings(key string) []string {
+	switch v := fm.values[key].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var out []string
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Bool returns a boolean value.
+func (fm *FrontMatter) Bool(key string) bool {
+	b, _ := fm.values[key].(bool)
+	return b
+}
+
+// Time returns a date value, which may be a TOML datetime or a string
+// in RFC 3339 or YYYY-MM-DD form.
+func (fm *FrontMatter) Time(key string) time.Time {
+	switch v := fm.values[key].(type) {
+	case time.Time:
+		return v
+	case string:
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// Set assigns a top-level key. An existing key is rewritten in place,
+// keeping any trailing comment; a new key is added after the last
+// top-level key.
+func (fm *FrontMatter) Set(key string, v any) error {
+	lit, err := literal(v)
+	if err != nil {
+		return fmt.Errorf("front matter %s: %v", key, err)
+	}
+	fm.values[key] = normalize(v)
+	fm.dirty = true
+	for i, l := range fm.lines {
+		if l.key != key {
+			continue
+		}
+		eq := strings.Index(l.text, "=")
+		value := l.text[eq+1:]
+		space := value[:len(value)-len(strings.TrimLeft(value, " \t"))]
+		fm.lines[i].text = l.text[:eq+1] + space + lit + trailingComment(value)
+		return nil
+	}
+	at := 0
+	for i, l := range fm.lines {
+		if l.table {
+			break
+		}
+		if l.key != "" {
+			at = i + 1
+		}
+	}
+	fm.present = true
+	nl := line{text: key + " = " + lit, key: key}
+	fm.lines = append(fm.lines[:at], append([]line{nl}, fm.lines[at:]...)...)
+	return nil
+}
+
+// Delete removes a top-level key.
+func (fm *FrontMatter) Delete(key string) {
+	delete(fm.values, key)
+	for i, l := range fm.lines {
+		if l.key == key {
+			fm.dirty = true
+			fm.lines = append(fm.lines[:i], fm.lines[i+1:]...)
+			return
+		}
+	}
+}
+
+// trailingComment returns the " # ..." comment ending a single-line
+// value, if any.
+func trailingComment(value string) string {
+	if strings.Contains(value, "\n") {
+		return ""
+	}
+	inStr := byte(0)
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case inStr != 0 && c == '\\' && inStr == '"':
+			i++
+		case inStr != 0 && c == inStr:
+			inStr = 0
+		case inStr == 0 && (c == '"' || c == '\''):
+			inStr = c
+		case inStr == 0 && c == '#':
+			return " " + strings.TrimSpace(value[i:])
+		}
+	}
+	return ""
+}
+
+// literal formats v as a TOML value.
+func literal(v any) (string, error) {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x), nil
+	case bool:
+		return strconv.FormatBool(x), nil
+	case int:
+		return strconv.Itoa(x), nil
+	case int64:
+		return strconv.FormatInt(x, 10), nil
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), nil
+	case time.Time:
+		return strconv.Quote(x.Format(time.RFC3339)), nil
+	case []string:
+		q := make([]string, len(x))
+		for i, s := range x {
+			q[i] = strconv.Quote(s)
+		}
+		return "[" + strings.Join(q, ", ") + "]", nil
+	case map[string]string:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		var parts []string
+		for _, k := range keys {
+			parts = append(parts, k+" = "+strconv.Quote(x[k]))
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", v)
+}
+
+// normalize converts v to the type toml.Decode would have produced.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case int:
+		return int64(x)
+	case time.Time:
+		return x.Format(time.RFC3339)
+	case []string:
+		out := make([]any, len(x))
+		for i, s := range x {
+			out[i] = s
+		}
+		return out
+	case map[string]string:
+		out := make(map[string]any, len(x))
+		for k, s := range x {
+			out[k] = s
+		}
+		return out
+	}
+	return v
+}
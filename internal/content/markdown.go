@@ -0,0 +1,259 @@
+package content
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+// MaskCode returns a copy of a markdown body with code blocks, code spans
+// and autolinks replaced by spaces, so that what remains is prose and raw
+// HTML. Newlines are kept and every byte stays at its offset, so
+// positions found in the result are positions in body.
+//
+// Code is found by parsing the body as Hugo does, with Goldmark, so that
+// backticks and fences inside raw HTML or link text do not hide code, or
+// make code of what is not.
+func MaskCode(body []byte) []byte {
+	return maskCode(body, true)
+}
+
+// CodeSpans returns the offsets in a markdown body of its code spans,
+// backticks included, as start and end pairs.
+func CodeSpans(body []byte) [][2]int {
+	return scanCode(body, false).spans
+}
+
+func maskCode(body []byte, autolinks bool) []byte {
+	return scanCode(body, autolinks).masked
+}
+
+// markdown parses bodies with the Goldmark extensions Hugo enables.
+var markdown = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Footnote, extension.DefinitionList)).Parser()
+
+// codeScan is where the code of a body is.
+type codeScan struct {
+	// masked is the body with its code blanked.
+	masked []byte
+	// spans are the offsets of the code spans, and info those of the
+	// info strings of fenced blocks, which are masked with their code.
+	spans, info [][2]int
+}
+
+func scanCode(body []byte, autolinks bool) codeScan {
+	sc := codeScan{masked: append([]byte(nil), body...)}
+	lines := func(n ast.Node) {
+		for i := 0; i < n.Lines().Len(); i++ {
+			seg := n.Lines().At(i)
+			blankOut(sc.masked[seg.Start:seg.Stop])
+		}
+	}
+	doc := markdown.Parse(text.NewReader(body))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock:
+			if n.Info != nil {
+				seg := n.Info.Segment
+				blankOut(sc.masked[seg.Start:seg.Stop])
+				sc.info = append(sc.info, [2]int{seg.Start, seg.Stop})
+			}
+			lines(n)
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeBlock:
+			lines(n)
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			if sp, ok := codeSpan(body, n); ok {
+				blankOut(sc.masked[sp[0]:sp[1]])
+				sc.spans = append(sc.spans, sp)
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	if autolinks {
+		for _, loc := range autolinkRE.FindAllIndex(sc.masked, -1) {
+			blankOut(sc.masked[loc[0]:loc[1]])
+		}
+	}
+	return sc
+}
+
+// codeSpan returns the offsets of a code span with its backticks. The
+// parser gives only the offsets of the code, from which the one space
+// it strips on each side and the backticks are found again.
+func codeSpan(body []byte, n *ast.CodeSpan) ([2]int, bool) {
+	start, end := -1, -1
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		t, ok := c.(*ast.Text)
+		if !ok {
+			continue
+		}
+		if start < 0 || t.Segment.Start < start {
+			start = t.Segment.Start
+		}
+		end = max(end, t.Segment.Stop)
+	}
+	if start < 0 {
+		return [2]int{}, false
+	}
+	start -= stripped(body[:start], false)
+	for start > 0 && body[start-1] == '`' {
+		start--
+	}
+	end += stripped(body[end:], true)
+	for end < len(body) && body[end] == '`' {
+		end++
+	}
+	return [2]int{start, end}, true
+}
+
+// stripped returns the length of the space or line ending at the end
+// of b, or at its start if after is set, that the parser dropped between
+// the code of a code span and its backticks.
+func stripped(b []byte, after bool) int {
+	for _, sp := range []string{" ", "\r\n", "\n"} {
+		if after && bytes.HasPrefix(b, []byte(sp+"`")) {
+			return len(sp)
+		}
+		if !after && bytes.HasSuffix(b, []byte("`"+sp)) {
+			return len(sp)
+		}
+	}
+	return 0
+}
+
+var autolinkRE = regexp.MustCompile(`<(?:[a-zA-Z][a-zA-Z0-9+.-]*:[^\s<>]*|[^\s<>@]+@[^\s<>@]+)>`)
+
+func blankOut(b []byte) {
+	for i := range b {
+		if b[i] != '\n' && b[i] != '\r' {
+			b[i] = ' '
+		}
+	}
+}
+
+var (
+	imageRE     = regexp.MustCompile(`!\[[^\]]*\]\(\s*<?([^)\s>]+)`)
+	imgTagRE    = regexp.MustCompile(`<img[^>]*\ssrc="([^"]+)"`)
+	linkRE      = regexp.MustCompile(`!?\[([^\]]*)\](?:\([^)]*\)|\[[^\]]*\])`)
+	emphasisRE  = regexp.MustCompile("[*_`]+")
+	htmlTagRE   = regexp.MustCompile(`<[^>]+>`)
+	shortcodeRE = regexp.MustCompile(`\{\{[<%].*?[%>]\}\}`)
+)
+
+// FirstImage returns the source of the first image in a markdown body,
+// written either as markdown or as an <img> tag, or "" if there is none.
+func FirstImage(body []byte) string {
+	masked := MaskCode(body)
+	first, src := len(masked), ""
+	for _, re := range []*regexp.Regexp{imageRE, imgTagRE} {
+		if m := re.FindSubmatchIndex(masked); m != nil && m[0] < first {
+			first, src = m[0], string(body[m[2]:m[3]])
+		}
+	}
+	return src
+}
+
+// Summary returns the first paragraph of prose in a markdown body as
+// plain text, cut at a word boundary to at most n bytes.
+func Summary(body []byte, n int) string {
+	off := 0
+	for _, para := range bytes.Split(MaskCode(body), []byte("\n\n")) {
+		start := off
+		off += len(para) + 2
+		if len(bytes.TrimSpace(para)) == 0 {
+			continue
+		}
+		// Code spans are masked too; take the text from the body.
+		text := string(bytes.TrimSpace(body[start : start+len(para)]))
+		if strings.ContainsAny(text[:1], "#<!|>-*=") || strings.HasPrefix(text, "{{") {
+			continue
+		}
+		text = shortcodeRE.ReplaceAllString(text, "")
+		text = linkRE.ReplaceAllString(text, "$1")
+		text = htmlTagRE.ReplaceAllString(text, "")
+		text = emphasisRE.ReplaceAllString(text, "")
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		if len(text) <= n {
+			return text
+		}
+		cut := strings.LastIndex(text[:n], " ")
+		if cut < 0 {
+			cut = n
+		}
+		return strings.TrimRight(text[:cut], ",;:.") + "…"
+	}
+	return ""
+}
+
+// refDefRE matches reference-style link definitions.
+var refDefRE = regexp.MustCompile(`(?m)^ {0,3}\[[^\]]+\]:\s.*$`)
+
+// Prose returns the prose of a markdown body as plain text: without
+// code, link targets, raw HTML tags, shortcodes or emphasis.
+func Prose(body []byte) string {
+	text := string(MaskCode(body))
+	text = refDefRE.ReplaceAllString(text, "")
+	text = shortcodeRE.ReplaceAllString(text, "")
+	text = linkRE.ReplaceAllString(text, "$1")
+	text = htmlTagRE.ReplaceAllString(text, "")
+	text = emphasisRE.ReplaceAllString(text, "")
+	return text
+}
+
+// Code returns a copy of a markdown body with everything but its code
+// blocks and code spans replaced by spaces, the converse of MaskCode.
+// The info strings of fenced blocks and the backticks around code spans
+// are blanked too. Newlines are kept.
+func Code(body []byte) []byte {
+	sc := scanCode(body, false)
+	out := append([]byte(nil), body...)
+	for i := range out {
+		if sc.masked[i] == body[i] && body[i] != '\n' {
+			out[i] = ' '
+		}
+	}
+	for _, in := range sc.info {
+		blankOut(out[in[0]:in[1]])
+	}
+	for _, sp := range sc.spans {
+		for i := sp[0]; i < sp[1] && out[i] == '`'; i++ {
+			out[i] = ' '
+		}
+		for i := sp[1] - 1; i >= sp[0] && out[i] == '`'; i-- {
+			out[i] = ' '
+		}
+	}
+	return out
+}
+
+var urlRE = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// Links returns the absolute http and https URLs in a markdown body,
+// outside code, in order of first appearance and without duplicates.
+// They include links, reference definitions, autolinks, bare URLs and
+// the URLs in raw HTML attributes.
+func Links(body []byte) []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, m := range urlRE.FindAll(maskCode(body, false), -1) {
+		u := strings.TrimRight(string(m), ".,;:!?*_")
+		if !seen[u] {
+			seen[u] = true
+			out = append(out, u)
+		}
+	}
+	return out
+}
@@ -0,0 +1,105 @@
+package content
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaskCode(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{
+			"code span",
+			"Call `fmt.Println` here.",
+			"Call               here.",
+		},
+		{
+			"double backticks",
+			"Use `` `x` `` and <b>bold</b>.",
+			"Use           and <b>bold</b>.",
+		},
+		{
+			"fenced block",
+			"Before\n\n```go\n<script>x</script>\n```\nAfter <i>x</i>",
+			"Before\n\n```  \n                  \n```\nAfter <i>x</i>",
+		},
+		{
+			"indented block",
+			"Text\n\n    <script>x</script>\n\nMore",
+			"Text\n\n                      \n\nMore",
+		},
+		{
+			// An indented line continuing a paragraph is not code.
+			"lazy continuation",
+			"Text\n    <b>x</b>",
+			"Text\n    <b>x</b>",
+		},
+		{
+			"autolinks",
+			"See <https://golang.org> or <gopher@example.com>.",
+			"See                      or                     .",
+		},
+		{
+			// Backticks inside raw HTML start no code span, so the
+			// HTML after them is not hidden.
+			"backticks in an HTML block",
+			"<div title=\"`\">\n<script src=\"https://evil.example/x.js\"></script>\n<p>`</p>\n</div>",
+			"<div title=\"`\">\n<script src=\"https://evil.example/x.js\"></script>\n<p>`</p>\n</div>",
+		},
+		{
+			"backtick in a tag",
+			"Hi <span title=\"`\">x</span> <script>y</script> `",
+			"Hi <span title=\"`\">x</span> <script>y</script> `",
+		},
+		{
+			"unclosed fence in a blockquote",
+			"> ```\n> code\n\nprose",
+			"> ```\n>     \n\nprose",
+		},
+	}
+	for _, tt := range tests {
+		got := string(MaskCode([]byte(tt.body)))
+		if got != tt.want {
+			t.Errorf("%s: MaskCode(%q)\n = %q\nwant %q", tt.name, tt.body, got, tt.want)
+		}
+		if len(got) != len(tt.body) {
+			t.Errorf("%s: MaskCode changed the length", tt.name)
+		}
+	}
+}
+
+func TestCodeSpans(t *testing.T) {
+	body := "A `b` and `` c` `` and ``\nd\n`` but not <i title=\"`\">`</i>\n\n    `indented`\n"
+	var got []string
+	for _, sp := range CodeSpans([]byte(body)) {
+		got = append(got, body[sp[0]:sp[1]])
+	}
+	want := []string{"`b`", "`` c` ``", "``\nd\n``"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CodeSpans = %q, want %q", got, want)
+	}
+}
+
+func TestCode(t *testing.T) {
+	body := "Use `x := 1` in\n\n```go\nfunc f() {}\n```\n"
+	got := string(Code([]byte(body)))
+	if want := "     x := 1    \n\n     \nfunc f() {}\n   \n"; got != want {
+		t.Errorf("Code = %q, want %q", got, want)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	body := "See [Go](https://golang.org/doc/), <https://go.dev>.\n\n" +
+		"`https://in.code/span`\n\n    https://in.code/block\n\n" +
+		"<a href=\"https://raw.example/\">x</a> and https://golang.org/doc/ again."
+	got := Links([]byte(body))
+	want := []string{"https://golang.org/doc/", "https://go.dev", "https://raw.example/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Links = %q, want %q", got, want)
+	}
+	if strings.Contains(strings.Join(got, " "), "in.code") {
+		t.Error("Links reports URLs in code")
+	}
+}
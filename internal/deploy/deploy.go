@@ -0,0 +1,465 @@
+// Package deploy publishes a built site as immutable, content-addressed
+// releases and switches between them atomically.
+//
+// A store holds:
+//
+//	objects/<sha256>     file contents, shared by every release
+//	releases/<id>.json   release manifests, mapping paths to objects
+//	history.json         release ids in the order they went live
+//	live                 the id of the release being served
+//	switch.json          a switch of releases not yet finished
+//
+// A release id is the hash of its manifest, so deploying an unchanged
+// tree uploads nothing and creates no new release. Switching releases
+// rewrites the live pointer, which every Store writes atomically, and
+// the history. So that a failure between the two writes cannot leave
+// them disagreeing, both are first journaled in switch.json, which the
+// next Push, Rollback or Prune replays before doing anything else.
+package deploy
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Key names within a store.
+const (
+	objectsPrefix  = "objects/"
+	releasesPrefix = "releases/"
+	historyKey     = "history.json"
+	liveKey        = "live"
+	journalKey     = "switch.json"
+)
+
+// File is one entry of a release manifest.
+type File struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+}
+
+// Manifest maps slash-separated paths relative to the site root to
+// their contents.
+type Manifest map[string]File
+
+// ID returns the content address of the manifest.
+func (m Manifest) ID() string {
+	paths := m.paths()
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "%s %s %d\n", m[p].Hash, p, m[p].Size)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+func (m Manifest) paths() []string {
+	paths := make([]string, 0, len(m))
+	for p := range m {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Scan hashes every file below dir.
+func Scan(dir string) (Manifest, error) {
+	m := make(Manifest)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		h := sha256.New()
+		n, err := io.Copy(h, f)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		m[filepath.ToSlash(rel)] = File{Hash: hex.EncodeToString(h.Sum(nil)), Size: n}
+		return nil
+	})
+	return m, err
+}
+
+// Change is the difference between two manifests.
+type Change struct {
+	Added, Changed, Removed []string
+}
+
+// Diff returns the paths added, changed and removed going from old to new.
+func Diff(old, new Manifest) Change {
+	var c Change
+	for _, p := range new.paths() {
+		o, ok := old[p]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, p)
+		case o.Hash != new[p].Hash:
+			c.Changed = append(c.Changed, p)
+		}
+	}
+	for _, p := range old.paths() {
+		if _, ok := new[p]; !ok {
+			c.Removed = append(c.Removed, p)
+		}
+	}
+	return c
+}
+
+// Release is an entry of the deploy history.
+type Release struct {
+	ID       string    `json:"id"`
+	Deployed time.Time `json:"deployed"`
+}
+
+// Deployer pushes releases to a Store.
+type Deployer struct {
+	Store Store
+	// Keep is the number of releases kept besides the live one.
+	Keep int
+	// Log, if set, receives a line per uploaded file and per interrupted
+	// switch it finishes.
+	Log func(format string, args ...any)
+}
+
+func (d *Deployer) logf(format string, args ...any) {
+	if d.Log != nil {
+		d.Log(format, args...)
+	}
+}
+
+// Push uploads the site built in dir as a new release and makes it live.
+// Only files whose contents are not already in the store are uploaded.
+func (d *Deployer) Push(dir string) (Release, Change, error) {
+	if err := d.recover(); err != nil {
+		return Release{}, Change{}, err
+	}
+	m, err := Scan(dir)
+	if err != nil {
+		return Release{}, Change{}, err
+	}
+	var old Manifest
+	if id, err := d.Live(); err == nil {
+		if old, err = d.Manifest(id); err != nil {
+			return Release{}, Change{}, err
+		}
+	} else if !errors.Is(err, ErrNotExist) {
+		return Release{}, Change{}, err
+	}
+	change := Diff(old, m)
+
+	uploaded := make(map[string]bool)
+	for _, p := range append(change.Added, change.Changed...) {
+		f := m[p]
+		if uploaded[f.Hash] {
+			continue
+		}
+		key := objectsPrefix + f.Hash
+		ok, err := d.Store.Has(key)
+		if err != nil {
+			return Release{}, change, err
+		}
+		if !ok {
+			if err := d.putFile(key, filepath.Join(dir, filepath.FromSlash(p))); err != nil {
+				return Release{}, change, err
+			}
+			d.logf("uploaded %s", p)
+		}
+		uploaded[f.Hash] = true
+	}
+
+	id := m.ID()
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Release{}, change, err
+	}
+	if err := d.Store.Put(releasesPrefix+id+".json", bytes.NewReader(b)); err != nil {
+		return Release{}, change, err
+	}
+	r := Release{ID: id, Deployed: time.Now().UTC()}
+	if err := d.activate(r); err != nil {
+		return Release{}, change, err
+	}
+	return r, change, d.Prune()
+}
+
+func (d *Deployer) putFile(key, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return d.Store.Put(key, f)
+}
+
+// activate points live at r and records it in the history.
+func (d *Deployer) activate(r Release) error {
+	h, err := d.History()
+	if err != nil {
+		return err
+	}
+	if n := len(h); n == 0 || h[n-1].ID != r.ID {
+		h = append(h, r)
+	}
+	return d.switchTo(r.ID, h)
+}
+
+// Rollback makes the release deployed before the live one live again
+// and drops the live one from the history.
+func (d *Deployer) Rollback() (Release, error) {
+	if err := d.recover(); err != nil {
+		return Release{}, err
+	}
+	h, err := d.History()
+	if err != nil {
+		return Release{}, err
+	}
+	if len(h) < 2 {
+		return Release{}, errors.New("deploy: no previous release to roll back to")
+	}
+	prev := h[len(h)-2]
+	return prev, d.switchTo(prev.ID, h[:len(h)-1])
+}
+
+// journal is a switch of releases in progress.
+type journal struct {
+	Live    string    `json:"live"`
+	History []Release `json:"history"`
+}
+
+// switchTo points live at id and replaces the history with h, journaling
+// both first so that recover can finish the switch if it is cut short.
+func (d *Deployer) switchTo(id string, h []Release) error {
+	b, err := json.MarshalIndent(journal{Live: id, History: h}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := d.Store.Put(journalKey, bytes.NewReader(b)); err != nil {
+		return err
+	}
+	return d.finish(journal{Live: id, History: h})
+}
+
+// finish applies j and removes the journal.
+func (d *Deployer) finish(j journal) error {
+	if err := d.putHistory(j.History); err != nil {
+		return err
+	}
+	if err := d.Store.Put(liveKey, strings.NewReader(j.Live+"\n")); err != nil {
+		return err
+	}
+	return d.Store.Delete(journalKey)
+}
+
+// recover finishes a switch left journaled by an earlier failure.
+func (d *Deployer) recover() error {
+	rc, err := d.Store.Get(journalKey)
+	if errors.Is(err, ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	var j journal
+	if err := json.NewDecoder(rc).Decode(&j); err != nil {
+		return fmt.Errorf("deploy: %s: %v", journalKey, err)
+	}
+	d.logf("finishing interrupted switch to %s", j.Live)
+	return d.finish(j)
+}
+
+// Live returns the id of the live release.
+func (d *Deployer) Live() (string, error) {
+	rc, err := d.Store.Get(liveKey)
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	return strings.TrimSpace(string(b)), err
+}
+
+// Manifest returns the manifest of release id.
+func (d *Deployer) Manifest(id string) (Manifest, error) {
+	rc, err := d.Store.Get(releasesPrefix + id + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("deploy: release %s: %w", id, err)
+	}
+	defer rc.Close()
+	var m Manifest
+	if err := json.NewDecoder(rc).Decode(&m); err != nil {
+		return nil, fmt.Errorf("deploy: release %s: %v", id, err)
+	}
+	return m, nil
+}
+
+// History returns the releases in the order they went live.
+func (d *Deployer) History() ([]Release, error) {
+	rc, err := d.Store.Get(historyKey)
+	if errors.Is(err, ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	var h []Release
+	if err := json.NewDecoder(rc).Decode(&h); err != nil {
+		return nil, fmt.Errorf("deploy: %s: %v", historyKey, err)
+	}
+	return h, nil
+}
+
+func (d *Deployer) putHistory(h []Release) error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return d.Store.Put(historyKey, bytes.NewReader(b))
+}
+
+// Prune trims the history to the live release and Keep predecessors,
+// then deletes the manifests and objects no remaining release uses.
+func (d *Deployer) Prune() error {
+	if err := d.recover(); err != nil {
+		return err
+	}
+	h, err := d.History()
+	if err != nil {
+		return err
+	}
+	if len(h) > d.Keep+1 {
+		h = h[len(h)-d.Keep-1:]
+		if err := d.putHistory(h); err != nil {
+			return err
+		}
+	}
+	kept := make(map[string]bool)
+	used := make(map[string]bool)
+	for _, r := range h {
+		m, err := d.Manifest(r.ID)
+		if err != nil {
+			return err
+		}
+		kept[releasesPrefix+r.ID+".json"] = true
+		for _, f := range m {
+			used[objectsPrefix+f.Hash] = true
+		}
+	}
+	releases, err := d.Store.List(releasesPrefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range releases {
+		if !kept[k] {
+			if err := d.Store.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	objects, err := d.Store.List(objectsPrefix)
+	if err != nil {
+		return err
+	}
+	for _, k := range objects {
+		if !used[k] {
+			if err := d.Store.Delete(k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Checkout writes the live release to dir/<id> and atomically points the
+// symlink dir/current at it, so that a web server whose root is
+// dir/current switches releases without serving a mix of both.
+// Checkouts of releases no longer in the history are removed.
+func (d *Deployer) Checkout(dir string) (string, error) {
+	id, err := d.Live()
+	if err != nil {
+		return "", err
+	}
+	m, err := d.Manifest(id)
+	if err != nil {
+		return "", err
+	}
+	root := filepath.Join(dir, id)
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		tmp := root + ".partial"
+		os.RemoveAll(tmp)
+		for _, p := range m.paths() {
+			if err := d.getFile(objectsPrefix+m[p].Hash, filepath.Join(tmp, filepath.FromSlash(p))); err != nil {
+				return "", err
+			}
+		}
+		if err := os.Rename(tmp, root); err != nil {
+			return "", err
+		}
+	}
+	link := filepath.Join(dir, "current")
+	os.Remove(link + ".tmp")
+	if err := os.Symlink(id, link+".tmp"); err != nil {
+		return "", err
+	}
+	if err := os.Rename(link+".tmp", link); err != nil {
+		return "", err
+	}
+
+	h, err := d.History()
+	if err != nil {
+		return "", err
+	}
+	kept := map[string]bool{id: true}
+	for _, r := range h {
+		kept[r.ID] = true
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if e.IsDir() && !kept[e.Name()] {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+	return id, nil
+}
+
+func (d *Deployer) getFile(key, name string) error {
+	rc, err := d.Store.Get(key)
+	if err != nil {
+		return fmt.Errorf("deploy: %s: %w", key, err)
+	}
+	defer rc.Close()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
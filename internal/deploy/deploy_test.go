@@ -0,0 +1,246 @@
+package deploy
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// stores returns a fresh store of each kind, by name.
+func stores(t *testing.T) map[string]Store {
+	srv := httptest.NewServer(NewFakeS3("site"))
+	t.Cleanup(srv.Close)
+	return map[string]Store{
+		"local": LocalStore{Dir: t.TempDir()},
+		"s3": &S3Store{
+			Endpoint:  srv.URL,
+			Region:    "us-east-1",
+			Bucket:    "site",
+			AccessKey: "key",
+			SecretKey: "secret",
+		},
+	}
+}
+
+// build writes files, by slash-separated path, to a new directory.
+func build(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for p, s := range files {
+		name := filepath.Join(dir, filepath.FromSlash(p))
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func ids(h []Release) []string {
+	var out []string
+	for _, r := range h {
+		out = append(out, r.ID)
+	}
+	return out
+}
+
+// check fails unless live is want and the history is hist.
+func check(t *testing.T, d *Deployer, want string, hist ...string) {
+	t.Helper()
+	live, err := d.Live()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if live != want {
+		t.Errorf("live = %s, want %s", live, want)
+	}
+	h, err := d.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(h); !reflect.DeepEqual(got, hist) {
+		t.Errorf("history = %v, want %v", got, hist)
+	}
+}
+
+func TestPushRollbackPrune(t *testing.T) {
+	v1 := build(t, map[string]string{"index.html": "one", "css/site.css": "body{}"})
+	v2 := build(t, map[string]string{"index.html": "two", "css/site.css": "body{}", "new/index.html": "new"})
+	v3 := build(t, map[string]string{"index.html": "three"})
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			var uploaded []string
+			d := &Deployer{Store: s, Keep: 1, Log: func(format string, args ...any) {
+				uploaded = append(uploaded, args[0].(string))
+			}}
+
+			r1, c, err := d.Push(v1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"css/site.css", "index.html"}; !reflect.DeepEqual(c.Added, want) {
+				t.Errorf("first push added %v, want %v", c.Added, want)
+			}
+			check(t, d, r1.ID, r1.ID)
+
+			uploaded = nil
+			r2, c, err := d.Push(v2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Change{Added: []string{"new/index.html"}, Changed: []string{"index.html"}}
+			if !reflect.DeepEqual(c, want) {
+				t.Errorf("second push = %+v, want %+v", c, want)
+			}
+			if want := []string{"new/index.html", "index.html"}; !reflect.DeepEqual(uploaded, want) {
+				t.Errorf("second push uploaded %v, want %v", uploaded, want)
+			}
+			check(t, d, r2.ID, r1.ID, r2.ID)
+
+			// Pushing an unchanged tree uploads nothing and adds no release.
+			uploaded = nil
+			again, c, err := d.Push(v2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.ID != r2.ID || len(uploaded) > 0 || len(c.Added)+len(c.Changed)+len(c.Removed) > 0 {
+				t.Errorf("unchanged push: release %s, uploaded %v, change %+v", again.ID, uploaded, c)
+			}
+			check(t, d, r2.ID, r1.ID, r2.ID)
+
+			prev, err := d.Rollback()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prev.ID != r1.ID {
+				t.Errorf("rolled back to %s, want %s", prev.ID, r1.ID)
+			}
+			check(t, d, r1.ID, r1.ID)
+			if _, err := d.Rollback(); err == nil {
+				t.Error("rolling back past the first release succeeded")
+			}
+
+			// With Keep 1, pushing r2 and r3 on top of r1 prunes r1 and
+			// the objects only it uses.
+			if _, _, err := d.Push(v2); err != nil {
+				t.Fatal(err)
+			}
+			r3, _, err := d.Push(v3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, d, r3.ID, r2.ID, r3.ID)
+			releases, err := s.List(releasesPrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{releasesPrefix + r2.ID + ".json", releasesPrefix + r3.ID + ".json"}; !sameSet(releases, want) {
+				t.Errorf("releases after prune = %v, want %v", releases, want)
+			}
+			objects, err := s.List(objectsPrefix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(objects) != 4 {
+				t.Errorf("%d objects after prune, want 4 (two, body{}, new, three): %v", len(objects), objects)
+			}
+			if ok, _ := s.Has(objectsPrefix + mustScan(t, v1)["index.html"].Hash); ok {
+				t.Error("object used only by the pruned release was kept")
+			}
+
+			out := t.TempDir()
+			id, err := d.Checkout(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if id != r3.ID {
+				t.Errorf("checked out %s, want %s", id, r3.ID)
+			}
+			b, err := os.ReadFile(filepath.Join(out, "current", "index.html"))
+			if err != nil || string(b) != "three" {
+				t.Errorf("current/index.html = %q, %v; want three", b, err)
+			}
+		})
+	}
+}
+
+func sameSet(a, b []string) bool {
+	m := make(map[string]bool)
+	for _, s := range a {
+		m[s] = true
+	}
+	for _, s := range b {
+		if !m[s] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func mustScan(t *testing.T, dir string) Manifest {
+	m, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// failingStore fails the next Put of key.
+type failingStore struct {
+	Store
+	key string
+}
+
+var errInjected = errors.New("injected failure")
+
+func (s *failingStore) Put(key string, r io.Reader) error {
+	if key == s.key {
+		s.key = ""
+		return errInjected
+	}
+	return s.Store.Put(key, r)
+}
+
+func TestInterruptedRollback(t *testing.T) {
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			fs := &failingStore{Store: s}
+			d := &Deployer{Store: fs, Keep: 5}
+			r1, _, err := d.Push(build(t, map[string]string{"index.html": "one"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r2, _, err := d.Push(build(t, map[string]string{"index.html": "two"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The history is written, then the rollback fails before
+			// live is.
+			fs.key = liveKey
+			if _, err := d.Rollback(); !errors.Is(err, errInjected) {
+				t.Fatalf("Rollback = %v, want the injected failure", err)
+			}
+			if ok, _ := s.Has(journalKey); !ok {
+				t.Fatal("interrupted rollback left no journal")
+			}
+
+			// The next operation finishes the rollback before its own work.
+			if err := d.Prune(); err != nil {
+				t.Fatal(err)
+			}
+			check(t, d, r1.ID, r1.ID)
+			if ok, _ := s.Has(journalKey); ok {
+				t.Error("journal kept after recovery")
+			}
+			if _, err := d.Manifest(r2.ID); err == nil {
+				t.Error("rolled back release not pruned")
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package deploy
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// FakeS3 is an in-process stand-in for an S3-compatible service, good
+// enough to exercise S3Store through httptest.NewServer. It serves one
+// bucket path-style, does not check signatures beyond requiring an
+// Authorization header, and returns every listing in a single page.
+type FakeS3 struct {
+	Bucket string
+	Store  *MemStore
+}
+
+// NewFakeS3 returns a FakeS3 serving an empty bucket.
+func NewFakeS3(bucket string) *FakeS3 {
+	return &FakeS3{Bucket: bucket, Store: NewMemStore()}
+}
+
+func (f *FakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") == "" {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/"+f.Bucket)
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(rest, "/")
+	if key == "" {
+		if r.Method != "GET" || r.URL.Query().Get("list-type") != "2" {
+			http.Error(w, "NotImplemented", http.StatusNotImplemented)
+			return
+		}
+		keys, _ := f.Store.List(r.URL.Query().Get("prefix"))
+		type content struct{ Key string }
+		res := struct {
+			XMLName  xml.Name `xml:"ListBucketResult"`
+			Contents []content
+		}{}
+		for _, k := range keys {
+			res.Contents = append(res.Contents, content{k})
+		}
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(res)
+		return
+	}
+	switch r.Method {
+	case "HEAD", "GET":
+		rc, err := f.Store.Get(key)
+		if err != nil {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		defer rc.Close()
+		if r.Method == "GET" {
+			io.Copy(w, rc)
+		}
+	case "PUT":
+		if err := f.Store.Put(key, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case "DELETE":
+		f.Store.Delete(key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,275 @@
+// Package htmlcheck reports markup errors in rendered pages: tags that
+// are never closed or closed out of order, duplicate ids, block elements
+// inside paragraphs and elements nested where HTML does not allow them.
+//
+// Browsers recover from all of these silently, each in its own way, so
+// they show up as layout bugs rather than errors. The checker tokenizes
+// pages with the HTML5 tokenizer and tracks the open elements itself,
+// reporting where the tree builder would have had to repair the markup.
+package htmlcheck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/net/html"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+)
+
+// Threshold is the number of problems a rendered site may have. The
+// pages rendered from the current layouts, kept in testdata/public,
+// have none, and CI fails on any.
+const Threshold = 0
+
+// Problem is a single markup error.
+type Problem struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+}
+
+// void elements have no end tag.
+var void = set("area", "base", "br", "col", "embed", "hr", "img", "input",
+	"link", "meta", "param", "source", "track", "wbr")
+
+// optionalEnd elements may be closed implicitly by their parent's end tag.
+var optionalEnd = set("p", "li", "dt", "dd", "option", "optgroup", "tr", "td",
+	"th", "thead", "tbody", "tfoot", "colgroup", "rt", "rp", "html", "head", "body")
+
+// closesP are the start tags that implicitly close an open <p>.
+var closesP = set("address", "article", "aside", "blockquote", "details",
+	"div", "dl", "fieldset", "figcaption", "figure", "footer", "form", "h1",
+	"h2", "h3", "h4", "h5", "h6", "header", "hr", "main", "nav", "ol", "p",
+	"pre", "section", "table", "ul")
+
+// phrasing elements may only contain phrasing content.
+var phrasing = set("abbr", "b", "bdi", "bdo", "cite", "code", "data", "dfn",
+	"em", "i", "kbd", "label", "mark", "q", "s", "samp", "small", "span",
+	"strong", "sub", "sup", "time", "u", "var", "h1", "h2", "h3", "h4", "h5", "h6")
+
+// noSelfNesting elements may not contain another of themselves.
+var noSelfNesting = set("a", "form", "button", "label")
+
+// listItemParents are the elements an <li> may appear in.
+var listItemParents = set("ul", "ol", "menu")
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+type element struct {
+	name string
+	line int
+}
+
+type checker struct {
+	file     string
+	line     int
+	stack    []element
+	ids      map[string]int
+	problems []Problem
+}
+
+func (c *checker) report(line int, format string, args ...any) {
+	c.problems = append(c.problems, Problem{File: c.file, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// open returns the index of the innermost open element called name, or -1.
+func (c *checker) open(name string) int {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if c.stack[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// CheckSite checks every page of the site rendered in dir and returns
+// their problems and the number of pages checked.
+func CheckSite(dir string) ([]Problem, int, error) {
+	m, err := site.Load(dir)
+	if err != nil {
+		return nil, 0, err
+	}
+	var problems []Problem
+	for _, p := range m.Pages {
+		f, err := os.Open(filepath.Join(dir, filepath.FromSlash(p.File)))
+		if err != nil {
+			return nil, 0, err
+		}
+		pp, err := Check(p.File, f)
+		f.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: %v", p.File, err)
+		}
+		problems = append(problems, pp...)
+	}
+	return problems, len(m.Pages), nil
+}
+
+// Check reads the page from r and returns its problems. file is used to
+// label them.
+func Check(file string, r io.Reader) ([]Problem, error) {
+	c := &checker{file: file, line: 1, ids: make(map[string]int)}
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return c.problems, z.Err()
+			}
+			break
+		}
+		raw := z.Raw()
+		line := c.line
+		c.line += bytes.Count(raw, []byte("\n"))
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			c.start(string(name), line, attrs(z, hasAttr), tt == html.SelfClosingTagToken)
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			c.end(string(name), line)
+		}
+	}
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		if e := c.stack[i]; !optionalEnd[e.name] {
+			c.report(e.line, "<%s> is never closed", e.name)
+		}
+	}
+	return c.problems, nil
+}
+
+func attrs(z *html.Tokenizer, more bool) map[string]string {
+	m := make(map[string]string)
+	for more {
+		var k, v []byte
+		k, v, more = z.TagAttr()
+		m[string(k)] = string(v)
+	}
+	return m
+}
+
+func (c *checker) start(name string, line int, attr map[string]string, selfClosing bool) {
+	if id, ok := attr["id"]; ok {
+		if first, dup := c.ids[id]; dup {
+			c.report(line, "duplicate id %q, first used on line %d", id, first)
+		} else {
+			c.ids[id] = line
+		}
+	}
+
+	if closesP[name] && c.open("p") >= 0 && c.inScope("p") {
+		if name == "p" {
+			c.closeTo(c.open("p"))
+		} else {
+			c.report(line, "<%s> inside <p> opened on line %d", name, c.stack[c.open("p")].line)
+			c.closeTo(c.open("p"))
+		}
+	}
+	c.implicitClose(name)
+
+	if noSelfNesting[name] && c.open(name) >= 0 {
+		c.report(line, "<%s> nested inside <%s> opened on line %d", name, name, c.stack[c.open(name)].line)
+	}
+	if name == "li" {
+		if n := len(c.stack); n == 0 || !listItemParents[c.stack[n-1].name] {
+			c.report(line, "<li> outside of a list")
+		}
+	}
+	if n := len(c.stack); n > 0 && phrasing[c.stack[n-1].name] && closesP[name] {
+		c.report(line, "block <%s> inside inline <%s> opened on line %d", name, c.stack[n-1].name, c.stack[n-1].line)
+	}
+
+	if void[name] {
+		return
+	}
+	if selfClosing {
+		if c.open("svg") >= 0 || c.open("math") >= 0 || name == "svg" || name == "math" {
+			// Self-closing tags are valid in foreign content.
+			return
+		}
+		c.report(line, "self-closing syntax on non-void <%s/> is ignored; the element stays open", name)
+	}
+	c.stack = append(c.stack, element{name, line})
+}
+
+// inScope reports whether no element that isolates its content (a table
+// cell, button or the like) sits between the innermost name and the top.
+func (c *checker) inScope(name string) bool {
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		switch c.stack[i].name {
+		case name:
+			return true
+		case "td", "th", "table", "button", "object", "caption":
+			return false
+		}
+	}
+	return false
+}
+
+// implicitClose pops the elements a start tag called name ends without
+// an end tag, as a list item ends the previous one.
+func (c *checker) implicitClose(name string) {
+	var closes []string
+	switch name {
+	case "li":
+		closes = []string{"li"}
+	case "dt", "dd":
+		closes = []string{"dt", "dd"}
+	case "tr":
+		closes = []string{"tr", "td", "th"}
+	case "td", "th":
+		closes = []string{"td", "th"}
+	case "option":
+		closes = []string{"option"}
+	default:
+		return
+	}
+	for n := len(c.stack); n > 0; n = len(c.stack) {
+		top := c.stack[n-1].name
+		match := false
+		for _, x := range closes {
+			match = match || top == x
+		}
+		if !match {
+			return
+		}
+		c.stack = c.stack[:n-1]
+	}
+}
+
+// closeTo pops the stack down to and including index i.
+func (c *checker) closeTo(i int) {
+	c.stack = c.stack[:i]
+}
+
+func (c *checker) end(name string, line int) {
+	if void[name] {
+		c.report(line, "end tag </%s> for void element", name)
+		return
+	}
+	i := c.open(name)
+	if i < 0 {
+		c.report(line, "</%s> without matching start tag", name)
+		return
+	}
+	for j := len(c.stack) - 1; j > i; j-- {
+		if e := c.stack[j]; !optionalEnd[e.name] {
+			c.report(line, "</%s> closes <%s> opened on line %d, which is still open", name, e.name, e.line)
+		}
+	}
+	c.closeTo(i)
+}
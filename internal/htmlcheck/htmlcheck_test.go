@@ -0,0 +1,53 @@
+package htmlcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRenderedSite holds the pages rendered from the current layouts to
+// Threshold, the limit cmd/htmlcheck enforces in CI: they must be free
+// of problems.
+func TestRenderedSite(t *testing.T) {
+	problems, pages, err := CheckSite("testdata/public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pages == 0 {
+		t.Fatal("no pages in testdata/public")
+	}
+	if len(problems) > Threshold {
+		for _, p := range problems {
+			t.Log(p)
+		}
+		t.Errorf("%d problems in %d pages, more than the threshold of %d", len(problems), pages, Threshold)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		html string
+		want []string
+	}{
+		{`<p>fine <em>text</em></p><ul><li>a<li>b</ul>`, nil},
+		{`<p><div>x</div></p>`, []string{"<div> inside <p>", "</p> without matching start tag"}},
+		{`<div><span>x</div>`, []string{"</div> closes <span>"}},
+		{`<a id="x"></a><b id="x"></b>`, []string{"duplicate id"}},
+		{`<section>`, []string{"<section> is never closed"}},
+	}
+	for _, tt := range tests {
+		problems, err := Check("page.html", strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(problems) != len(tt.want) {
+			t.Errorf("Check(%q) = %v, want %d problems", tt.html, problems, len(tt.want))
+			continue
+		}
+		for i, p := range problems {
+			if !strings.Contains(p.Message, tt.want[i]) {
+				t.Errorf("Check(%q) problem %d = %q, want it to mention %q", tt.html, i, p.Message, tt.want[i])
+			}
+		}
+	}
+}
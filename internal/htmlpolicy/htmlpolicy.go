@@ -0,0 +1,382 @@
+// Package htmlpolicy checks the raw HTML written into article bodies
+// against the elements and attributes the site accepts, and removes what
+// it does not accept.
+//
+// Markdown passes raw HTML through to the page unchanged, so a submitted
+// article could load a tracking script or run code on every reader's
+// visit. The policy allows a set of formatting elements and attributes,
+// iframes from known video and slide hosts, and nothing else. Scripts
+// and inline event handlers are refused unless the article allows them
+// in its front matter:
+//
+//	allowscripts = ["platform.twitter.com"]   # hosts scripts may load from
+//	allowscripts = ["inline"]                 # <script> without src
+//	allowhandlers = true                      # onclick= and friends
+//
+// HTML inside code blocks and code spans is text, not markup, and is not
+// checked. Code is found by content.MaskCode, which parses the body as
+// Hugo does, so backticks inside an HTML block or a tag cannot hide the
+// HTML around them.
+package htmlpolicy
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Violation is raw HTML the policy does not accept.
+type Violation struct {
+	File string
+	Line int
+	// Rule is the kind of violation: element, attribute, script, handler,
+	// iframe, url or style.
+	Rule    string
+	Message string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", v.File, v.Line, v.Rule, v.Message)
+}
+
+// global attributes are accepted on every allowed element, as are data-*
+// attributes.
+var global = set("class", "id", "title", "lang", "dir", "style")
+
+// elements maps each allowed element to the attributes it accepts besides
+// the global ones.
+var elements = map[string]map[string]bool{
+	"a":          set("href", "name", "rel", "target"),
+	"abbr":       nil,
+	"b":          nil,
+	"blockquote": set("cite", "align"),
+	"br":         nil,
+	"caption":    nil,
+	"center":     nil,
+	"cite":       nil,
+	"code":       nil,
+	"col":        set("span", "width"),
+	"colgroup":   set("span", "width"),
+	"dd":         nil,
+	"del":        set("cite", "datetime"),
+	"details":    set("open"),
+	"div":        set("align"),
+	"dl":         nil,
+	"dt":         nil,
+	"em":         nil,
+	"figcaption": nil,
+	"figure":     nil,
+	"footer":     nil,
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"hr":         nil,
+	"i":          nil,
+	"iframe":     set("src", "width", "height", "frameborder", "allowfullscreen", "webkitallowfullscreen", "mozallowfullscreen", "scrolling", "marginwidth", "marginheight", "allow"),
+	"img":        set("src", "alt", "width", "height", "align", "border"),
+	"ins":        set("cite", "datetime"),
+	"kbd":        nil,
+	"li":         nil,
+	"mark":       nil,
+	"ol":         set("start", "type"),
+	"p":          set("align"),
+	"pre":        nil,
+	"q":          set("cite"),
+	"s":          nil,
+	"samp":       nil,
+	"script":     set("src", "type", "async", "defer", "charset"),
+	"small":      nil,
+	"span":       nil,
+	"strong":     nil,
+	"sub":        nil,
+	"summary":    nil,
+	"sup":        nil,
+	"table":      set("width", "border", "cellpadding", "cellspacing", "align", "summary"),
+	"tbody":      nil,
+	"td":         set("colspan", "rowspan", "align", "valign", "width"),
+	"tfoot":      nil,
+	"th":         set("colspan", "rowspan", "align", "valign", "width", "scope"),
+	"thead":      nil,
+	"time":       set("datetime"),
+	"tr":         set("align", "valign"),
+	"u":          nil,
+	"ul":         nil,
+	"var":        nil,
+}
+
+// FrameHosts are the hosts iframes may embed, with their subdomains.
+var FrameHosts = []string{
+	"youtube.com",
+	"youtube-nocookie.com",
+	"player.vimeo.com",
+	"speakerdeck.com",
+	"slideshare.net",
+	"play.golang.org",
+}
+
+// urlAttrs hold URLs.
+var urlAttrs = set("href", "src", "cite", "action", "poster", "background")
+
+// dropContent elements are removed together with everything up to their
+// end tag when they are not allowed.
+var dropContent = set("script", "style", "iframe", "object", "noscript", "template")
+
+func set(names ...string) map[string]bool {
+	m := make(map[string]bool, len(names))
+	for _, n := range names {
+		m[n] = true
+	}
+	return m
+}
+
+// Allow is what an article's front matter permits beyond the policy.
+type Allow struct {
+	// ScriptHosts are the hosts scripts may be loaded from, with their
+	// subdomains.
+	ScriptHosts []string
+	// InlineScripts permits <script> elements without src.
+	InlineScripts bool
+	// Handlers permits on* event handler attributes.
+	Handlers bool
+}
+
+// ArticleAllow reads the allowlist of an article.
+func ArticleAllow(a *content.Article) Allow {
+	var allow Allow
+	for _, h := range a.Meta.Strings("allowscripts") {
+		if h == "inline" {
+			allow.InlineScripts = true
+			continue
+		}
+		allow.ScriptHosts = append(allow.ScriptHosts, h)
+	}
+	allow.Handlers = a.Meta.Bool("allowhandlers")
+	return allow
+}
+
+// tag is a start or end tag of the body and the violations it causes.
+type tag struct {
+	name       string
+	start, end int // offsets in the body
+	token      html.Token
+	violations []Violation
+	// drop is set if the whole tag is removed; badAttrs lists the
+	// attributes removed otherwise.
+	drop     bool
+	badAttrs map[int]bool
+}
+
+// scan tokenizes the raw HTML of a markdown body and applies the policy
+// to every tag.
+func scan(body []byte, allow Allow) []*tag {
+	z := html.NewTokenizer(bytes.NewReader(content.MaskCode(body)))
+	var tags []*tag
+	off := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return tags
+		}
+		raw := len(z.Raw())
+		start := off
+		off += raw
+		if tt != html.StartTagToken && tt != html.EndTagToken && tt != html.SelfClosingTagToken {
+			continue
+		}
+		t := &tag{start: start, end: off, token: z.Token()}
+		t.name = t.token.Data
+		if tt == html.EndTagToken {
+			if _, ok := elements[t.name]; !ok {
+				t.drop = true
+			}
+		} else {
+			check(t, allow)
+		}
+		tags = append(tags, t)
+	}
+}
+
+// check applies the policy to a start tag.
+func check(t *tag, allow Allow) {
+	flag := func(rule, format string, args ...any) {
+		t.violations = append(t.violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	attrs, ok := elements[t.name]
+	if !ok {
+		flag("element", "<%s> is not allowed", t.name)
+		t.drop = true
+		return
+	}
+	src := attr(t.token, "src")
+	switch t.name {
+	case "script":
+		if src == "" {
+			if !allow.InlineScripts {
+				flag("script", "inline script; add \"inline\" to allowscripts to keep it")
+				t.drop = true
+			}
+		} else if h := host(src); !matchHost(h, allow.ScriptHosts) {
+			flag("script", "script from %s; add it to allowscripts to keep it", h)
+			t.drop = true
+		}
+	case "iframe":
+		if h := host(src); !matchHost(h, FrameHosts) {
+			flag("iframe", "iframe of %s, which is not an embed host", h)
+			t.drop = true
+		}
+	}
+	if t.drop {
+		return
+	}
+	for i, a := range t.token.Attr {
+		name := strings.ToLower(a.Key)
+		switch {
+		case strings.HasPrefix(name, "on"):
+			if !allow.Handlers {
+				flag("handler", "<%s %s> event handler; set allowhandlers to keep it", t.name, name)
+				t.bad(i)
+			}
+		case !global[name] && !attrs[name] && !strings.HasPrefix(name, "data-"):
+			flag("attribute", "<%s %s> is not allowed", t.name, name)
+			t.bad(i)
+		case urlAttrs[name] && !safeURL(t.name, name, a.Val):
+			flag("url", "<%s %s=%q> has an unsafe scheme", t.name, name, a.Val)
+			t.bad(i)
+		case name == "style" && !safeStyle(a.Val):
+			flag("style", "<%s style=%q> may load or run code", t.name, a.Val)
+			t.bad(i)
+		}
+	}
+}
+
+func (t *tag) bad(i int) {
+	if t.badAttrs == nil {
+		t.badAttrs = make(map[int]bool)
+	}
+	t.badAttrs[i] = true
+}
+
+func attr(t html.Token, name string) string {
+	for _, a := range t.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// host returns the host of a URL, which may be protocol-relative.
+func host(s string) string {
+	u, err := url.Parse(strings.TrimSpace(s))
+	if err != nil || u.Host == "" {
+		return s
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// matchHost reports whether h is one of hosts or a subdomain of one.
+func matchHost(h string, hosts []string) bool {
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if h == allowed || strings.HasSuffix(h, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeURL rejects URLs that run code: javascript: and vbscript: anywhere,
+// and data: except for images.
+func safeURL(elem, attr, s string) bool {
+	s = strings.ToLower(strings.Map(func(r rune) rune {
+		if r <= ' ' {
+			return -1
+		}
+		return r
+	}, s))
+	switch {
+	case strings.HasPrefix(s, "javascript:"), strings.HasPrefix(s, "vbscript:"):
+		return false
+	case strings.HasPrefix(s, "data:"):
+		return elem == "img" && attr == "src" && strings.HasPrefix(s, "data:image/") && !strings.HasPrefix(s, "data:image/svg")
+	}
+	return true
+}
+
+// safeStyle rejects inline styles that can load resources or run script.
+func safeStyle(s string) bool {
+	s = strings.ToLower(s)
+	for _, bad := range []string{"url(", "expression(", "javascript:", "@import", "behavior:"} {
+		if strings.Contains(s, bad) {
+			return false
+		}
+	}
+	return true
+}
+
+// Check returns the violations in the raw HTML of a markdown body. file
+// and line, the line the body starts on, position the violations.
+func Check(file string, line int, body []byte, allow Allow) []Violation {
+	var out []Violation
+	for _, t := range scan(body, allow) {
+		at := line + bytes.Count(body[:t.start], []byte("\n"))
+		for _, v := range t.violations {
+			v.File, v.Line = file, at
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// CheckArticle checks an article body against the policy and the
+// article's allowlist.
+func CheckArticle(a *content.Article) []Violation {
+	return Check(a.File, a.Meta.BodyLine(), a.Body(), ArticleAllow(a))
+}
+
+// Sanitize returns body with the raw HTML the policy does not accept
+// removed: disallowed elements lose their tags, scripts, iframes and
+// styles lose their content as well, and disallowed attributes are
+// dropped from tags that are otherwise kept. Everything else is left
+// byte for byte as it was.
+func Sanitize(body []byte, allow Allow) []byte {
+	var buf bytes.Buffer
+	tags := scan(body, allow)
+	off := 0
+	for i := 0; i < len(tags); i++ {
+		t := tags[i]
+		buf.Write(body[off:t.start])
+		off = t.end
+		switch {
+		case t.drop && t.token.Type == html.StartTagToken && dropContent[t.name]:
+			for j := i + 1; j < len(tags); j++ {
+				if tags[j].name == t.name && tags[j].token.Type == html.EndTagToken {
+					i, off = j, tags[j].end
+					break
+				}
+			}
+		case t.drop:
+		case len(t.badAttrs) > 0:
+			tok := t.token
+			tok.Attr = nil
+			for j, a := range t.token.Attr {
+				if !t.badAttrs[j] {
+					tok.Attr = append(tok.Attr, a)
+				}
+			}
+			buf.WriteString(tok.String())
+		default:
+			buf.Write(body[t.start:t.end])
+		}
+	}
+	buf.Write(body[off:])
+	return buf.Bytes()
+}
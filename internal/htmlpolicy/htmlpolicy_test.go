@@ -0,0 +1,84 @@
+package htmlpolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func rules(vs []Violation) []string {
+	var out []string
+	for _, v := range vs {
+		out = append(out, v.Rule)
+	}
+	return out
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string // rules of the violations, joined by spaces
+	}{
+		{"formatting", "Some <em>text</em> and <a href=\"https://golang.org\">a link</a>.\n", ""},
+		{"script", "<script src=\"https://evil.example/x.js\"></script>\n", "script"},
+		{"handler", "<img src=\"x.png\" onerror=\"alert(1)\">\n", "handler"},
+		{"javascript url", "<a href=\"javascript:alert(1)\">x</a>\n", "url"},
+		{"iframe", "<iframe src=\"https://evil.example/\"></iframe>\n", "iframe"},
+		{"code span", "Write `<script src=\"https://evil.example/x.js\"></script>` in the page.\n", ""},
+		{"fenced code", "```html\n<script src=\"https://evil.example/x.js\"></script>\n```\n", ""},
+		{"indented code", "Like this:\n\n    <img src=x onerror=\"alert(1)\">\n", ""},
+		{"autolink", "See <https://golang.org/> or mail <gophers@example.com>.\n", ""},
+
+		// Backticks in raw HTML start no code span, so they cannot hide
+		// the markup around them.
+		{"backticks in html block", "<div>\n`<script src=\"https://evil.example/x.js\"></script>`\n</div>\n", "script"},
+		{"backticks in attribute", "<img src=x title=\"`\" onerror=\"alert(1)\" x=\"`\">\n", "handler attribute"},
+		{"backticks in inline attribute", "Look: <img src=x title=\"`\" onerror=\"alert(1)\" x=\"`\"> here.\n", "handler attribute"},
+		{"backticks in quoted html block", "> <div>\n> `<script src=\"https://evil.example/x.js\"></script>`\n> </div>\n", "script"},
+	}
+	for _, tt := range tests {
+		got := strings.Join(rules(Check("a.md", 1, []byte(tt.body), Allow{})), " ")
+		if got != tt.want {
+			t.Errorf("%s: violations %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckLine(t *testing.T) {
+	vs := Check("a.md", 5, []byte("Intro.\n\n```\n<script>\n```\n\n<p onclick=\"x()\">hi</p>\n"), Allow{})
+	if len(vs) != 1 || vs[0].Line != 11 {
+		t.Errorf("Check = %v, want one violation on line 11", vs)
+	}
+}
+
+func TestAllow(t *testing.T) {
+	body := []byte("<script async src=\"https://platform.twitter.com/widgets.js\"></script>\n<button onclick=\"go()\">\n")
+	allow := Allow{ScriptHosts: []string{"twitter.com"}, Handlers: true}
+	if vs := Check("a.md", 1, body, allow); len(vs) != 1 || vs[0].Rule != "element" {
+		t.Errorf("Check = %v, want only the <button> element", vs)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		body, want string
+	}{
+		{
+			"<div>\n`<script src=\"https://evil.example/x.js\"></script>`\n</div>\n",
+			"<div>\n``\n</div>\n",
+		},
+		{
+			"<img src=x title=\"`\" onerror=\"alert(1)\" x=\"`\">\n",
+			"<img src=\"x\" title=\"`\">\n",
+		},
+		{
+			"Keep `<script>` in code.\n\n<p style=\"color: red\">ok</p>\n",
+			"Keep `<script>` in code.\n\n<p style=\"color: red\">ok</p>\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := string(Sanitize([]byte(tt.body), Allow{})); got != tt.want {
+			t.Errorf("Sanitize(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+// Package jobs keeps the Go job board of the main site.
+//
+// Each posting is a TOML file in data/jobs, named after the posting,
+// e.g. data/jobs/acme-backend-engineer.toml, best started as a copy of
+// data/jobs/_example.toml:
+//
+//	company = "Acme"
+//	role = "Backend Engineer"
+//	location = "Denver, CO"
+//	remote = true
+//	salary_min = 120000
+//	salary_max = 150000
+//	currency = "USD"
+//	apply = "https://acme.example.com/jobs/42"
+//	posted = 2015-01-12T00:00:00Z
+//	expires = 2015-03-12T00:00:00Z
+//	description = """
+//	What the job is, in plain paragraphs.
+//	"""
+//
+// Check rejects malformed and expired postings, and Build generates the
+// board's pages and feeds from the postings that have not expired, so
+// expired ones drop off the site the next time it is built even if
+// their files are still there. cmd/build publishes the board with the
+// main site.
+package jobs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Dir holds the postings, relative to the repository root.
+const Dir = "data/jobs"
+
+// MaxListing is the longest a posting can stay up.
+const MaxListing = 90 * 24 * time.Hour
+
+// Example is the ID of the posting new ones are copied from. It is
+// checked like the others, except for its dates falling due, and never
+// published.
+const Example = "_example"
+
+// Job is a posting.
+type Job struct {
+	// ID is the file name without ".toml", and the page's path under
+	// /jobs/.
+	ID      string `toml:"-"`
+	Company string `toml:"company"`
+	Role    string `toml:"role"`
+	// Location is where the job is; it may be empty for remote jobs.
+	Location string `toml:"location"`
+	Remote   bool   `toml:"remote"`
+	// SalaryMin and SalaryMax are the yearly salary range in Currency,
+	// or both 0 if the company gives none.
+	SalaryMin   int       `toml:"salary_min"`
+	SalaryMax   int       `toml:"salary_max"`
+	Currency    string    `toml:"currency"`
+	Apply       string    `toml:"apply"`
+	Posted      time.Time `toml:"posted"`
+	Expires     time.Time `toml:"expires"`
+	Description string    `toml:"description"`
+}
+
+// Expired reports whether the posting is down at now.
+func (j Job) Expired(now time.Time) bool {
+	return !now.Before(j.Expires)
+}
+
+// Problem is something wrong with a posting.
+type Problem struct {
+	File    string
+	Line    int
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s: %s", p.File, p.Rule, p.Message)
+	}
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Rule, p.Message)
+}
+
+var (
+	idRE       = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	currencyRE = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// Load reads the postings in dir. Files that cannot be decoded are
+// reported as problems rather than returned.
+func Load(dir string) ([]Job, []Problem, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return nil, nil, err
+	}
+	var jobs []Job
+	var problems []Problem
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := filepath.ToSlash(file)
+		var j Job
+		md, err := toml.Decode(string(b), &j)
+		if err != nil {
+			p := Problem{File: name, Rule: "syntax", Message: err.Error()}
+			if pe, ok := err.(toml.ParseError); ok {
+				p.Line, p.Message = pe.Position.Line, pe.Message
+			}
+			problems = append(problems, p)
+			continue
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			problems = append(problems, Problem{File: name, Rule: "syntax", Message: fmt.Sprintf("unknown keys %v", undecoded)})
+			continue
+		}
+		j.ID = strings.TrimSuffix(filepath.Base(file), ".toml")
+		jobs = append(jobs, j)
+	}
+	return jobs, problems, nil
+}
+
+// Check returns the problems of a posting at now. dir is the directory
+// it was loaded from, for the reports.
+func Check(dir string, j Job, now time.Time) []Problem {
+	file := filepath.ToSlash(filepath.Join(dir, j.ID+".toml"))
+	var problems []Problem
+	report := func(rule, format string, args ...any) {
+		problems = append(problems, Problem{File: file, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	if j.ID != Example && !idRE.MatchString(j.ID) {
+		report("name", "file names are lower-case words joined by hyphens, like acme-backend-engineer.toml")
+	}
+	if strings.TrimSpace(j.Company) == "" || strings.TrimSpace(j.Role) == "" {
+		report("required", "company and role are required")
+	}
+	if strings.TrimSpace(j.Location) == "" && !j.Remote {
+		report("required", "give a location, or set remote = true")
+	}
+	if strings.TrimSpace(j.Description) == "" {
+		report("required", "description is required")
+	}
+	if !validApply(j.Apply) {
+		report("apply", "apply must be an http(s) or mailto: link, not %q", j.Apply)
+	}
+	switch {
+	case j.SalaryMin == 0 && j.SalaryMax == 0:
+		if j.Currency != "" {
+			report("salary", "currency without a salary range")
+		}
+	case j.SalaryMin <= 0 || j.SalaryMax < j.SalaryMin:
+		report("salary", "salary_min and salary_max must both be given, positive, and in order")
+	case !currencyRE.MatchString(j.Currency):
+		report("salary", "currency must be a three-letter code like USD, not %q", j.Currency)
+	}
+	switch {
+	case j.Posted.IsZero() || j.Expires.IsZero():
+		report("dates", "posted and expires are required")
+	case !j.Expires.After(j.Posted):
+		report("dates", "expires is not after posted")
+	case j.Expires.Sub(j.Posted) > MaxListing:
+		report("dates", "postings stay up at most %d days", int(MaxListing.Hours()/24))
+	case j.ID == Example:
+	case j.Expired(now):
+		report("expired", "expired on %s; remove the file", j.Expires.Format("2006-01-02"))
+	case j.Posted.After(now):
+		report("dates", "posted is in the future")
+	}
+	return problems
+}
+
+func validApply(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto":
+		return u.Opaque != ""
+	}
+	return false
+}
+
+// Active returns the postings that pass Check at now, and so are up,
+// newest first. The example is left out.
+func Active(dir string, jobs []Job, now time.Time) []Job {
+	var out []Job
+	for _, j := range jobs {
+		if j.ID != Example && len(Check(dir, j, now)) == 0 {
+			out = append(out, j)
+		}
+	}
+	sort.SliceStable(out, func(i, k int) bool {
+		if !out[i].Posted.Equal(out[k].Posted) {
+			return out[i].Posted.After(out[k].Posted)
+		}
+		return out[i].ID < out[k].ID
+	})
+	return out
+}
+
+var nonSlugRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug returns the path segment of a location or company page.
+func Slug(s string) string {
+	return strings.Trim(nonSlugRE.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
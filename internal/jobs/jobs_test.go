@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// posting is a valid posting, posted on 2015-01-12 for two months.
+const posting = `company = "Acme"
+role = "%s"
+location = "Denver, CO"
+apply = "mailto:jobs@example.com"
+posted = 2015-01-12T00:00:00Z
+expires = 2015-03-12T00:00:00Z
+description = "Write Go."
+`
+
+func writeJobs(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	dir := filepath.Join(root, Dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestCommittedExample(t *testing.T) {
+	dir := filepath.Join("..", "..", Dir)
+	all, problems, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, j := range all {
+		if j.ID == Example {
+			found = true
+			problems = append(problems, Check(dir, j, time.Now())...)
+		}
+	}
+	if !found {
+		t.Fatalf("no %s.toml in %s", Example, dir)
+	}
+	for _, p := range problems {
+		t.Error(p)
+	}
+}
+
+func TestExample(t *testing.T) {
+	root := writeJobs(t, map[string]string{
+		Example + ".toml":       strings.Replace(posting, "%s", "Example", 1),
+		"acme-gopher.toml":      strings.Replace(posting, "%s", "Gopher", 1),
+		"acme-bad-gopher.toml":  strings.Replace(posting, "%s", "", 1),
+		"acme-late-gopher.toml": strings.Replace(strings.Replace(posting, "%s", "Late Gopher", 1), "2015-03-12", "2015-01-20", 1),
+	})
+	dir := filepath.Join(root, Dir)
+	all, _, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Long after the postings expire, the example still passes.
+	later := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, j := range all {
+		problems := Check(dir, j, later)
+		if j.ID == Example && len(problems) > 0 {
+			t.Errorf("example: %v", problems)
+		}
+		if j.ID == "acme-gopher" && (len(problems) != 1 || problems[0].Rule != "expired") {
+			t.Errorf("expired posting: %v", problems)
+		}
+	}
+
+	// A broken example is reported.
+	broken := Job{ID: Example, Company: "Acme", Location: "Denver, CO", Description: "Write Go.", Apply: "jobs@example.com",
+		Posted: time.Date(2015, 1, 12, 0, 0, 0, 0, time.UTC), Expires: time.Date(2015, 3, 12, 0, 0, 0, 0, time.UTC)}
+	var rules []string
+	for _, p := range Check(dir, broken, later) {
+		rules = append(rules, p.Rule)
+	}
+	if strings.Join(rules, " ") != "required apply" {
+		t.Errorf("broken example: rules %v, want required and apply", rules)
+	}
+
+	// Publishing leaves out the example, the expired and the malformed.
+	now := time.Date(2015, 2, 1, 0, 0, 0, 0, time.UTC)
+	out := filepath.Join(root, "public-main")
+	n, problems, err := Publish(root, out, "http://www.gopheracademy.com", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("published %d postings, want 1", n)
+	}
+	var left []string
+	for _, p := range problems {
+		left = append(left, filepath.Base(p.File)+": "+p.Rule)
+	}
+	if want := "acme-bad-gopher.toml: required acme-late-gopher.toml: expired"; strings.Join(left, " ") != want {
+		t.Errorf("left out %q, want %q", left, want)
+	}
+	for _, page := range []string{"index.html", "acme-gopher/index.html", "index.xml", "feed.json"} {
+		if _, err := os.Stat(filepath.Join(out, "jobs", page)); err != nil {
+			t.Errorf("board: %v", err)
+		}
+	}
+	for _, page := range []string{Example, "acme-late-gopher", "acme-bad-gopher"} {
+		if _, err := os.Stat(filepath.Join(out, "jobs", page)); err == nil {
+			t.Errorf("board has a page for %s", page)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package jobs
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var pageTmpl = template.Must(template.New("page.html").Funcs(template.FuncMap{
+	"paragraphs": paragraphs,
+}).ParseFS(templates, "templates/page.html"))
+
+// Path is where the board is published on the main site.
+const Path = "/jobs/"
+
+// Where returns where the job is, as the board shows it.
+func (j Job) Where() string {
+	switch {
+	case j.Location == "":
+		return "Remote"
+	case j.Remote:
+		return j.Location + " or remote"
+	}
+	return j.Location
+}
+
+// Salary returns the salary range, or "".
+func (j Job) Salary() string {
+	if j.SalaryMax == 0 {
+		return ""
+	}
+	return j.Currency + " " + thousands(j.SalaryMin) + "–" + thousands(j.SalaryMax)
+}
+
+// URL returns the path of the posting's page.
+func (j Job) URL() string {
+	return Path + j.ID + "/"
+}
+
+func thousands(n int) string {
+	s := strconv.Itoa(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+func paragraphs(text string) []string {
+	var out []string
+	for _, p := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Filter is a link to one of the filtered listings.
+type Filter struct {
+	Name  string
+	URL   string
+	Count int
+}
+
+// page is what the template renders.
+type page struct {
+	Title   string
+	URL     string
+	Jobs    []Job
+	Job     *Job
+	Remote  Filter
+	Where   []Filter
+	Company []Filter
+}
+
+// filters returns the filters for the values key takes on jobs, by
+// name, and the jobs under each.
+func filters(jobs []Job, prefix string, key func(Job) string) ([]Filter, map[string][]Job) {
+	byURL := make(map[string][]Job)
+	names := make(map[string]string)
+	for _, j := range jobs {
+		name := key(j)
+		if name == "" {
+			continue
+		}
+		u := Path + prefix + "/" + Slug(name) + "/"
+		byURL[u] = append(byURL[u], j)
+		names[u] = name
+	}
+	var out []Filter
+	for u, js := range byURL {
+		out = append(out, Filter{Name: names[u], URL: u, Count: len(js)})
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+	return out, byURL
+}
+
+// Build generates the board from the active jobs into dir/jobs, the
+// publish directory of the main site: the listing of every job, the
+// listings filtered by remote work, location and company, a page per
+// job, an RSS feed at index.xml and a JSON Feed at feed.json. base is
+// the main site's URL. The old board is removed first.
+func Build(dir, base string, jobs []Job, now time.Time) error {
+	root := filepath.Join(dir, filepath.FromSlash(Path))
+	if err := os.RemoveAll(root); err != nil {
+		return err
+	}
+	var remote []Job
+	for _, j := range jobs {
+		if j.Remote || j.Location == "" {
+			remote = append(remote, j)
+		}
+	}
+	where, byWhere := filters(jobs, "location", func(j Job) string { return j.Location })
+	company, byCompany := filters(jobs, "company", func(j Job) string { return j.Company })
+	nav := page{
+		Remote:  Filter{Name: "Remote", URL: Path + "remote/", Count: len(remote)},
+		Where:   where,
+		Company: company,
+	}
+	listing := func(title, u string, js []Job) page {
+		p := nav
+		p.Title, p.URL, p.Jobs = title, u, js
+		return p
+	}
+	pages := []page{
+		listing("Go jobs", Path, jobs),
+		listing("Remote Go jobs", Path+"remote/", remote),
+	}
+	for _, f := range where {
+		pages = append(pages, listing("Go jobs in "+f.Name, f.URL, byWhere[f.URL]))
+	}
+	for _, f := range company {
+		pages = append(pages, listing("Go jobs at "+f.Name, f.URL, byCompany[f.URL]))
+	}
+	for i := range jobs {
+		p := nav
+		p.Title, p.URL, p.Job = jobs[i].Role+" at "+jobs[i].Company, jobs[i].URL(), &jobs[i]
+		pages = append(pages, p)
+	}
+	for _, p := range pages {
+		var buf bytes.Buffer
+		if err := pageTmpl.Execute(&buf, p); err != nil {
+			return err
+		}
+		if err := write(dir, path.Join(p.URL, "index.html"), buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	rss, err := RSS(base, jobs, now)
+	if err != nil {
+		return err
+	}
+	if err := write(dir, Path+"index.xml", rss); err != nil {
+		return err
+	}
+	feed, err := JSONFeed(base, jobs)
+	if err != nil {
+		return err
+	}
+	return write(dir, Path+"feed.json", feed)
+}
+
+// Publish builds the board from the postings of the repository at root
+// into dir, the publish directory of the site at base. It returns the
+// number of postings published and the problems of the ones left out.
+func Publish(root, dir, base string, now time.Time) (int, []Problem, error) {
+	src := filepath.Join(root, Dir)
+	all, problems, err := Load(src)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, j := range all {
+		if j.ID != Example {
+			problems = append(problems, Check(src, j, now)...)
+		}
+	}
+	active := Active(src, all, now)
+	return len(active), problems, Build(dir, base, active, now)
+}
+
+func write(dir, urlPath string, b []byte) error {
+	file := filepath.Join(dir, filepath.FromSlash(urlPath))
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, b, 0644)
+}
+
+// summary is the one-line description of a job in the feeds.
+func summary(j Job) string {
+	s := j.Where()
+	if sal := j.Salary(); sal != "" {
+		s += ", " + sal
+	}
+	return s + ". Apply by " + j.Expires.Format("January 2, 2006") + ": " + j.Apply
+}
+
+// RSS returns the RSS 2.0 feed of jobs.
+func RSS(base string, jobs []Job, now time.Time) ([]byte, error) {
+	base = strings.TrimSuffix(base, "/")
+	type item struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		GUID        string `xml:"guid"`
+		PubDate     string `xml:"pubDate"`
+		Description string `xml:"description"`
+	}
+	type channel struct {
+		Title         string `xml:"title"`
+		Link          string `xml:"link"`
+		Description   string `xml:"description"`
+		LastBuildDate string `xml:"lastBuildDate"`
+		Items         []item `xml:"item"`
+	}
+	feed := struct {
+		XMLName xml.Name `xml:"rss"`
+		Version string   `xml:"version,attr"`
+		Channel channel  `xml:"channel"`
+	}{Version: "2.0", Channel: channel{
+		Title:         "Go jobs on Gopher Academy",
+		Link:          base + Path,
+		Description:   "Go jobs posted by the companies supporting Gopher Academy and GopherCon.",
+		LastBuildDate: now.Format(time.RFC1123Z),
+	}}
+	for _, j := range jobs {
+		feed.Channel.Items = append(feed.Channel.Items, item{
+			Title:       j.Role + " at " + j.Company,
+			Link:        base + j.URL(),
+			GUID:        base + j.URL(),
+			PubDate:     j.Posted.Format(time.RFC1123Z),
+			Description: summary(j) + "\n\n" + strings.TrimSpace(j.Description),
+		})
+	}
+	b, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), append(b, '\n')...), nil
+}
+
+// JSONFeed returns the JSON Feed 1.1 of jobs. Each item carries the
+// posting's fields in a "_job" extension.
+func JSONFeed(base string, jobs []Job) ([]byte, error) {
+	base = strings.TrimSuffix(base, "/")
+	type ext struct {
+		Company   string `json:"company"`
+		Role      string `json:"role"`
+		Location  string `json:"location,omitempty"`
+		Remote    bool   `json:"remote"`
+		SalaryMin int    `json:"salary_min,omitempty"`
+		SalaryMax int    `json:"salary_max,omitempty"`
+		Currency  string `json:"currency,omitempty"`
+		Apply     string `json:"apply"`
+		Expires   string `json:"expires"`
+	}
+	type item struct {
+		ID            string `json:"id"`
+		URL           string `json:"url"`
+		ExternalURL   string `json:"external_url,omitempty"`
+		Title         string `json:"title"`
+		Summary       string `json:"summary"`
+		ContentText   string `json:"content_text"`
+		DatePublished string `json:"date_published"`
+		Job           ext    `json:"_job"`
+	}
+	feed := struct {
+		Version     string `json:"version"`
+		Title       string `json:"title"`
+		HomePageURL string `json:"home_page_url"`
+		FeedURL     string `json:"feed_url"`
+		Items       []item `json:"items"`
+	}{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       "Go jobs on Gopher Academy",
+		HomePageURL: base + Path,
+		FeedURL:     base + Path + "feed.json",
+		Items:       []item{},
+	}
+	for _, j := range jobs {
+		it := item{
+			ID:            base + j.URL(),
+			URL:           base + j.URL(),
+			Title:         j.Role + " at " + j.Company,
+			Summary:       summary(j),
+			ContentText:   strings.TrimSpace(j.Description),
+			DatePublished: j.Posted.Format(time.RFC3339),
+			Job: ext{
+				Company: j.Company, Role: j.Role, Location: j.Location, Remote: j.Remote,
+				SalaryMin: j.SalaryMin, SalaryMax: j.SalaryMax, Currency: j.Currency,
+				Apply: j.Apply, Expires: j.Expires.Format(time.RFC3339),
+			},
+		}
+		if strings.HasPrefix(j.Apply, "http") {
+			it.ExternalURL = j.Apply
+		}
+		feed.Items = append(feed.Items, it)
+	}
+	b, err := json.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
@@ -0,0 +1,141 @@
+package license
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ImagesFile records where the images of articles come from, relative
+// to the repository root.
+const ImagesFile = "data/images.toml"
+
+// ImageDir holds the images of articles, relative to the repository
+// root.
+const ImageDir = "static/postimages"
+
+// imageExts are the extensions of the files under ImageDir that are
+// images.
+var imageExts = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true}
+
+// Image is an entry of ImagesFile.
+type Image struct {
+	// File is the image's path under ImageDir, or a pattern, as
+	// path.Match takes, for several images. The first entry that
+	// matches an image is the one that applies to it.
+	File string `toml:"file"`
+	// Original is set for images the article's authors made
+	// themselves, which are under the article's prose license.
+	Original bool `toml:"original"`
+	// Author, Source and License describe a third-party image: who
+	// made it, the page it was taken from, and the SPDX identifier of
+	// the license it is used under. Source and License are required.
+	Author  string `toml:"author"`
+	Source  string `toml:"source"`
+	License string `toml:"license"`
+	// Unrecorded marks images added before ImagesFile, whose
+	// provenance was never recorded; they pass the check until someone
+	// looks it up.
+	Unrecorded bool `toml:"unrecorded"`
+}
+
+// LoadImages reads the entries of ImagesFile from file.
+func LoadImages(file string) ([]Image, error) {
+	var doc struct {
+		Images []Image `toml:"image"`
+	}
+	md, err := toml.DecodeFile(file, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("%s: unknown keys %v", file, undecoded)
+	}
+	return doc.Images, nil
+}
+
+// CheckImages checks the images under root's ImageDir against entries:
+// every image needs an entry, every third-party entry a source and a
+// license, and every entry an image. It also returns the number of
+// images whose provenance is unrecorded.
+func CheckImages(root string, entries []Image) ([]Problem, int, error) {
+	var problems []Problem
+	report := func(file, rule, format string, args ...any) {
+		problems = append(problems, Problem{File: file, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	bad := make([]bool, len(entries))
+	for i, e := range entries {
+		where := ImagesFile + ": " + e.File
+		if _, err := path.Match(e.File, ""); err != nil || e.File == "" {
+			report(ImagesFile, "file", "bad file pattern %q", e.File)
+			bad[i] = true
+			continue
+		}
+		switch {
+		case e.Original || e.Unrecorded:
+			if e.Original && e.Unrecorded {
+				report(where, "source", "original and unrecorded contradict each other")
+			}
+		case e.Source == "" || e.License == "":
+			report(where, "source", "third-party images need a source and a license, or original = true")
+		case !validSource(e.Source):
+			report(where, "source", "source must be an http(s) URL, not %q", e.Source)
+		}
+		if e.License != "" && !Valid(e.License) {
+			report(where, "license", "%q is not an SPDX license identifier", e.License)
+		}
+	}
+
+	var files []string
+	dir := filepath.Join(root, ImageDir)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !imageExts[strings.ToLower(filepath.Ext(p))] {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Strings(files)
+
+	used := make([]bool, len(entries))
+	unrecorded := 0
+	for _, f := range files {
+		matched := false
+		for i, e := range entries {
+			if ok, _ := path.Match(e.File, f); ok {
+				matched, used[i] = true, true
+				if e.Unrecorded {
+					unrecorded++
+				}
+				break
+			}
+		}
+		if !matched {
+			report(path.Join(ImageDir, f), "unlisted", "no entry in %s says where the image comes from", ImagesFile)
+		}
+	}
+	for i, e := range entries {
+		if !used[i] && !bad[i] {
+			report(ImagesFile+": "+e.File, "missing", "matches no image under %s", ImageDir)
+		}
+	}
+	return problems, unrecorded, nil
+}
+
+func validSource(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
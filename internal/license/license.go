@@ -0,0 +1,113 @@
+// Package license works out the licenses articles are published under
+// and checks that the images they use record where they came from.
+//
+// An article's prose and its code samples can be under different
+// licenses. The site config sets both in its params, from sites.toml,
+// and an article's front matter can override either:
+//
+//	[license]
+//	prose = "CC-BY-4.0"
+//	code = "Apache-2.0"
+//
+// Licenses are SPDX identifiers, which the templates link to
+// https://spdx.org/licenses/<id>.html.
+package license
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Param is the front matter key, and the site param, holding the
+// licenses.
+const Param = "license"
+
+// Terms are the licenses of an article.
+type Terms struct {
+	// Prose is the license of the text.
+	Prose string
+	// Code is the license of the code samples.
+	Code string
+}
+
+// Problem is a license that is missing or malformed.
+type Problem struct {
+	File    string
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Rule, p.Message)
+}
+
+// idRE matches the characters of SPDX license identifiers.
+var idRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.+-]*$`)
+
+// Valid reports whether id looks like an SPDX license identifier.
+func Valid(id string) bool {
+	return idRE.MatchString(id)
+}
+
+// parse reads the prose and code keys of a license table into t,
+// reporting anything else in it.
+func parse(v any, t *Terms) []string {
+	table, ok := v.(map[string]any)
+	if !ok {
+		return []string{fmt.Sprintf("%s is a table with prose and code keys, not %v", Param, v)}
+	}
+	var errs []string
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		id, ok := table[k].(string)
+		switch {
+		case k != "prose" && k != "code":
+			errs = append(errs, fmt.Sprintf("unknown key %s.%s; use prose or code", Param, k))
+		case !ok || !Valid(id):
+			errs = append(errs, fmt.Sprintf("%s.%s is not an SPDX license identifier: %v", Param, k, table[k]))
+		case k == "prose":
+			t.Prose = id
+		default:
+			t.Code = id
+		}
+	}
+	return errs
+}
+
+// Defaults returns the licenses set in the params of the site config c.
+func Defaults(c *content.Config) (Terms, error) {
+	var t Terms
+	v, ok := c.Params[Param]
+	if !ok {
+		return t, fmt.Errorf("%s: no %s in params", c.File, Param)
+	}
+	if errs := parse(v, &t); len(errs) > 0 {
+		return t, fmt.Errorf("%s: params: %s", c.File, errs[0])
+	}
+	if t.Prose == "" || t.Code == "" {
+		return t, fmt.Errorf("%s: params: %s needs both prose and code", c.File, Param)
+	}
+	return t, nil
+}
+
+// Of returns the licenses of a, taking those its front matter leaves
+// out from def, and the problems with its front matter.
+func Of(a *content.Article, def Terms) (Terms, []Problem) {
+	t := def
+	v, ok := a.Meta.Get(Param)
+	if !ok {
+		return t, nil
+	}
+	var problems []Problem
+	for _, msg := range parse(v, &t) {
+		problems = append(problems, Problem{File: a.File, Rule: "license", Message: msg})
+	}
+	return t, problems
+}
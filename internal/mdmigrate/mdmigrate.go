@@ -0,0 +1,296 @@
+// Package mdmigrate compares how articles render with the markdown
+// engine the site was written for, Blackfriday, and with Goldmark, the
+// engine current Hugo uses, and classifies the differences.
+//
+// Both renderings are parsed and normalized, so that differences in
+// whitespace, attribute order, entity spelling and XHTML-style void tags
+// do not count. The top-level blocks are then diffed, and each changed
+// run of blocks is put into the class that explains it: typography
+// (smart quotes and dashes), heading ids, tabs in code that Blackfriday
+// expanded, raw HTML that Goldmark drops, tables, lists, other code
+// block changes, or other.
+package mdmigrate
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/russross/blackfriday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	gmhtml "github.com/yuin/goldmark/renderer/html"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// The classes of differences.
+const (
+	Typography = "typography"
+	HeadingIDs = "heading ids"
+	RawHTML    = "raw html"
+	Table      = "table"
+	List       = "list"
+	Code       = "code block"
+	Tabs       = "code tabs"
+	Other      = "other"
+)
+
+// Old renders markdown as Hugo did with Blackfriday.
+func Old(md []byte) []byte {
+	flags := blackfriday.HTML_USE_XHTML |
+		blackfriday.HTML_USE_SMARTYPANTS |
+		blackfriday.HTML_SMARTYPANTS_FRACTIONS |
+		blackfriday.HTML_SMARTYPANTS_DASHES |
+		blackfriday.HTML_SMARTYPANTS_LATEX_DASHES |
+		blackfriday.HTML_FOOTNOTE_RETURN_LINKS
+	extensions := blackfriday.EXTENSION_NO_INTRA_EMPHASIS |
+		blackfriday.EXTENSION_TABLES |
+		blackfriday.EXTENSION_FENCED_CODE |
+		blackfriday.EXTENSION_AUTOLINK |
+		blackfriday.EXTENSION_STRIKETHROUGH |
+		blackfriday.EXTENSION_SPACE_HEADERS |
+		blackfriday.EXTENSION_FOOTNOTES |
+		blackfriday.EXTENSION_HEADER_IDS |
+		blackfriday.EXTENSION_AUTO_HEADER_IDS
+	return blackfriday.Markdown(md, blackfriday.HtmlRenderer(flags, "", ""), extensions)
+}
+
+// New returns a renderer with Hugo's Goldmark defaults. Hugo omits raw
+// HTML unless markup.goldmark.renderer.unsafe is set; unsafe does the
+// same here.
+func New(unsafe bool) func(md []byte) ([]byte, error) {
+	var opts []goldmark.Option
+	opts = append(opts,
+		goldmark.WithExtensions(extension.GFM, extension.Typographer, extension.Footnote, extension.DefinitionList),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID(), parser.WithAttribute()),
+	)
+	if unsafe {
+		opts = append(opts, goldmark.WithRendererOptions(gmhtml.WithUnsafe()))
+	}
+	md := goldmark.New(opts...)
+	return func(src []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		err := md.Convert(src, &buf)
+		return buf.Bytes(), err
+	}
+}
+
+// Hunk is a run of blocks that render differently.
+type Hunk struct {
+	Class    string
+	Old, New string
+}
+
+// Compare diffs two renderings of the same markdown.
+func Compare(old, new []byte) ([]Hunk, error) {
+	a, err := blocks(old)
+	if err != nil {
+		return nil, err
+	}
+	b, err := blocks(new)
+	if err != nil {
+		return nil, err
+	}
+	var hunks []Hunk
+	var delA, insB []string
+	flush := func() {
+		if len(delA) == 0 && len(insB) == 0 {
+			return
+		}
+		if len(delA) == len(insB) {
+			// Block for block changes are classified one by one.
+			for i := range delA {
+				hunks = append(hunks, Hunk{Class: classify(delA[i], insB[i]), Old: delA[i], New: insB[i]})
+			}
+			delA, insB = nil, nil
+			return
+		}
+		o, n := strings.Join(delA, "\n"), strings.Join(insB, "\n")
+		hunks = append(hunks, Hunk{Class: classify(o, n), Old: o, New: n})
+		delA, insB = nil, nil
+	}
+	for _, op := range diff(a, b) {
+		switch op.kind {
+		case '=':
+			flush()
+		case '-':
+			delA = append(delA, a[op.i])
+		case '+':
+			insB = append(insB, b[op.j])
+		}
+	}
+	flush()
+	return hunks, nil
+}
+
+// Summary counts hunks by class.
+func Summary(hunks []Hunk) map[string]int {
+	m := make(map[string]int)
+	for _, h := range hunks {
+		m[h.Class]++
+	}
+	return m
+}
+
+// Classes returns the classes of m in a fixed order.
+func Classes(m map[string]int) []string {
+	order := []string{Table, List, RawHTML, Code, Tabs, Typography, HeadingIDs, Other}
+	var out []string
+	for _, c := range order {
+		if m[c] > 0 {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+var (
+	// typography undoes smart punctuation in normalized HTML, where
+	// straight quotes are escaped.
+	typography = strings.NewReplacer(
+		"‘", "&#39;", "’", "&#39;", "‚", "&#39;", "“", "&#34;", "”", "&#34;", "„", "&#34;", "«", "&#34;", "»", "&#34;",
+		"–", "-", "—", "-", "…", "...", "½", "1/2", "¼", "1/4", "¾", "3/4", "\u00a0", " ",
+	)
+	dashesRE = regexp.MustCompile(`-{2,}`)
+	idRE     = regexp.MustCompile(` id="[^"]*"`)
+)
+
+func untypeset(s string) string {
+	return dashesRE.ReplaceAllString(typography.Replace(s), "-")
+}
+
+func stripIDs(s string) string {
+	return idRE.ReplaceAllString(s, "")
+}
+
+// classify names the most likely cause of a difference. Structural
+// causes win over typography, which is ignored while looking for them.
+func classify(old, new string) string {
+	switch {
+	case stripIDs(old) == stripIDs(new):
+		return HeadingIDs
+	case untypeset(old) == untypeset(new):
+		return Typography
+	}
+	o, n := untypeset(stripIDs(old)), untypeset(stripIDs(new))
+	if o == n {
+		return HeadingIDs
+	}
+	if strings.ReplaceAll(o, "\t", "    ") == strings.ReplaceAll(n, "\t", "    ") {
+		return Tabs
+	}
+	has := func(tag string) bool {
+		return strings.Contains(o, "<"+tag) || strings.Contains(n, "<"+tag)
+	}
+	switch {
+	case strings.Contains(n, "raw HTML omitted"):
+		return RawHTML
+	case has("table"):
+		return Table
+	case has("ul") || has("ol") || has("li") || has("dl"):
+		return List
+	case has("pre"):
+		return Code
+	}
+	return Other
+}
+
+// blocks parses a rendering and returns its top-level nodes, each
+// normalized to a string.
+func blocks(b []byte) ([]string, error) {
+	body := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(bytes.NewReader(b), body)
+	if err != nil {
+		return nil, err
+	}
+	var out []string
+	for _, n := range nodes {
+		var sb strings.Builder
+		normalize(&sb, n, false)
+		if s := strings.TrimSpace(sb.String()); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// normalize writes n with sorted attributes, collapsed whitespace outside
+// <pre>, and text escaped the same way whatever its source spelling.
+func normalize(sb *strings.Builder, n *html.Node, pre bool) {
+	switch n.Type {
+	case html.TextNode:
+		text := n.Data
+		if !pre {
+			text = strings.Join(strings.Fields(text), " ")
+			if strings.TrimSpace(n.Data) != n.Data && text != "" {
+				text = " " + text + " "
+			}
+		}
+		sb.WriteString(html.EscapeString(text))
+	case html.CommentNode:
+		sb.WriteString("<!--" + strings.TrimSpace(n.Data) + "-->")
+	case html.ElementNode:
+		attrs := append([]html.Attribute(nil), n.Attr...)
+		sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+		sb.WriteString("<" + n.Data)
+		for _, a := range attrs {
+			sb.WriteString(" " + a.Key + `="` + html.EscapeString(a.Val) + `"`)
+		}
+		sb.WriteString(">")
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			normalize(sb, c, pre || n.DataAtom == atom.Pre)
+		}
+		sb.WriteString("</" + n.Data + ">")
+	}
+}
+
+// op is an edit: '=' keeps a[i] (equal to b[j]), '-' deletes a[i],
+// '+' inserts b[j].
+type op struct {
+	kind byte
+	i, j int
+}
+
+// diff returns the edit script from a to b, by longest common
+// subsequence.
+func diff(a, b []string) []op {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{'=', i, j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', i, j})
+			i++
+		default:
+			ops = append(ops, op{'+', i, j})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', i, j})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', i, j})
+	}
+	return ops
+}
@@ -0,0 +1,93 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Directive is a media shortcode in an article body.
+type Directive struct {
+	// Start and End are the offsets of the shortcode in the body.
+	Start, End int
+	// Params are the shortcode's named parameters.
+	Params map[string]string
+}
+
+var (
+	directiveRE = regexp.MustCompile(`\{\{<\s*media\s+((?:[a-z]+="[^"]*"\s*)*)>\}\}`)
+	paramRE     = regexp.MustCompile(`([a-z]+)="([^"]*)"`)
+)
+
+// params is the order parameters are written in.
+var params = []string{"url", "title", "provider", "thumb", "player"}
+
+// Find returns the media directives of a markdown body, outside code.
+func Find(body []byte) []Directive {
+	var out []Directive
+	masked := content.MaskCode(body)
+	for _, loc := range directiveRE.FindAllSubmatchIndex(masked, -1) {
+		d := Directive{Start: loc[0], End: loc[1], Params: make(map[string]string)}
+		for _, m := range paramRE.FindAllSubmatch(body[loc[2]:loc[3]], -1) {
+			d.Params[string(m[1])] = string(m[2])
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// String formats the directive as a shortcode.
+func (d Directive) String() string {
+	var b strings.Builder
+	b.WriteString("{{< media")
+	for _, k := range params {
+		if v := d.Params[k]; v != "" {
+			fmt.Fprintf(&b, " %s=%q", k, strings.ReplaceAll(v, `"`, "'"))
+		}
+	}
+	b.WriteString(" >}}")
+	return b.String()
+}
+
+// Fill sets the parameters the facade is rendered from. A title given
+// by the author is kept.
+func (d Directive) Fill(m *Media) {
+	if d.Params["title"] == "" {
+		d.Params["title"] = m.Title
+	}
+	d.Params["provider"] = m.Provider
+	d.Params["thumb"] = m.Thumb
+	d.Params["player"] = m.Player
+}
+
+// Sync fills in the media directives of a markdown body through f. It
+// returns the new body and the URLs nothing could be found for, whose
+// directives still render as plain links.
+func Sync(body []byte, f *Fetcher) ([]byte, []string, error) {
+	var buf bytes.Buffer
+	var missing []string
+	off := 0
+	for _, d := range Find(body) {
+		u := d.Params["url"]
+		if u == "" {
+			return nil, nil, fmt.Errorf("media directive without url: %s", body[d.Start:d.End])
+		}
+		m, err := f.Lookup(u)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", u, err)
+		}
+		if m == nil {
+			missing = append(missing, u)
+			continue
+		}
+		d.Fill(m)
+		buf.Write(body[off:d.Start])
+		buf.WriteString(d.String())
+		off = d.End
+	}
+	buf.Write(body[off:])
+	return buf.Bytes(), missing, nil
+}
@@ -0,0 +1,235 @@
+// Package media fills in the media directives of articles from oEmbed,
+// so that videos and slides render as a thumbnail that loads the player
+// only when clicked.
+//
+// An article embeds a video or a slide deck with the media shortcode:
+//
+//	{{< media url="https://www.youtube.com/watch?v=rD11pEx5h8c" >}}
+//
+// Sync looks the URL up through the provider's oEmbed endpoint, saves
+// the response under data/oembed and the thumbnail under static/media,
+// and writes the title, thumbnail and player URL into the directive. The
+// shortcode renders a facade from them, or a plain link while they are
+// missing. Saved responses are reused, so builds without network access
+// produce the same pages.
+package media
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Provider is a site whose media can be embedded.
+type Provider struct {
+	Name string
+	// Endpoint is the oEmbed endpoint.
+	Endpoint string
+	// match finds the media id in a URL.
+	match *regexp.Regexp
+	// player returns the URL of the player loaded on click.
+	player func(id string, r *Response) string
+}
+
+// Providers are the supported media sites.
+var Providers = []*Provider{
+	{
+		Name:     "youtube",
+		Endpoint: "https://www.youtube.com/oembed",
+		match:    regexp.MustCompile(`(?:youtube\.com/watch\?(?:.*&)?v=|youtu\.be/|youtube\.com/embed/)([A-Za-z0-9_-]{11})`),
+		player: func(id string, _ *Response) string {
+			return "https://www.youtube-nocookie.com/embed/" + id + "?autoplay=1"
+		},
+	},
+	{
+		Name:     "vimeo",
+		Endpoint: "https://vimeo.com/api/oembed.json",
+		match:    regexp.MustCompile(`vimeo\.com/(?:video/)?([0-9]+)`),
+		player: func(id string, _ *Response) string {
+			return "https://player.vimeo.com/video/" + id + "?autoplay=1"
+		},
+	},
+	{
+		Name:     "speakerdeck",
+		Endpoint: "https://speakerdeck.com/oembed.json",
+		match:    regexp.MustCompile(`speakerdeck\.com/([^/?#]+/[^/?#]+)`),
+		player: func(_ string, r *Response) string {
+			m := iframeSrcRE.FindStringSubmatch(r.HTML)
+			if m == nil {
+				return ""
+			}
+			if strings.HasPrefix(m[1], "//") {
+				return "https:" + m[1]
+			}
+			return m[1]
+		},
+	},
+}
+
+var iframeSrcRE = regexp.MustCompile(`<iframe[^>]*\ssrc="([^"]+)"`)
+
+// ErrUnsupported is returned for URLs of sites without a provider.
+var ErrUnsupported = errors.New("media: unsupported site")
+
+// Identify returns the provider of rawurl, the id of the media on the
+// provider's site, and a key naming the media, such as
+// "youtube-rD11pEx5h8c".
+func Identify(rawurl string) (*Provider, string, string, error) {
+	for _, p := range Providers {
+		if m := p.match.FindStringSubmatch(rawurl); m != nil {
+			return p, m[1], p.Name + "-" + strings.ReplaceAll(m[1], "/", "-"), nil
+		}
+	}
+	return nil, "", "", ErrUnsupported
+}
+
+// Response is the part of an oEmbed response the facade uses.
+type Response struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	HTML         string `json:"html"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// Media is what the facade of an embed is rendered from.
+type Media struct {
+	URL      string
+	Provider string
+	Title    string
+	Author   string
+	// Thumb is the site path of the cached thumbnail, or "" if none
+	// could be cached.
+	Thumb string
+	// Player is loaded in an iframe on click.
+	Player string
+}
+
+// Fetcher looks media up through oEmbed and caches the results.
+type Fetcher struct {
+	Client *http.Client
+	// Fixtures is the directory oEmbed responses are saved in, as
+	// <key>.json.
+	Fixtures string
+	// Static is the site's static directory; thumbnails are saved in its
+	// media subdirectory.
+	Static string
+	// Offline prevents requests: only saved responses and thumbnails
+	// are used.
+	Offline bool
+}
+
+// ThumbDir is the directory under the static root thumbnails are kept in.
+const ThumbDir = "media"
+
+// Lookup returns the media at rawurl. It returns nil and no error when
+// the fetcher is offline and nothing is saved for the URL.
+func (f *Fetcher) Lookup(rawurl string) (*Media, error) {
+	p, id, key, err := Identify(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	r, err := f.response(p, key, rawurl)
+	if r == nil || err != nil {
+		return nil, err
+	}
+	m := &Media{
+		URL:      rawurl,
+		Provider: p.Name,
+		Title:    r.Title,
+		Author:   r.AuthorName,
+		Player:   p.player(id, r),
+	}
+	if r.ThumbnailURL != "" {
+		thumb, err := f.thumbnail(key, r.ThumbnailURL)
+		if err != nil {
+			return nil, err
+		}
+		m.Thumb = thumb
+	}
+	return m, nil
+}
+
+// response returns the saved oEmbed response for key, fetching and
+// saving it first if needed.
+func (f *Fetcher) response(p *Provider, key, rawurl string) (*Response, error) {
+	file := filepath.Join(f.Fixtures, key+".json")
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		if f.Offline {
+			return nil, nil
+		}
+		b, err = f.get(p.Endpoint + "?format=json&url=" + url.QueryEscape(rawurl))
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(f.Fixtures, 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(file, b, 0644); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &Response{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return r, nil
+}
+
+// thumbnail returns the site path of the thumbnail for key, downloading
+// it first if needed. It returns "" when offline and not downloaded.
+func (f *Fetcher) thumbnail(key, rawurl string) (string, error) {
+	ext := path.Ext(strings.SplitN(rawurl, "?", 2)[0])
+	if ext == "" {
+		ext = ".jpg"
+	}
+	name := key + ext
+	file := filepath.Join(f.Static, ThumbDir, name)
+	if _, err := os.Stat(file); err == nil {
+		return "/" + ThumbDir + "/" + name, nil
+	}
+	if f.Offline {
+		return "", nil
+	}
+	b, err := f.get(rawurl)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, b, 0644); err != nil {
+		return "", err
+	}
+	return "/" + ThumbDir + "/" + name, nil
+}
+
+func (f *Fetcher) get(rawurl string) ([]byte, error) {
+	client := f.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Get(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", rawurl, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
@@ -0,0 +1,165 @@
+package media
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		url, provider, key string
+	}{
+		{"https://www.youtube.com/watch?v=rD11pEx5h8c", "youtube", "youtube-rD11pEx5h8c"},
+		{"https://www.youtube.com/watch?feature=share&v=rD11pEx5h8c", "youtube", "youtube-rD11pEx5h8c"},
+		{"https://youtu.be/rD11pEx5h8c", "youtube", "youtube-rD11pEx5h8c"},
+		{"https://vimeo.com/115309491", "vimeo", "vimeo-115309491"},
+		{"https://speakerdeck.com/campoy/go-tooling", "speakerdeck", "speakerdeck-campoy-go-tooling"},
+	}
+	for _, tt := range tests {
+		p, _, key, err := Identify(tt.url)
+		if err != nil || p.Name != tt.provider || key != tt.key {
+			t.Errorf("Identify(%s) = %v, %s, %v; want %s, %s", tt.url, p, key, err, tt.provider, tt.key)
+		}
+	}
+	if _, _, _, err := Identify("https://example.com/talk.mp4"); err != ErrUnsupported {
+		t.Errorf("Identify of an unknown site = %v, want ErrUnsupported", err)
+	}
+}
+
+// fakeYouTube serves an oEmbed endpoint and a thumbnail in place of
+// YouTube's for the duration of the test.
+func fakeYouTube(t *testing.T) *int {
+	requests := new(int)
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		switch r.URL.Path {
+		case "/oembed":
+			if r.FormValue("url") != "https://www.youtube.com/watch?v=rD11pEx5h8c" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"type":"video","title":"Go development in Vim","author_name":"fatih","thumbnail_url":"` + srv.URL + `/vi/rD11pEx5h8c/hqdefault.jpg","html":"<iframe></iframe>"}`))
+		case "/vi/rD11pEx5h8c/hqdefault.jpg":
+			w.Write([]byte("jpeg"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	p := Providers[0]
+	old := p.Endpoint
+	p.Endpoint = srv.URL + "/oembed"
+	t.Cleanup(func() { p.Endpoint = old })
+	return requests
+}
+
+const article = "Watch it:\n\n" +
+	`{{< media url="https://www.youtube.com/watch?v=rD11pEx5h8c" >}}` + "\n\n" +
+	"In code, directives are left alone:\n\n" +
+	"    {{< media url=\"https://www.youtube.com/watch?v=aaaaaaaaaaa\" >}}\n"
+
+func TestSync(t *testing.T) {
+	requests := fakeYouTube(t)
+	dir := t.TempDir()
+	f := &Fetcher{Fixtures: filepath.Join(dir, "data", "oembed"), Static: filepath.Join(dir, "static")}
+
+	// Offline, with nothing saved, the directive is left as it is, to
+	// render as a link.
+	f.Offline = true
+	body, missing, err := Sync([]byte(article), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != article || !reflect.DeepEqual(missing, []string{"https://www.youtube.com/watch?v=rD11pEx5h8c"}) {
+		t.Errorf("offline Sync = %q, missing %v; want the body unchanged and the video missing", body, missing)
+	}
+	if *requests > 0 {
+		t.Errorf("offline Sync made %d requests", *requests)
+	}
+
+	f.Offline = false
+	body, missing, err = Sync([]byte(article), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(article, `{{< media url="https://www.youtube.com/watch?v=rD11pEx5h8c" >}}`,
+		`{{< media url="https://www.youtube.com/watch?v=rD11pEx5h8c" title="Go development in Vim" provider="youtube" thumb="/media/youtube-rD11pEx5h8c.jpg" player="https://www.youtube-nocookie.com/embed/rD11pEx5h8c?autoplay=1" >}}`, 1)
+	if string(body) != want || len(missing) > 0 {
+		t.Errorf("Sync = %q, missing %v; want %q", body, missing, want)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "static", "media", "youtube-rD11pEx5h8c.jpg")); err != nil || string(b) != "jpeg" {
+		t.Errorf("thumbnail %q, %v", b, err)
+	}
+
+	// The saved response and thumbnail are enough offline, and an
+	// author's title is kept.
+	f.Offline = true
+	n := *requests
+	titled := strings.Replace(article, `>}}`, `title="Vim and Go" >}}`, 1)
+	body, _, err = Sync([]byte(titled), f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(body), `title="Vim and Go" provider="youtube" thumb="/media/youtube-rD11pEx5h8c.jpg"`) || *requests != n {
+		t.Errorf("offline Sync from saved files = %q after %d requests", body, *requests-n)
+	}
+}
+
+// shortcode stands in for Hugo's shortcode context.
+type shortcode map[string]string
+
+func (s shortcode) Get(key string) string { return s[key] }
+
+func render(t *testing.T, d Directive) string {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join("..", "..", "layouts", "shortcodes", "media.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := template.Must(template.New("media").Parse(string(b)))
+	var out strings.Builder
+	if err := tmpl.Execute(&out, shortcode(d.Params)); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestShortcode(t *testing.T) {
+	ds := Find([]byte(article))
+	if len(ds) != 1 {
+		t.Fatalf("found %d directives, want the one outside code", len(ds))
+	}
+	d := ds[0]
+	got := render(t, d)
+	if !strings.HasPrefix(got, `<p class="media-link">`) || !strings.Contains(got, `<a href="https://www.youtube.com/watch?v=rD11pEx5h8c">https://www.youtube.com/watch?v=rD11pEx5h8c</a>`) || strings.Contains(got, "<img") {
+		t.Errorf("unfilled directive renders as\n%s\nwant a plain link", got)
+	}
+	d.Params["title"] = "Go development in Vim"
+	if got := render(t, d); !strings.Contains(got, `>Go development in Vim</a>`) {
+		t.Errorf("unfilled directive with a title renders as\n%s", got)
+	}
+
+	d.Fill(&Media{Provider: "youtube", Title: "ignored", Thumb: "/media/youtube-rD11pEx5h8c.jpg", Player: "https://www.youtube-nocookie.com/embed/rD11pEx5h8c?autoplay=1"})
+	got = render(t, d)
+	for _, s := range []string{
+		`<div class="media-facade media-youtube" data-player="https://www.youtube-nocookie.com/embed/rD11pEx5h8c?autoplay=1">`,
+		`<a href="https://www.youtube.com/watch?v=rD11pEx5h8c" title="Go development in Vim">`,
+		`<img src="/media/youtube-rD11pEx5h8c.jpg" alt="Go development in Vim">`,
+		`<span class="media-title">Go development in Vim</span>`,
+	} {
+		if !strings.Contains(got, s) {
+			t.Errorf("filled directive renders as\n%s\nwithout %s", got, s)
+		}
+	}
+	if strings.Contains(got, "<iframe") {
+		t.Error("facade loads the player before it is clicked")
+	}
+}
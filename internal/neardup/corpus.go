@@ -0,0 +1,134 @@
+package neardup
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/archive"
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// FromHTML returns the document of an HTML page. The text of pre and
+// code elements is its code, and the rest of the text outside scripts
+// and styles its prose.
+func FromHTML(name string, r io.Reader) (*Doc, error) {
+	var prose, code strings.Builder
+	z := html.NewTokenizer(r)
+	inCode, skip := 0, 0
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() != io.EOF {
+				return nil, fmt.Errorf("%s: %v", name, z.Err())
+			}
+			return NewDoc(name, prose.String(), code.String()), nil
+		case html.StartTagToken:
+			switch tag, _ := z.TagName(); string(tag) {
+			case "pre", "code":
+				inCode++
+			case "script", "style":
+				skip++
+			}
+		case html.EndTagToken:
+			switch tag, _ := z.TagName(); string(tag) {
+			case "pre", "code":
+				inCode = max(inCode-1, 0)
+			case "script", "style":
+				skip = max(skip-1, 0)
+			}
+		case html.TextToken:
+			switch {
+			case skip > 0:
+			case inCode > 0:
+				code.Write(z.Text())
+				code.WriteByte('\n')
+			default:
+				prose.Write(z.Text())
+				prose.WriteByte(' ')
+			}
+		}
+	}
+}
+
+// fromFile returns the document of a corpus file: HTML, markdown, with
+// or without front matter, or plain text, which is taken as markdown.
+func fromFile(name string, b []byte) (*Doc, error) {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".html", ".htm":
+		return FromHTML(name, bytes.NewReader(b))
+	}
+	fm, err := content.ParseFile(b)
+	if err != nil {
+		return FromMarkdown(name, b), nil
+	}
+	return FromMarkdown(name, fm.Body()), nil
+}
+
+// corpusExts are the extensions of the files LoadCorpus reads.
+var corpusExts = map[string]bool{".html": true, ".htm": true, ".md": true, ".markdown": true, ".txt": true}
+
+// LoadCorpus returns the documents of the external posts in dir: the
+// pages of the web archive there, as kept by cmd/archive or fetched
+// with cmd/neardup fetch, and any HTML, markdown or text files saved
+// there by hand. A missing dir is an error, not an empty corpus, so that
+// a mistyped -corpus does not pass every submission.
+func LoadCorpus(dir string) ([]*Doc, error) {
+	fi, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("corpus: %v", err)
+	}
+	if !fi.IsDir() {
+		return nil, fmt.Errorf("corpus: %s is not a directory", dir)
+	}
+	a, err := archive.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	var docs []*Doc
+	for _, u := range a.URLs() {
+		resp, _, err := a.Response(u)
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", u, err)
+		}
+		typ, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		switch {
+		case typ == "text/html" || typ == "application/xhtml+xml":
+			d, err := FromHTML(u, bytes.NewReader(b))
+			if err != nil {
+				return nil, err
+			}
+			docs = append(docs, d)
+		case strings.HasPrefix(typ, "text/"):
+			docs = append(docs, FromMarkdown(u, b))
+		}
+	}
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !corpusExts[strings.ToLower(filepath.Ext(p))] {
+			return err
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		doc, err := fromFile(p, b)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, err
+}
@@ -0,0 +1,121 @@
+package neardup
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/archive"
+)
+
+func TestFromHTML(t *testing.T) {
+	page := `<html><head><style>body { color: red }</style><script>var x = 1;</script></head>
+<body><p>` + words(0, 40) + `</p><pre><code>` + "x := m[key]\nfmt.Println(x)" + `</code></pre></body></html>`
+	d, err := FromHTML("page.html", strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Shingles(ProseTokens(words(0, 40)), ProseShingle); Similarity(d.Prose, want) != 1 || len(d.Prose) != len(want) {
+		t.Errorf("prose has %d shingles, want the %d of the paragraph alone", len(d.Prose), len(want))
+	}
+	if want := Shingles(CodeTokens("x := m[key]\nfmt.Println(x)"), CodeShingle); len(d.Code) != len(want) {
+		t.Errorf("code has %d shingles, want %d", len(d.Code), len(want))
+	}
+}
+
+func TestLoadCorpus(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"saved.html":       "<html><body><p>" + words(100, 40) + "</p></body></html>",
+		"post.md":          "+++\ntitle = \"Post\"\n+++\n\n" + words(200, 40) + "\n",
+		"notes/draft.txt":  words(300, 40),
+		"image.png":        "not a corpus file",
+		"notes/README.MD":  words(400, 40),
+		"notes/empty.html": "",
+	}
+	for name, text := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A post fetched into the corpus as cmd/neardup fetch does, and a
+	// file it fetched that is not text.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/post":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			io.WriteString(w, "<html><body><p>"+words(500, 40)+"</p></body></html>")
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			io.WriteString(w, "\x89PNG")
+		}
+	}))
+	defer srv.Close()
+	a, err := archive.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/post", "/logo.png"} {
+		if err := a.Fetch(srv.URL+p, "corpus"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := a.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := LoadCorpus(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, d := range docs {
+		names = append(names, strings.TrimPrefix(filepath.ToSlash(strings.TrimPrefix(d.Name, dir)), "/"))
+	}
+	sort.Strings(names)
+	want := []string{"notes/README.MD", "notes/draft.txt", "notes/empty.html", "post.md", "saved.html", srv.URL + "/post"}
+	sort.Strings(want)
+	if strings.Join(names, " ") != strings.Join(want, " ") {
+		t.Fatalf("corpus %q, want %q", names, want)
+	}
+
+	// Each document is found again from its own text, front matter and
+	// markup left out.
+	for name, from := range map[string]int{"saved.html": 100, "post.md": 200, "notes/draft.txt": 300, srv.URL + "/post": 500} {
+		var d *Doc
+		for _, doc := range docs {
+			if strings.HasSuffix(filepath.ToSlash(doc.Name), name) {
+				d = doc
+			}
+		}
+		probe := NewDoc("probe", words(from, 40), "")
+		if m := Compare(probe, d); m.Prose != 1 || len(d.Prose) != len(probe.Prose) {
+			t.Errorf("%s: prose %.2f with %d shingles, want 1 with %d", name, m.Prose, len(d.Prose), len(probe.Prose))
+		}
+	}
+}
+
+func TestLoadCorpusMissing(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "corpus")
+	if _, err := LoadCorpus(dir); err == nil {
+		t.Error("missing corpus loaded")
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("loading a missing corpus created it: %v", err)
+	}
+	file := filepath.Join(t.TempDir(), "corpus.txt")
+	os.WriteFile(file, []byte(words(0, 40)), 0644)
+	if _, err := LoadCorpus(file); err == nil {
+		t.Error("a file loaded as a corpus")
+	}
+}
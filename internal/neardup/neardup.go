@@ -0,0 +1,168 @@
+// Package neardup finds articles that are near copies of each other, or
+// of posts published elsewhere.
+//
+// Texts are compared by shingling: each is cut into overlapping runs of
+// a few words, and two texts are alike in the measure their runs are
+// the same. Prose and code are compared apart, since an article may
+// rightly reuse another's code samples and tell a new story around them,
+// or the reverse. The similarity of two texts is the share of the
+// shorter one's shingles found in the longer, so that a submission that
+// copies half of a longer post scores as high as a full copy.
+package neardup
+
+import (
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Shingle lengths, in tokens. Code tokens are shorter than words, each
+// bracket and operator being one, so code shingles are longer.
+const (
+	ProseShingle = 5
+	CodeShingle  = 10
+)
+
+// MinShingles is the fewest shingles a text needs to be compared;
+// shorter texts, like a single go get line, match too easily.
+const MinShingles = 20
+
+// Set is a set of shingle hashes.
+type Set map[uint64]struct{}
+
+var (
+	wordRE  = regexp.MustCompile(`[\p{L}\p{N}]+`)
+	tokenRE = regexp.MustCompile(`[\p{L}\p{N}_]+|[^\s\p{L}\p{N}_]`)
+)
+
+// ProseTokens returns the words of text, in lower case.
+func ProseTokens(text string) []string {
+	return wordRE.FindAllString(strings.ToLower(text), -1)
+}
+
+// CodeTokens returns the identifiers, numbers and punctuation of code.
+func CodeTokens(code string) []string {
+	return tokenRE.FindAllString(code, -1)
+}
+
+// Shingles returns the set of runs of k tokens.
+func Shingles(tokens []string, k int) Set {
+	s := make(Set)
+	for i := 0; i+k <= len(tokens); i++ {
+		h := fnv.New64a()
+		for _, t := range tokens[i : i+k] {
+			h.Write([]byte(t))
+			h.Write([]byte{0})
+		}
+		s[h.Sum64()] = struct{}{}
+	}
+	return s
+}
+
+// Similarity returns the share of the smaller set found in the larger,
+// from 0 to 1. Sets with fewer than MinShingles shingles are never
+// similar.
+func Similarity(a, b Set) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(a) < MinShingles {
+		return 0
+	}
+	n := 0
+	for h := range a {
+		if _, ok := b[h]; ok {
+			n++
+		}
+	}
+	return float64(n) / float64(len(a))
+}
+
+// Doc is a shingled text.
+type Doc struct {
+	// Name is the file or URL the text came from.
+	Name  string
+	Prose Set
+	Code  Set
+}
+
+// NewDoc returns the document with the given prose and code.
+func NewDoc(name, prose, code string) *Doc {
+	return &Doc{
+		Name:  name,
+		Prose: Shingles(ProseTokens(prose), ProseShingle),
+		Code:  Shingles(CodeTokens(code), CodeShingle),
+	}
+}
+
+// FromMarkdown returns the document of a markdown body.
+func FromMarkdown(name string, body []byte) *Doc {
+	return NewDoc(name, content.Prose(body), string(content.Code(body)))
+}
+
+// FromArticle returns the document of an article.
+func FromArticle(a *content.Article) *Doc {
+	return FromMarkdown(a.File, a.Body())
+}
+
+// Match is a pair of similar documents.
+type Match struct {
+	A, B        *Doc
+	Prose, Code float64
+}
+
+// Max returns the higher of the prose and code similarities.
+func (m Match) Max() float64 {
+	return max(m.Prose, m.Code)
+}
+
+// Compare returns the similarity of a and b.
+func Compare(a, b *Doc) Match {
+	return Match{A: a, B: b, Prose: Similarity(a.Prose, b.Prose), Code: Similarity(a.Code, b.Code)}
+}
+
+// Pairs returns the pairs of docs whose prose or code similarity is at
+// least threshold, most similar first. skip, if not nil, leaves pairs
+// out, such as an article and its translation.
+func Pairs(docs []*Doc, threshold float64, skip func(a, b *Doc) bool) []Match {
+	var out []Match
+	for i, a := range docs {
+		for _, b := range docs[i+1:] {
+			if skip != nil && skip(a, b) {
+				continue
+			}
+			if m := Compare(a, b); m.Max() >= threshold {
+				out = append(out, m)
+			}
+		}
+	}
+	sortMatches(out)
+	return out
+}
+
+// Against returns the pairs of a doc of docs and one of corpus whose
+// prose or code similarity is at least threshold, most similar first.
+func Against(docs, corpus []*Doc, threshold float64) []Match {
+	var out []Match
+	for _, a := range docs {
+		for _, b := range corpus {
+			if m := Compare(a, b); m.Max() >= threshold {
+				out = append(out, m)
+			}
+		}
+	}
+	sortMatches(out)
+	return out
+}
+
+func sortMatches(ms []Match) {
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].Max() != ms[j].Max() {
+			return ms[i].Max() > ms[j].Max()
+		}
+		return ms[i].A.Name+ms[i].B.Name < ms[j].A.Name+ms[j].B.Name
+	})
+}
@@ -0,0 +1,92 @@
+package neardup
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// words returns n distinct words starting at the n-th, as prose.
+func words(from, n int) string {
+	var w []string
+	for i := from; i < from+n; i++ {
+		w = append(w, fmt.Sprintf("word%d", i))
+	}
+	return strings.Join(w, " ")
+}
+
+func TestTokens(t *testing.T) {
+	if got, want := ProseTokens("Go's *fast*, isn't it? 1.5 gophers"), []string{"go", "s", "fast", "isn", "t", "it", "1", "5", "gophers"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ProseTokens = %q, want %q", got, want)
+	}
+	if got, want := CodeTokens("x := m[key_1] + 2"), []string{"x", ":", "=", "m", "[", "key_1", "]", "+", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("CodeTokens = %q, want %q", got, want)
+	}
+}
+
+func TestShingles(t *testing.T) {
+	tokens := strings.Fields("a b c a b c")
+	if n := len(Shingles(tokens, 3)); n != 3 {
+		t.Errorf("%d shingles of 3 in %q, want 3: abc, bca and cab", n, tokens)
+	}
+	if n := len(Shingles(tokens, 7)); n != 0 {
+		t.Errorf("%d shingles longer than the text, want 0", n)
+	}
+	// Token boundaries count: "ab c" and "a bc" differ.
+	if a, b := Shingles([]string{"ab", "c"}, 2), Shingles([]string{"a", "bc"}, 2); reflect.DeepEqual(a, b) {
+		t.Error("shingles of different tokens with the same letters are equal")
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	prose := func(s string) Set { return Shingles(ProseTokens(s), ProseShingle) }
+	long := prose(words(0, 200))
+	tests := []struct {
+		name string
+		a, b Set
+		want float64
+	}{
+		{"identical", long, long, 1},
+		{"disjoint", long, prose(words(1000, 200)), 0},
+		// Half of the long text, copied: every shingle of the smaller
+		// set is in the larger, whichever order they are given in.
+		{"contained", prose(words(0, 100)), long, 1},
+		{"containing", long, prose(words(0, 100)), 1},
+		// The first 50 words of 100, followed by 50 new ones: 46 of the
+		// 96 shingles are shared.
+		{"half copied", prose(words(0, 50) + " " + words(500, 50)), long, 46.0 / 96},
+		// A short text is never similar, even to itself.
+		{"below MinShingles", prose(words(0, MinShingles+ProseShingle-2)), long, 0},
+		{"at MinShingles", prose(words(0, MinShingles+ProseShingle-1)), long, 1},
+		{"empty", Set{}, long, 0},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Similarity = %.3f, want %.3f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPairs(t *testing.T) {
+	a := NewDoc("a.md", words(0, 100), "")
+	copied := NewDoc("copied.md", words(0, 100), "")
+	translated := NewDoc("a.ja.md", words(0, 100), "")
+	other := NewDoc("other.md", words(500, 100), "")
+	// The same code with a new story around it.
+	code := "func mean(x []int, n int) (int, error) {\nfor i := 0; i < n; i++ { sum += x[i] }\nreturn sum / n, nil\n}"
+	code1 := NewDoc("code1.md", words(1000, 100), code)
+	code2 := NewDoc("code2.md", words(2000, 100), code)
+
+	docs := []*Doc{a, copied, translated, other, code1, code2}
+	skip := func(x, y *Doc) bool { return x == translated || y == translated }
+	var got []string
+	for _, m := range Pairs(docs, 0.5, skip) {
+		got = append(got, fmt.Sprintf("%s %s %.0f %.0f", m.A.Name, m.B.Name, m.Prose, m.Code))
+	}
+	want := []string{"a.md copied.md 1 0", "code1.md code2.md 0 1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Pairs = %q, want %q", got, want)
+	}
+}
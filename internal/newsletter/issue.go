@@ -0,0 +1,128 @@
+// Package newsletter builds email digests of new articles and delivers
+// them over SMTP.
+//
+// An issue covers the articles published since the previous issue of
+// the same scope: every article, or the articles of one series. Issues
+// are recorded in data/newsletter.toml and archived as web pages under
+// static/newsletter/<id>/, holding exactly the HTML and plain-text
+// bodies that are mailed. Delivery state, which addresses have received
+// an issue and which have bounced, is private and kept outside the
+// repository.
+package newsletter
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// LogFile is the issue log, relative to the repository root.
+const LogFile = "data/newsletter.toml"
+
+// ArchiveDir is where issues are archived, relative to the repository
+// root; Hugo publishes them at /newsletter/<id>/.
+const ArchiveDir = "static/newsletter"
+
+// Issue is a digest that has been built.
+type Issue struct {
+	ID   string    `toml:"id"`
+	Date time.Time `toml:"date"`
+	// Series is the series the issue covers, or "" for every article.
+	Series  string `toml:"series,omitempty"`
+	Subject string `toml:"subject"`
+	// Articles are the URLs of the articles in the issue.
+	Articles []string `toml:"articles"`
+}
+
+// Log is the list of issues, oldest first.
+type Log struct {
+	Issues []Issue `toml:"issue"`
+}
+
+// LoadLog reads the issue log from file. A missing file is an empty log.
+func LoadLog(file string) (*Log, error) {
+	l := &Log{}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(b), l); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return l, nil
+}
+
+// Save writes the log to file.
+func (l *Log) Save(file string) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by cmd/newsletter. Each issue covers the articles\n# published since the previous issue of the same series.\n\n")
+	if err := toml.NewEncoder(&buf).Encode(l); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// Lookup returns the issue with the given id.
+func (l *Log) Lookup(id string) (Issue, bool) {
+	for _, is := range l.Issues {
+		if is.ID == id {
+			return is, true
+		}
+	}
+	return Issue{}, false
+}
+
+// Last returns the date of the latest issue for series, or the zero time
+// if there is none.
+func (l *Log) Last(series string) time.Time {
+	var last time.Time
+	for _, is := range l.Issues {
+		if is.Series == series && is.Date.After(last) {
+			last = is.Date
+		}
+	}
+	return last
+}
+
+// Select returns the published articles dated after since and not after
+// now, in series if it is not "", oldest first.
+func Select(lib *content.Library, series string, since, now time.Time) []*content.Article {
+	var out []*content.Article
+	for _, a := range lib.Published() {
+		if !a.Date.After(since) || a.Date.After(now) {
+			continue
+		}
+		if series != "" && !inSeries(a, series) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+func inSeries(a *content.Article, series string) bool {
+	for _, s := range a.Series {
+		if content.Urlize(s) == content.Urlize(series) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewID returns the id of an issue of series built at date: the date,
+// after the series slug for series issues.
+func NewID(series string, date time.Time) string {
+	id := date.Format("2006-01-02")
+	if series != "" {
+		id = content.Urlize(series) + "-" + id
+	}
+	return id
+}
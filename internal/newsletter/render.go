@@ -0,0 +1,148 @@
+package newsletter
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+//go:embed templates
+var templates embed.FS
+
+var (
+	htmlTmpl = htmltemplate.Must(htmltemplate.ParseFS(templates, "templates/issue.html"))
+	textTmpl = texttemplate.Must(texttemplate.ParseFS(templates, "templates/issue.txt"))
+)
+
+// SummaryLength is the length, in bytes, of the article summaries.
+const SummaryLength = 300
+
+// Entry is an article as the digest shows it.
+type Entry struct {
+	Title   string
+	URL     string
+	Authors string
+	Date    string
+	Summary string
+	// Image is the absolute URL of the article's picture, or "".
+	Image string
+}
+
+// Digest is what the templates render.
+type Digest struct {
+	Issue   Issue
+	Site    string
+	Base    string
+	WebURL  string
+	Entries []Entry
+	// Unsubscribe is UnsubscribeURL, which stands for each recipient's
+	// own unsubscribe link until the message is sent.
+	Unsubscribe string
+}
+
+// UnsubscribeURL is the placeholder for the unsubscribe link in the
+// mailed bodies of an issue; see Recipient.
+const UnsubscribeURL = "https://unsubscribe.invalid/"
+
+// SubscribePath is where readers manage their subscriptions. The web
+// copy of an issue links there instead of to an unsubscribe link.
+const SubscribePath = "/subscribe/"
+
+// Bodies are the renderings of an issue: the two mailed bodies, and the
+// web copy.
+type Bodies struct {
+	HTML []byte
+	Text []byte
+	Web  []byte
+}
+
+// Render renders an issue of the articles, whose links point at the
+// site at base, e.g. "http://blog.gopheracademy.com".
+func Render(is Issue, articles []*content.Article, site, base string) (*Bodies, error) {
+	base = strings.TrimSuffix(base, "/")
+	d := Digest{Issue: is, Site: site, Base: base, WebURL: base + WebPath(is.ID), Unsubscribe: UnsubscribeURL}
+	for _, a := range articles {
+		e := Entry{
+			Title:   a.Title,
+			URL:     base + a.URL(),
+			Authors: strings.Join(a.Authors, ", "),
+			Date:    a.Date.Format("January 2, 2006"),
+			Summary: a.Summary(SummaryLength),
+		}
+		if img := a.Image(); img != "" {
+			e.Image = Absolute(img, base)
+		}
+		d.Entries = append(d.Entries, e)
+	}
+	var h, t bytes.Buffer
+	if err := htmlTmpl.Execute(&h, d); err != nil {
+		return nil, err
+	}
+	if err := textTmpl.Execute(&t, d); err != nil {
+		return nil, err
+	}
+	html := AbsoluteHTML(h.Bytes(), base)
+	web := bytes.ReplaceAll(html, []byte(UnsubscribeURL), []byte(base+SubscribePath))
+	return &Bodies{HTML: html, Text: t.Bytes(), Web: web}, nil
+}
+
+// WebPath is the path an issue is archived at on the site.
+func WebPath(id string) string {
+	return "/newsletter/" + id + "/"
+}
+
+// Absolute makes a site-relative URL, such as /postimages/a/b.png,
+// absolute. Other URLs are returned unchanged.
+func Absolute(u, base string) string {
+	if strings.HasPrefix(u, "/") && !strings.HasPrefix(u, "//") {
+		return strings.TrimSuffix(base, "/") + u
+	}
+	return u
+}
+
+var rootRelRE = regexp.MustCompile(`(\s(?:src|href)=")(/[^/"][^"]*|/)"`)
+
+// AbsoluteHTML rewrites the site-relative src and href attributes of an
+// HTML document, such as images under /postimages/, to absolute URLs, as
+// mail clients have no base to resolve them against.
+func AbsoluteHTML(b []byte, base string) []byte {
+	return rootRelRE.ReplaceAll(b, []byte(`${1}`+strings.TrimSuffix(base, "/")+`${2}"`))
+}
+
+// Archive writes an issue's bodies under root, for the site to publish:
+// the web copy as index.html, and the mailed bodies as issue.html and
+// issue.txt.
+func Archive(root, id string, b *Bodies) error {
+	dir := filepath.Join(root, filepath.FromSlash(path.Join(ArchiveDir, id)))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, data := range map[string][]byte{"index.html": b.Web, "issue.html": b.HTML, "issue.txt": b.Text} {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadArchive reads back the mailed bodies of an archived issue.
+func ReadArchive(root, id string) (*Bodies, error) {
+	dir := filepath.Join(root, filepath.FromSlash(path.Join(ArchiveDir, id)))
+	h, err := os.ReadFile(filepath.Join(dir, "issue.html"))
+	if err != nil {
+		return nil, err
+	}
+	t, err := os.ReadFile(filepath.Join(dir, "issue.txt"))
+	if err != nil {
+		return nil, err
+	}
+	return &Bodies{HTML: h, Text: t}, nil
+}
@@ -0,0 +1,356 @@
+package newsletter
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// StateFile is the name of the delivery state in the state directory.
+const StateFile = "state.json"
+
+// SoftLimit is the number of temporary failures in a row after which an
+// address is no longer mailed.
+const SoftLimit = 3
+
+// Bounce records the delivery failures of an address.
+type Bounce struct {
+	// Hard is set once the server has refused the address permanently.
+	Hard bool `json:"hard,omitempty"`
+	// Soft counts temporary failures since the last delivery.
+	Soft   int       `json:"soft,omitempty"`
+	Last   time.Time `json:"last"`
+	Reason string    `json:"reason"`
+}
+
+// Suppressed reports whether the address should no longer be mailed.
+func (b *Bounce) Suppressed() bool {
+	return b != nil && (b.Hard || b.Soft >= SoftLimit)
+}
+
+// State is the private delivery state: which addresses each issue went
+// to, and which addresses bounce.
+type State struct {
+	Dir     string                          `json:"-"`
+	Sent    map[string]map[string]time.Time `json:"sent"`
+	Bounces map[string]*Bounce              `json:"bounces"`
+}
+
+// OpenState reads the delivery state in dir, creating the directory if
+// needed.
+func OpenState(dir string) (*State, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &State{Dir: dir, Sent: make(map[string]map[string]time.Time), Bounces: make(map[string]*Bounce)}
+	b, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, s); err != nil {
+		return nil, fmt.Errorf("%s: %v", StateFile, err)
+	}
+	if s.Sent == nil {
+		s.Sent = make(map[string]map[string]time.Time)
+	}
+	if s.Bounces == nil {
+		s.Bounces = make(map[string]*Bounce)
+	}
+	return s, nil
+}
+
+// Save writes the state, replacing the old one atomically.
+func (s *State) Save() error {
+	b, err := json.MarshalIndent(s, "", "\t")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(s.Dir, StateFile)
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Bounced returns the addresses that have bounced, sorted.
+func (s *State) Bounced() []string {
+	var out []string
+	for addr := range s.Bounces {
+		out = append(out, addr)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Forget removes every record of addr.
+func (s *State) Forget(addr string) {
+	for _, sent := range s.Sent {
+		for a := range sent {
+			if strings.EqualFold(a, addr) {
+				delete(sent, a)
+			}
+		}
+	}
+	for a := range s.Bounces {
+		if strings.EqualFold(a, addr) {
+			delete(s.Bounces, a)
+		}
+	}
+}
+
+func (s *State) sent(id, addr string, now time.Time) {
+	if s.Sent[id] == nil {
+		s.Sent[id] = make(map[string]time.Time)
+	}
+	s.Sent[id][addr] = now
+	delete(s.Bounces, addr)
+}
+
+func (s *State) bounce(addr string, err *textproto.Error, now time.Time) {
+	b := s.Bounces[addr]
+	if b == nil {
+		b = &Bounce{}
+		s.Bounces[addr] = b
+	}
+	if err.Code >= 500 {
+		b.Hard = true
+	} else {
+		b.Soft++
+	}
+	b.Last = now
+	b.Reason = fmt.Sprintf("%d %s", err.Code, err.Msg)
+}
+
+// Recipient is an address to mail an issue to.
+type Recipient struct {
+	Address string
+	// Unsubscribe is the recipient's own unsubscribe link, which takes
+	// the place of UnsubscribeURL in the bodies and is offered in the
+	// List-Unsubscribe header for one-click unsubscribing (RFC 8058).
+	// Without it the bodies link to Mailer.Unsubscribe.
+	Unsubscribe string
+}
+
+// Mailer delivers issues over SMTP.
+type Mailer struct {
+	// Addr is the SMTP server, host:port.
+	Addr string
+	// Auth, if set, is used when the server supports it, after STARTTLS
+	// if the server offers it.
+	Auth smtp.Auth
+	// From is the sender, e.g. "Gopher Academy <newsletter@gopheracademy.com>".
+	From string
+	// Batch is the number of messages sent over one connection, and
+	// Pause the time waited between batches, to stay under the relay's
+	// rate limits.
+	Batch int
+	Pause time.Duration
+	// Hostname is announced in HELO and used in Message-IDs.
+	Hostname string
+	// Header holds headers added to every message, such as List-Id.
+	Header map[string]string
+	// Unsubscribe is the link for recipients without their own, such as
+	// the site's subscription page.
+	Unsubscribe string
+}
+
+// Report counts the outcome of a delivery.
+type Report struct {
+	Sent, Skipped, Bounced int
+}
+
+// Deliver mails an issue to the recipients that have neither received it
+// nor been suppressed for bouncing, recording each outcome in s. It stops
+// at the first connection or server failure, returning it; deliveries
+// made so far are recorded, so the call can be repeated to resume.
+func (m *Mailer) Deliver(is Issue, b *Bodies, to []Recipient, s *State) (Report, error) {
+	var r Report
+	var pending []Recipient
+	for _, rc := range to {
+		if _, ok := s.Sent[is.ID][rc.Address]; ok || s.Bounces[rc.Address].Suppressed() {
+			r.Skipped++
+			continue
+		}
+		pending = append(pending, rc)
+	}
+	batch := m.Batch
+	if batch <= 0 {
+		batch = 50
+	}
+	for i := 0; i < len(pending); i += batch {
+		if i > 0 && m.Pause > 0 {
+			time.Sleep(m.Pause)
+		}
+		end := min(i+batch, len(pending))
+		err := m.send(is, b, pending[i:end], s, &r)
+		if serr := s.Save(); err == nil {
+			err = serr
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+// send mails one batch over a single connection.
+func (m *Mailer) send(is Issue, b *Bodies, batch []Recipient, s *State, r *Report) error {
+	c, err := smtp.Dial(m.Addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if m.Hostname != "" {
+		if err := c.Hello(m.Hostname); err != nil {
+			return err
+		}
+	}
+	host, _, _ := strings.Cut(m.Addr, ":")
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if m.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(m.Auth); err != nil {
+				return err
+			}
+		}
+	}
+	from, err := envelope(m.From)
+	if err != nil {
+		return err
+	}
+	for _, rc := range batch {
+		msg, err := m.Message(is, b, rc)
+		if err != nil {
+			return err
+		}
+		err = deliverOne(c, from, rc.Address, msg)
+		var te *textproto.Error
+		switch {
+		case err == nil:
+			s.sent(is.ID, rc.Address, time.Now())
+			r.Sent++
+		case errors.As(err, &te) && te.Code != 421:
+			// The server refused this message; note the bounce, reset
+			// the transaction and go on with the next address.
+			s.bounce(rc.Address, te, time.Now())
+			r.Bounced++
+			if err := c.Reset(); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+	return c.Quit()
+}
+
+func deliverOne(c *smtp.Client, from, to string, msg []byte) error {
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// envelope returns the bare address of a From header value.
+func envelope(from string) (string, error) {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">"), nil
+	}
+	if from == "" {
+		return "", errors.New("newsletter: no sender address")
+	}
+	return from, nil
+}
+
+// Message returns the multipart/alternative message mailing an issue
+// to a recipient.
+func (m *Mailer) Message(is Issue, b *Bodies, rc Recipient) ([]byte, error) {
+	unsubscribe := rc.Unsubscribe
+	if unsubscribe == "" {
+		unsubscribe = m.Unsubscribe
+	}
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct {
+		typ  string
+		data []byte
+	}{{"text/plain; charset=utf-8", b.Text}, {"text/html; charset=utf-8", b.HTML}} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.typ},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		qw.Write(bytes.ReplaceAll(part.data, []byte(UnsubscribeURL), []byte(unsubscribe)))
+		qw.Close()
+	}
+	mw.Close()
+
+	host := m.Hostname
+	if host == "" {
+		host = "localhost"
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	h := map[string]string{
+		"From":         m.From,
+		"To":           rc.Address,
+		"Subject":      mime.QEncoding.Encode("utf-8", is.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   fmt.Sprintf("<%s.%x@%s>", is.ID, id, host),
+		"MIME-Version": "1.0",
+		"Content-Type": "multipart/alternative; boundary=" + mw.Boundary(),
+	}
+	for k, v := range m.Header {
+		h[k] = v
+	}
+	if rc.Unsubscribe != "" {
+		h["List-Unsubscribe"] = "<" + rc.Unsubscribe + ">"
+		h["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var msg bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&msg, "%s: %s\r\n", k, h[k])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,181 @@
+package newsletter
+
+import (
+	"errors"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/smtpsink"
+)
+
+// connections returns the recipients of the messages accepted over each
+// connection made to sink.
+func connections(sink *smtpsink.Sink) [][]string {
+	conns := make([][]string, sink.Connections())
+	for _, m := range sink.Messages() {
+		conns[m.Conn] = append(conns[m.Conn], m.To...)
+	}
+	return conns
+}
+
+// count returns the number of messages sink accepted for addr.
+func count(sink *smtpsink.Sink, addr string) int {
+	n := 0
+	for _, m := range sink.Messages() {
+		for _, to := range m.To {
+			if to == addr {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func recipients(addrs ...string) []Recipient {
+	var out []Recipient
+	for _, a := range addrs {
+		out = append(out, Recipient{Address: a})
+	}
+	return out
+}
+
+func setup(t *testing.T) (*smtpsink.Sink, *Mailer, *State) {
+	sink, err := smtpsink.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sink.Close() })
+	m := &Mailer{Addr: sink.Addr(), From: "Gopher Academy <newsletter@example.com>", Hostname: "test", Unsubscribe: "https://example.com/subscribe/"}
+	s, err := OpenState(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sink, m, s
+}
+
+var (
+	issue  = Issue{ID: "2016-01-02", Date: time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC), Subject: "New on Gopher Academy"}
+	bodies = &Bodies{HTML: []byte("<p>Hi. <a href=\"" + UnsubscribeURL + "\">Unsubscribe</a></p>"), Text: []byte("Hi. Unsubscribe: " + UnsubscribeURL)}
+)
+
+func TestDeliverBatches(t *testing.T) {
+	sink, m, s := setup(t)
+	m.Batch = 2
+	to := recipients("a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com")
+	r, err := m.Deliver(issue, bodies, to, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Report{Sent: 5}) {
+		t.Errorf("report %+v, want 5 sent", r)
+	}
+	want := [][]string{{"a@example.com", "b@example.com"}, {"c@example.com", "d@example.com"}, {"e@example.com"}}
+	conns := connections(sink)
+	if len(conns) != len(want) {
+		t.Fatalf("%d connections, want %d: %v", len(conns), len(want), conns)
+	}
+	for i := range want {
+		if strings.Join(conns[i], " ") != strings.Join(want[i], " ") {
+			t.Errorf("connection %d delivered to %v, want %v", i, conns[i], want[i])
+		}
+	}
+
+	// Delivering again sends nothing.
+	r, err = m.Deliver(issue, bodies, to, s)
+	if err != nil || r != (Report{Skipped: 5}) {
+		t.Errorf("second delivery: %+v, %v; want all 5 skipped", r, err)
+	}
+}
+
+func TestBounces(t *testing.T) {
+	sink, m, s := setup(t)
+	sink.Reply("hard@example.com", "550 5.1.1 no such user")
+	sink.Reply("soft@example.com", "452 4.2.2 mailbox full", "452 4.2.2 mailbox full", "452 4.2.2 mailbox full")
+	to := recipients("hard@example.com", "soft@example.com", "ok@example.com")
+
+	r, err := m.Deliver(issue, bodies, to, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Report{Sent: 1, Bounced: 2}) {
+		t.Errorf("report %+v, want 1 sent and 2 bounced", r)
+	}
+	if b := s.Bounces["hard@example.com"]; b == nil || !b.Hard || !b.Suppressed() || b.Reason != "550 5.1.1 no such user" {
+		t.Errorf("5xx bounce recorded as %+v, want a hard bounce", b)
+	}
+	if b := s.Bounces["soft@example.com"]; b == nil || b.Hard || b.Soft != 1 || b.Suppressed() {
+		t.Errorf("4xx bounce recorded as %+v, want one soft bounce", b)
+	}
+
+	// The hard bounce is not tried again; the soft one is, until it has
+	// failed SoftLimit times in a row.
+	for i := 2; i <= SoftLimit; i++ {
+		r, err = m.Deliver(issue, bodies, to, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r != (Report{Skipped: 2, Bounced: 1}) {
+			t.Errorf("attempt %d: report %+v, want the soft bounce retried alone", i, r)
+		}
+	}
+	if b := s.Bounces["soft@example.com"]; b.Soft != SoftLimit || !b.Suppressed() {
+		t.Errorf("after %d soft bounces: %+v, want it suppressed", SoftLimit, b)
+	}
+	conns := len(connections(sink))
+	r, err = m.Deliver(issue, bodies, to, s)
+	if err != nil || r != (Report{Skipped: 3}) {
+		t.Errorf("after suppression: %+v, %v; want all 3 skipped", r, err)
+	}
+	if len(connections(sink)) != conns {
+		t.Error("connected with nothing to send")
+	}
+
+	// A delivery clears the soft bounces of an address.
+	s.Bounces["ok@example.com"] = &Bounce{Soft: SoftLimit - 1}
+	next := issue
+	next.ID = "2016-02-01"
+	if _, err := m.Deliver(next, bodies, recipients("ok@example.com"), s); err != nil {
+		t.Fatal(err)
+	}
+	if b := s.Bounces["ok@example.com"]; b != nil {
+		t.Errorf("bounce kept after delivery: %+v", b)
+	}
+}
+
+func TestResumeAfter421(t *testing.T) {
+	sink, m, s := setup(t)
+	sink.Reply("b@example.com", "421 4.7.0 try again later")
+	to := recipients("a@example.com", "b@example.com", "c@example.com")
+
+	r, err := m.Deliver(issue, bodies, to, s)
+	var te *textproto.Error
+	if !errors.As(err, &te) || te.Code != 421 {
+		t.Fatalf("Deliver = %v, want the 421", err)
+	}
+	if r != (Report{Sent: 1}) {
+		t.Errorf("report %+v, want 1 sent before the 421", r)
+	}
+	if s.Bounces["b@example.com"] != nil {
+		t.Error("421 recorded as a bounce of the address")
+	}
+
+	// The state is saved, so a new run resumes where this one stopped.
+	s, err = OpenState(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = m.Deliver(issue, bodies, to, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != (Report{Sent: 2, Skipped: 1}) {
+		t.Errorf("resumed report %+v, want 2 sent and 1 skipped", r)
+	}
+	for _, a := range []string{"a@example.com", "b@example.com", "c@example.com"} {
+		if n := count(sink, a); n != 1 {
+			t.Errorf("%s got %d messages, want 1", a, n)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+// Package notfound serves a real 404 for URLs that match no published
+// page, suggesting the articles the reader most likely meant.
+//
+// Suggestions are ranked by the edit distance between the requested slug
+// and each article slug, and by how many words of the request appear in
+// the article title. A request that is a single edit away from exactly
+// one article is redirected there permanently.
+package notfound
+
+import (
+	"html/template"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+)
+
+// Suggestion is an article offered on the 404 page.
+type Suggestion struct {
+	URL   string
+	Title string
+	// Distance is the edit distance between the requested slug and the
+	// article slug.
+	Distance int
+	Score    float64
+}
+
+type entry struct {
+	page   site.Page
+	slug   string
+	tokens map[string]bool
+}
+
+// Index ranks published articles against unknown URLs.
+type Index struct {
+	entries []entry
+}
+
+// NewIndex builds an index of the articles in m.
+func NewIndex(m *site.Manifest) *Index {
+	idx := &Index{}
+	for _, p := range m.Articles() {
+		tokens := make(map[string]bool)
+		for _, t := range words(p.Title) {
+			tokens[t] = true
+		}
+		for _, t := range words(p.Slug()) {
+			tokens[t] = true
+		}
+		idx.entries = append(idx.entries, entry{page: p, slug: strings.ToLower(p.Slug()), tokens: tokens})
+	}
+	return idx
+}
+
+// Suggest returns up to n articles ranked by similarity to the URL path
+// p, best first. Articles sharing nothing with the request are omitted.
+func (idx *Index) Suggest(p string, n int) []Suggestion {
+	slug := requestSlug(p)
+	if slug == "" {
+		return nil
+	}
+	query := words(slug)
+	var out []Suggestion
+	for _, e := range idx.entries {
+		d := distance(slug, e.slug)
+		closeness := 1 - float64(d)/float64(max(len(slug), len(e.slug)))
+		score := closeness + overlap(query, e.tokens)
+		if score <= 0.5 {
+			continue
+		}
+		out = append(out, Suggestion{URL: e.page.URL, Title: e.page.Title, Distance: d, Score: score})
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].URL < out[j].URL
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+// Redirect returns the URL of the only article within one edit of the
+// requested slug, if there is exactly one.
+func (idx *Index) Redirect(p string) (string, bool) {
+	slug := requestSlug(p)
+	if slug == "" {
+		return "", false
+	}
+	var target string
+	for _, e := range idx.entries {
+		if distance(slug, e.slug) > 1 {
+			continue
+		}
+		if target != "" {
+			return "", false
+		}
+		target = e.page.URL
+	}
+	return target, target != ""
+}
+
+// requestSlug reduces a request path to the slug it most likely names.
+func requestSlug(p string) string {
+	p = strings.TrimSuffix(path.Clean("/"+p), "/")
+	p = strings.TrimSuffix(p, "/index.html")
+	p = strings.TrimSuffix(p, ".html")
+	if p == "" {
+		return ""
+	}
+	return strings.ToLower(path.Base(p))
+}
+
+// words splits s into lower case words, dropping punctuation.
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// overlap returns the fraction of query words found in tokens.
+func overlap(query []string, tokens map[string]bool) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+	hits := 0
+	for _, q := range query {
+		if tokens[q] {
+			hits++
+		}
+	}
+	return float64(hits) / float64(len(query))
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// Handler serves files from root and answers requests for missing
+// files with a redirect or a 404 page listing suggestions.
+type Handler struct {
+	Root  http.FileSystem
+	Index *Index
+	// Site is the host name passed to the search box.
+	Site string
+	// Suggestions is the number of articles listed on the 404 page.
+	Suggestions int
+
+	files http.Handler
+}
+
+// NewHandler returns a Handler serving the site published in dir.
+func NewHandler(dir string, m *site.Manifest, host string) *Handler {
+	root := http.Dir(dir)
+	return &Handler{
+		Root:        root,
+		Index:       NewIndex(m),
+		Site:        host,
+		Suggestions: 5,
+		files:       http.FileServer(root),
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.exists(r.URL.Path) {
+		h.files.ServeHTTP(w, r)
+		return
+	}
+	if target, ok := h.Index.Redirect(r.URL.Path); ok {
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusNotFound)
+	page.Execute(w, struct {
+		Path        string
+		Site        string
+		Query       string
+		Suggestions []Suggestion
+	}{
+		Path:        r.URL.Path,
+		Site:        h.Site,
+		Query:       strings.Join(words(requestSlug(r.URL.Path)), " "),
+		Suggestions: h.Index.Suggest(r.URL.Path, h.Suggestions),
+	})
+}
+
+// exists reports whether p names a file, or a directory with an
+// index.html, under the root.
+func (h *Handler) exists(p string) bool {
+	p = path.Clean("/" + p)
+	f, err := h.Root.Open(p)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if !fi.IsDir() {
+		return true
+	}
+	idx, err := h.Root.Open(path.Join(p, "index.html"))
+	if err != nil {
+		return false
+	}
+	idx.Close()
+	return true
+}
+
+var page = template.Must(template.New("404").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Page not found</title>
+  <meta name="robots" content="noindex">
+  <link href="/css/bootstrap.min.css" rel="stylesheet">
+  <link href="/css/hc.css" rel="stylesheet">
+</head>
+<body>
+  <div class="container">
+    <div id="article-body">
+      <div class="article-title">Page not found</div>
+      <p class="meta">There is nothing at <code>{{.Path}}</code>.</p>
+      {{- if .Suggestions}}
+      <p>Perhaps you were looking for one of these articles:</p>
+      <ul class="posts">
+        {{- range .Suggestions}}
+        <li><a href="{{.URL}}">{{.Title}}</a></li>
+        {{- end}}
+      </ul>
+      {{- end}}
+      <form name="google-search" method="get" action="http://www.google.com/search">
+        <div class="post_search input-group">
+          <input type="hidden" name="sitesearch" value="{{.Site}}">
+          <input type="text" name="q" class="form-control" value="{{.Query}}">
+          <span class="input-group-btn">
+            <button class="btn btn-default" type="submit">Search</button>
+          </span>
+        </div>
+      </form>
+      <p><a href="/">Back to the home page</a></p>
+    </div>
+  </div>
+</body>
+</html>
+`))
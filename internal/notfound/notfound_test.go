@@ -0,0 +1,150 @@
+package notfound
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+)
+
+var manifest = &site.Manifest{Pages: []site.Page{
+	{URL: "/", Title: "Gopher Academy Blog"},
+	{URL: "/advent-2014/delve/", Title: "Debugging Go programs with Delve", Article: true},
+	{URL: "/advent-2014/goquery/", Title: "goquery: jQuery-like HTML parsing", Article: true},
+	{URL: "/advent-2014/parsers-lexers/", Title: "Handwritten Parsers & Lexers in Go", Article: true},
+	{URL: "/go-awesome/", Title: "Go is awesome", Article: true},
+	{URL: "/go-awesomer/", Title: "Go is even more awesome", Article: true},
+	{URL: "/moving-to-go/", Title: "Moving to Go", Article: true},
+	{URL: "/tags/go/", Title: "Go"},
+}}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"delve", "delve", 0},
+		{"delv", "delve", 1},
+		{"dleve", "delve", 2},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		// Runes, not bytes, are edited.
+		{"héllo", "hello", 1},
+	}
+	for _, tt := range tests {
+		if got := distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := distance(tt.b, tt.a); got != tt.want {
+			t.Errorf("distance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestRedirect(t *testing.T) {
+	idx := NewIndex(manifest)
+	tests := []struct {
+		path, want string
+	}{
+		{"/delv", "/advent-2014/delve/"},
+		{"/advent-2014/delvee/", "/advent-2014/delve/"},
+		{"/advent-2013/delve/index.html", "/advent-2014/delve/"},
+		{"/DELVE.html", "/advent-2014/delve/"},
+		{"/moving-to-goo/", "/moving-to-go/"},
+		// Within one edit of two articles: no redirect.
+		{"/go-awesomex", ""},
+		// Two edits away.
+		{"/dleve", ""},
+		{"/", ""},
+		{"/tags/go/page/9/", ""},
+		// Only articles are redirected to.
+		{"/tag", ""},
+	}
+	for _, tt := range tests {
+		got, ok := idx.Redirect(tt.path)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Redirect(%s) = %q, %v; want %q", tt.path, got, ok, tt.want)
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	idx := NewIndex(manifest)
+	tests := []struct {
+		path string
+		want []string
+	}{
+		// Words of the request found in the slug or title.
+		{"/parsers", []string{"/advent-2014/parsers-lexers/"}},
+		{"/lexers-and-parsers", []string{"/advent-2014/parsers-lexers/"}},
+		{"/jquery-html", []string{"/advent-2014/goquery/"}},
+		{"/debugging-with-delve/", []string{"/advent-2014/delve/"}},
+		// Edit distance ranks near misses of the slug.
+		{"/go-awesom", []string{"/go-awesome/", "/go-awesomer/", "/moving-to-go/"}},
+		{"/zzzzzz", nil},
+		{"/", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range idx.Suggest(tt.path, 3) {
+			got = append(got, s.URL)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Suggest(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+	if got := idx.Suggest("/go-awesom", 1); len(got) != 1 || got[0].Distance != 1 {
+		t.Errorf("Suggest(/go-awesom, 1) = %+v, want /go-awesome/ at distance 1", got)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"index.html", "moving-to-go/index.html", "css/hc.css"} {
+		file := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(f), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.MkdirAll(filepath.Join(dir, "empty"), 0755)
+	h := NewHandler(dir, manifest, "blog.gopheracademy.com")
+	tests := []struct {
+		path     string
+		status   int
+		location string
+		body     string
+	}{
+		{"/moving-to-go/", http.StatusOK, "", "moving-to-go/index.html"},
+		{"/css/hc.css", http.StatusOK, "", "css/hc.css"},
+		{"/moving-to-goo/", http.StatusMovedPermanently, "/moving-to-go/", ""},
+		{"/delv", http.StatusMovedPermanently, "/advent-2014/delve/", ""},
+		{"/go-awesomex", http.StatusNotFound, "", `<a href="/go-awesome/">Go is awesome</a>`},
+		{"/parsers", http.StatusNotFound, "", `<a href="/advent-2014/parsers-lexers/">Handwritten Parsers &amp; Lexers in Go</a>`},
+		// A directory without an index is not a page.
+		{"/empty/", http.StatusNotFound, "", `value="empty"`},
+		{"/<script>", http.StatusNotFound, "", "<code>/&lt;script&gt;</code>"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "http://blog.gopheracademy.com"+strings.ReplaceAll(tt.path, "<", "%3C"), nil))
+		if w.Code != tt.status {
+			t.Errorf("GET %s: status %d, want %d", tt.path, w.Code, tt.status)
+		}
+		if loc := w.Header().Get("Location"); loc != tt.location {
+			t.Errorf("GET %s: Location %q, want %q", tt.path, loc, tt.location)
+		}
+		if !strings.Contains(w.Body.String(), tt.body) {
+			t.Errorf("GET %s: body does not contain %s:\n%s", tt.path, tt.body, w.Body)
+		}
+	}
+}
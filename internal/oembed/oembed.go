@@ -0,0 +1,237 @@
+// Package oembed serves oEmbed (https://oembed.com) descriptions of the
+// site's articles, so that community sites and chat tools can show rich
+// previews of links to them.
+//
+// A consumer asks for /oembed?url=<permalink>, in JSON or XML, and gets
+// the article's title, authors and series, a thumbnail and a short HTML
+// summary. Pages advertise the endpoint with discovery <link> tags.
+package oembed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"html/template"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Path is the URL path of the endpoint.
+const Path = "/oembed"
+
+// Provider is the name the site is described by.
+const Provider = "Gopher Academy"
+
+// Response is an oEmbed response of type rich.
+type Response struct {
+	XMLName         xml.Name `json:"-" xml:"oembed"`
+	Version         string   `json:"version" xml:"version"`
+	Type            string   `json:"type" xml:"type"`
+	Title           string   `json:"title" xml:"title"`
+	AuthorName      string   `json:"author_name,omitempty" xml:"author_name,omitempty"`
+	AuthorURL       string   `json:"author_url,omitempty" xml:"author_url,omitempty"`
+	ProviderName    string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL     string   `json:"provider_url" xml:"provider_url"`
+	ThumbnailURL    string   `json:"thumbnail_url,omitempty" xml:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int      `json:"thumbnail_width,omitempty" xml:"thumbnail_width,omitempty"`
+	ThumbnailHeight int      `json:"thumbnail_height,omitempty" xml:"thumbnail_height,omitempty"`
+	HTML            string   `json:"html" xml:"html"`
+	Width           int      `json:"width" xml:"width"`
+	Height          int      `json:"height" xml:"height"`
+	// Series is not part of oEmbed; consumers that know it can show the
+	// series an article belongs to.
+	Series string `json:"series,omitempty" xml:"series,omitempty"`
+}
+
+// The size of the summary card, which shrinks to the consumer's maximum.
+const (
+	cardWidth  = 600
+	cardHeight = 200
+)
+
+// summaryLen is the length of the summary in the card, in bytes.
+const summaryLen = 280
+
+var card = template.Must(template.New("card").Parse(`<blockquote class="gopheracademy-embed">` +
+	`<p><a href="{{.URL}}">{{.Title}}</a></p>` +
+	`<p>{{.Summary}}</p>` +
+	`<p>&mdash; {{.Authors}}{{with .Series}}, {{.}}{{end}} on <a href="{{.Base}}">Gopher Academy</a></p>` +
+	`</blockquote>`))
+
+// Handler serves the endpoint.
+type Handler struct {
+	Library *content.Library
+	// Base is the site URL, e.g. "http://blog.gopheracademy.com". URLs
+	// on its host are described, with links to the article there.
+	Base string
+	// Aliases are the URLs of other sites showing the same articles,
+	// such as the main site. URLs on their hosts are described too.
+	Aliases []string
+	// Static is the directory images are served from, read for
+	// thumbnail sizes.
+	Static string
+}
+
+// NewHandler returns a handler describing the published articles of lib.
+func NewHandler(lib *content.Library, base string) *Handler {
+	return &Handler{
+		Library: lib,
+		Base:    strings.TrimSuffix(base, "/"),
+		Static:  filepath.Join(lib.Root, "static"),
+	}
+}
+
+// format returns the response format the request asks for, "json" or
+// "xml", from the format parameter or else the Accept header. It
+// returns "" for formats it cannot serve.
+func format(r *http.Request) string {
+	if f := r.FormValue("format"); f != "" {
+		if f == "json" || f == "xml" {
+			return f
+		}
+		return ""
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		t, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		switch t {
+		case "application/json", "application/json+oembed", "*/*", "application/*":
+			return "json"
+		case "text/xml", "application/xml", "text/xml+oembed":
+			return "xml"
+		}
+	}
+	return "json"
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f := format(r)
+	if f == "" {
+		http.Error(w, "format not supported", http.StatusNotImplemented)
+		return
+	}
+	a, ok := h.article(r.FormValue("url"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	maxWidth, _ := strconv.Atoi(r.FormValue("maxwidth"))
+	maxHeight, _ := strconv.Atoi(r.FormValue("maxheight"))
+	resp, err := h.describe(a, maxWidth, maxHeight)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Add("Vary", "Accept")
+	if f == "xml" {
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(resp)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// article returns the published article at rawurl, which must be on the
+// host of the site or one of its aliases.
+func (h *Handler) article(rawurl string) (*content.Article, bool) {
+	u, err := url.Parse(rawurl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, false
+	}
+	if !h.ownHost(u.Host) {
+		return nil, false
+	}
+	p := u.Path
+	if !strings.HasSuffix(p, "/") {
+		p += "/"
+	}
+	return h.Library.ByURL(p)
+}
+
+func (h *Handler) ownHost(host string) bool {
+	for _, site := range append([]string{h.Base}, h.Aliases...) {
+		if u, err := url.Parse(site); err == nil && strings.EqualFold(host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe builds the response for a, fitting the card within the
+// maximum size when one is given.
+func (h *Handler) describe(a *content.Article, maxWidth, maxHeight int) (*Response, error) {
+	resp := &Response{
+		Version:      "1.0",
+		Type:         "rich",
+		Title:        a.Title,
+		AuthorName:   strings.Join(a.Authors, ", "),
+		ProviderName: Provider,
+		ProviderURL:  h.Base + "/",
+		Width:        fit(cardWidth, maxWidth),
+		Height:       fit(cardHeight, maxHeight),
+	}
+	if len(a.Authors) == 1 {
+		resp.AuthorURL = h.Base + "/authors/" + content.Urlize(a.Authors[0]) + "/"
+	}
+	if len(a.Series) > 0 {
+		resp.Series = a.Series[0]
+	}
+	if img := a.Image(); img != "" {
+		resp.ThumbnailURL, resp.ThumbnailWidth, resp.ThumbnailHeight = h.thumbnail(img)
+	}
+	var buf strings.Builder
+	err := card.Execute(&buf, struct {
+		URL, Title, Summary, Authors, Series, Base string
+	}{h.Base + a.URL(), a.Title, a.Summary(summaryLen), strings.Join(a.Authors, " & "), resp.Series, h.Base + "/"})
+	if err != nil {
+		return nil, err
+	}
+	resp.HTML = buf.String()
+	return resp, nil
+}
+
+func fit(size, max int) int {
+	if max > 0 && max < size {
+		return max
+	}
+	return size
+}
+
+// thumbnail returns the absolute URL of an image and, for images served
+// by the site, its size.
+func (h *Handler) thumbnail(src string) (string, int, int) {
+	if strings.HasPrefix(src, "//") {
+		return "http:" + src, 0, 0
+	}
+	if strings.Contains(src, "://") {
+		return src, 0, 0
+	}
+	if !strings.HasPrefix(src, "/") {
+		return "", 0, 0
+	}
+	f, err := os.Open(filepath.Join(h.Static, filepath.FromSlash(src)))
+	if err != nil {
+		return h.Base + src, 0, 0
+	}
+	defer f.Close()
+	c, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return h.Base + src, 0, 0
+	}
+	return h.Base + src, c.Width, c.Height
+}
@@ -0,0 +1,149 @@
+package oembed
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+func newHandler(t *testing.T) *Handler {
+	root := t.TempDir()
+	files := map[string]string{
+		"content/advent-2014/delve.md": "+++\ntitle = \"Delve: Go debugger\"\nauthor = [\"Derek Parker\"]\ndate = \"2014-12-03T08:00:00Z\"\nseries = [\"Advent 2014\"]\n+++\n\nDelve is a Go debugger.\n",
+		"content/draft.md":             "+++\ntitle = \"Not yet\"\ndraft = true\n+++\n\nSoon.\n",
+	}
+	for name, s := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lib, err := content.Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewHandler(lib, "http://blog.gopheracademy.com/")
+	h.Aliases = []string{"http://www.gopheracademy.com"}
+	return h
+}
+
+func get(h http.Handler, query, accept string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", Path+"?"+query, nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestUnknownURLs(t *testing.T) {
+	h := newHandler(t)
+	for _, u := range []string{
+		"",
+		"http://blog.gopheracademy.com/advent-2014/nope/",
+		"http://blog.gopheracademy.com/draft/",
+		"http://example.com/advent-2014/delve/",
+		"ftp://blog.gopheracademy.com/advent-2014/delve/",
+		"blog.gopheracademy.com/advent-2014/delve/",
+	} {
+		if w := get(h, "url="+url.QueryEscape(u), ""); w.Code != http.StatusNotFound {
+			t.Errorf("%q: status %d, want 404", u, w.Code)
+		}
+	}
+}
+
+func TestSiteHosts(t *testing.T) {
+	h := newHandler(t)
+	for _, u := range []string{
+		"http://blog.gopheracademy.com/advent-2014/delve/",
+		"https://blog.gopheracademy.com/advent-2014/delve",
+		"http://www.gopheracademy.com/advent-2014/delve/",
+	} {
+		w := get(h, "url="+url.QueryEscape(u), "")
+		if w.Code != http.StatusOK {
+			t.Errorf("%q: status %d, want 200", u, w.Code)
+			continue
+		}
+		var resp Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Title != "Delve: Go debugger" || resp.Series != "Advent 2014" || resp.AuthorName != "Derek Parker" {
+			t.Errorf("%q: got %+v", u, resp)
+		}
+		if !strings.Contains(resp.HTML, `href="http://blog.gopheracademy.com/advent-2014/delve/"`) {
+			t.Errorf("%q: card does not link to the blog: %s", u, resp.HTML)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	h := newHandler(t)
+	q := "url=" + url.QueryEscape("http://blog.gopheracademy.com/advent-2014/delve/")
+	tests := []struct {
+		query, accept string
+		status        int
+		format        string
+	}{
+		{q, "", http.StatusOK, "json"},
+		{q + "&format=json", "text/xml", http.StatusOK, "json"},
+		{q + "&format=xml", "", http.StatusOK, "xml"},
+		{q + "&format=yaml", "", http.StatusNotImplemented, ""},
+		{q, "text/xml", http.StatusOK, "xml"},
+		{q, "application/xml;q=0.9, */*;q=0.8", http.StatusOK, "xml"},
+		{q, "text/html, application/json", http.StatusOK, "json"},
+		{q, "*/*", http.StatusOK, "json"},
+		{q, "image/png", http.StatusOK, "json"},
+	}
+	for _, tt := range tests {
+		w := get(h, tt.query, tt.accept)
+		if w.Code != tt.status {
+			t.Errorf("%s, Accept %q: status %d, want %d", tt.query, tt.accept, w.Code, tt.status)
+			continue
+		}
+		var resp Response
+		switch tt.format {
+		case "json":
+			if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("%s, Accept %q: Content-Type %q, want JSON", tt.query, tt.accept, ct)
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Errorf("%s, Accept %q: %v", tt.query, tt.accept, err)
+			}
+		case "xml":
+			if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/xml") {
+				t.Errorf("%s, Accept %q: Content-Type %q, want XML", tt.query, tt.accept, ct)
+			}
+			if err := xml.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Errorf("%s, Accept %q: %v", tt.query, tt.accept, err)
+			}
+		}
+		if tt.format != "" && resp.Version != "1.0" {
+			t.Errorf("%s, Accept %q: version %q", tt.query, tt.accept, resp.Version)
+		}
+	}
+}
+
+func TestMaxSize(t *testing.T) {
+	h := newHandler(t)
+	q := "url=" + url.QueryEscape("http://blog.gopheracademy.com/advent-2014/delve/")
+	var resp Response
+	if err := json.Unmarshal(get(h, q+"&maxwidth=320&maxheight=1000", "").Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Width != 320 || resp.Height != cardHeight {
+		t.Errorf("size %dx%d, want 320x%d", resp.Width, resp.Height, cardHeight)
+	}
+}
@@ -0,0 +1,245 @@
+// Package reflinks checks the reference-style links of article bodies:
+//
+//	[GOPATH][code], [Godep][] and [pike]
+//
+//	[code]: https://golang.org/doc/code.html
+//
+// A reference whose label has no definition renders as literal brackets,
+// as does one written inside a code span, and neither is easy to spot in
+// review. The checker reports references to undefined labels,
+// definitions no reference uses, labels defined twice with different
+// targets (markdown uses the first), and references inside code spans.
+//
+// A shortcut reference, [label] on its own, is only a link when the label
+// is defined; otherwise it is ordinary bracketed text, such as [sic], so
+// undefined shortcut references are not reported. Footnotes, [^1], are
+// not reference links and are skipped.
+package reflinks
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Problem is a reference link that does not render as intended.
+type Problem struct {
+	File string
+	Line int
+	// Rule is the kind of problem: undefined, unused, duplicate or code.
+	Rule    string
+	Message string
+
+	off int
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Rule, p.Message)
+}
+
+// Definition is a link reference definition.
+type Definition struct {
+	Label  string
+	Target string
+	off    int
+}
+
+// Reference is a use of a label. Explicit references are the full,
+// [text][label], and collapsed, [label][], forms.
+type Reference struct {
+	Label    string
+	Explicit bool
+	off      int
+}
+
+var (
+	defRE      = regexp.MustCompile(`(?m)^ {0,3}\[((?:[^\]\\\n]|\\.)+)\]:`)
+	spanRefRE  = regexp.MustCompile(`\[([^\[\]\n]+)\]\[([^\[\]\n]*)\]`)
+	spanWordRE = regexp.MustCompile(`\[([^\[\]\n]+)\]`)
+)
+
+// Normalize returns the form labels are matched in: case-folded, with
+// runs of whitespace collapsed.
+func Normalize(label string) string {
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// Parse returns the definitions and references of a markdown body,
+// outside code.
+func Parse(body []byte) ([]Definition, []Reference) {
+	masked := content.MaskCode(body)
+	var defs []Definition
+	isDef := make(map[int]bool)
+	for _, m := range defRE.FindAllSubmatchIndex(masked, -1) {
+		label := string(body[m[2]:m[3]])
+		isDef[m[2]-1] = true
+		if strings.HasPrefix(label, "^") {
+			continue
+		}
+		rest := body[m[1]:]
+		if end := bytes.IndexByte(rest, '\n'); end >= 0 && len(bytes.TrimSpace(rest[:end])) > 0 {
+			rest = rest[:end]
+		}
+		target := ""
+		if f := bytes.Fields(rest); len(f) > 0 {
+			target = strings.Trim(string(f[0]), "<>")
+		}
+		defs = append(defs, Definition{Label: label, Target: target, off: m[2] - 1})
+	}
+
+	var refs []Reference
+	skip := make(map[int]bool)
+	for i := 0; i < len(masked); i++ {
+		if masked[i] != '[' || skip[i] || isDef[i] || escaped(masked, i) {
+			continue
+		}
+		end := closeBracket(masked, i)
+		if end < 0 {
+			continue
+		}
+		text := string(body[i+1 : end])
+		next := end + 1
+		switch {
+		case strings.HasPrefix(text, "^"):
+		case next < len(masked) && masked[next] == '(':
+		case next < len(masked) && masked[next] == '[':
+			lend := closeBracket(masked, next)
+			if lend < 0 {
+				continue
+			}
+			skip[next] = true
+			label := string(body[next+1 : lend])
+			if strings.TrimSpace(label) == "" {
+				label = text
+			}
+			refs = append(refs, Reference{Label: label, Explicit: true, off: i})
+		default:
+			refs = append(refs, Reference{Label: text, off: i})
+		}
+	}
+	return defs, refs
+}
+
+// escaped reports whether the byte at i is escaped by a backslash.
+func escaped(b []byte, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && b[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// closeBracket returns the offset of the bracket closing the one at i,
+// within the same paragraph, or -1.
+func closeBracket(b []byte, i int) int {
+	depth := 0
+	for j := i; j < len(b); j++ {
+		switch b[j] {
+		case '[':
+			if !escaped(b, j) {
+				depth++
+			}
+		case ']':
+			if !escaped(b, j) {
+				depth--
+				if depth == 0 {
+					return j
+				}
+			}
+		case '\n':
+			if rest := b[j+1:]; len(bytes.TrimSpace(rest[:lineEnd(rest)])) == 0 {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func lineEnd(b []byte) int {
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return i
+	}
+	return len(b)
+}
+
+// Check returns the problems with the reference links of a markdown
+// body. file and line, the line the body starts on, position them.
+func Check(file string, line int, body []byte) []Problem {
+	defs, refs := Parse(body)
+	var out []Problem
+	add := func(off int, rule, format string, args ...any) {
+		out = append(out, Problem{Rule: rule, Message: fmt.Sprintf(format, args...), off: off})
+	}
+
+	first := make(map[string]Definition)
+	for _, d := range defs {
+		key := Normalize(d.Label)
+		if f, ok := first[key]; ok {
+			if f.Target != d.Target {
+				add(d.off, "duplicate", "[%s] is defined again as %s; the first definition, %s on line %d, wins",
+					d.Label, d.Target, f.Target, line+bytes.Count(body[:f.off], []byte("\n")))
+			}
+			continue
+		}
+		first[key] = d
+	}
+
+	used := make(map[string]bool)
+	for _, r := range refs {
+		key := Normalize(r.Label)
+		if _, ok := first[key]; ok {
+			used[key] = true
+		} else if r.Explicit {
+			add(r.off, "undefined", "[%s] has no definition and renders as literal brackets", r.Label)
+		}
+	}
+
+	for _, sp := range content.CodeSpans(body) {
+		code := body[sp[0]:sp[1]]
+		found := false
+		for _, m := range spanRefRE.FindAllSubmatchIndex(code, -1) {
+			label := string(code[m[4]:m[5]])
+			if label == "" {
+				label = string(code[m[2]:m[3]])
+			}
+			if _, ok := first[Normalize(label)]; ok {
+				used[Normalize(label)] = true
+				add(sp[0], "code", "reference %s is inside a code span and renders literally", code[m[0]:m[1]])
+				found = true
+			}
+		}
+		if found {
+			continue
+		}
+		for _, m := range spanWordRE.FindAllSubmatchIndex(code, -1) {
+			label := Normalize(string(code[m[2]:m[3]]))
+			if _, ok := first[label]; ok && !used[label] {
+				used[label] = true
+				add(sp[0], "code", "reference %s is inside a code span and renders literally", code[m[0]:m[1]])
+			}
+		}
+	}
+
+	for _, d := range defs {
+		key := Normalize(d.Label)
+		if !used[key] && first[key].off == d.off {
+			add(d.off, "unused", "[%s] is defined but never referenced", d.Label)
+		}
+	}
+
+	sort.SliceStable(out, func(i, j int) bool { return out[i].off < out[j].off })
+	for i := range out {
+		out[i].File = file
+		out[i].Line = line + bytes.Count(body[:out[i].off], []byte("\n"))
+	}
+	return out
+}
+
+// CheckArticle checks the reference links of an article body.
+func CheckArticle(a *content.Article) []Problem {
+	return Check(a.File, a.Meta.BodyLine(), a.Body())
+}
@@ -0,0 +1,141 @@
+package reflinks
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := strings.Join([]string{
+		"Set [GOPATH][code] first, vendor with [Godep][] and ask [pike].",
+		"[Inline](https://golang.org/) links are not references, nor",
+		`is \[escaped] text or a footnote[^1].`,
+		"",
+		"```",
+		"[fenced][code]",
+		"```",
+		"",
+		"[code]: https://golang.org/doc/code.html",
+		"[Godep]: <https://github.com/tools/godep> \"Godep\"",
+		"[pike]:",
+		"  https://github.com/robpike",
+		"[^1]: A footnote.",
+	}, "\n")
+	defs, refs := Parse([]byte(body))
+
+	var gotDefs [][2]string
+	for _, d := range defs {
+		gotDefs = append(gotDefs, [2]string{d.Label, d.Target})
+	}
+	wantDefs := [][2]string{
+		{"code", "https://golang.org/doc/code.html"},
+		{"Godep", "https://github.com/tools/godep"},
+		{"pike", "https://github.com/robpike"},
+	}
+	if !reflect.DeepEqual(gotDefs, wantDefs) {
+		t.Errorf("definitions %q, want %q", gotDefs, wantDefs)
+	}
+
+	var gotRefs []Reference
+	for _, r := range refs {
+		gotRefs = append(gotRefs, Reference{Label: r.Label, Explicit: r.Explicit})
+	}
+	wantRefs := []Reference{
+		{Label: "code", Explicit: true},
+		{Label: "Godep", Explicit: true},
+		{Label: "pike"},
+	}
+	if !reflect.DeepEqual(gotRefs, wantRefs) {
+		t.Errorf("references %+v, want %+v", gotRefs, wantRefs)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for _, label := range []string{"Go  Blog", "go blog", " GO\nblog "} {
+		if got := Normalize(label); got != "go blog" {
+			t.Errorf("Normalize(%q) = %q, want %q", label, got, "go blog")
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name string
+		body []string
+		want []string // "line: rule"
+	}{
+		{
+			name: "full",
+			body: []string{"See [the docs][docs].", "", "[docs]: https://golang.org/doc/"},
+		},
+		{
+			name: "full undefined",
+			body: []string{"See [the docs][doc].", "", "[docs]: https://golang.org/doc/"},
+			want: []string{"10: undefined", "12: unused"},
+		},
+		{
+			name: "collapsed",
+			body: []string{"See [Docs][].", "", "[docs]: https://golang.org/doc/"},
+		},
+		{
+			name: "collapsed undefined",
+			body: []string{"See [docs][]."},
+			want: []string{"10: undefined"},
+		},
+		{
+			name: "shortcut",
+			body: []string{"See [Go   docs].", "", "[go docs]: https://golang.org/doc/"},
+		},
+		{
+			name: "shortcut undefined is text",
+			body: []string{"It is [sic] and x[1] indexes a slice."},
+		},
+		{
+			name: "escaped brackets",
+			body: []string{`Type \[docs\] literally.`, "", "[docs]: https://golang.org/doc/"},
+			want: []string{"12: unused"},
+		},
+		{
+			name: "footnotes",
+			body: []string{"A claim.[^1]", "", "[^1]: Source."},
+		},
+		{
+			name: "duplicate same target",
+			body: []string{"[docs][]", "", "[docs]: https://golang.org/doc/", "[Docs]: https://golang.org/doc/"},
+		},
+		{
+			name: "duplicate different target",
+			body: []string{"[docs][]", "", "[docs]: https://golang.org/doc/", "[Docs]: https://golang.org/pkg/"},
+			want: []string{"13: duplicate"},
+		},
+		{
+			name: "code span",
+			body: []string{"Write `[docs][]` or `[spec]` for a link.", "", "[docs]: https://golang.org/doc/", "[spec]: https://golang.org/ref/spec"},
+			want: []string{"10: code", "10: code"},
+		},
+		{
+			name: "code span without definition",
+			body: []string{"Index with `a[i][j]`."},
+		},
+		{
+			name: "fenced code",
+			body: []string{"```go", "x := m[key][0]", "```", "", "[key]: https://golang.org/doc/"},
+			want: []string{"14: unused"},
+		},
+	}
+	for _, tt := range tests {
+		body := strings.Join(tt.body, "\n") + "\n"
+		var got []string
+		for _, p := range Check("post.md", 10, []byte(body)) {
+			if p.File != "post.md" {
+				t.Errorf("%s: problem in %s", tt.name, p.File)
+			}
+			got = append(got, fmt.Sprintf("%d: %s", p.Line, p.Rule))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
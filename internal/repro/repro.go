@@ -0,0 +1,235 @@
+// Package repro compares two builds of the same commit and explains why
+// they differ. A reproducible build lets deploys upload only the files a
+// change actually touched.
+package repro
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/deploy"
+)
+
+// Kind classifies a difference between two builds.
+type Kind string
+
+const (
+	// Missing is a file produced by only one of the builds.
+	Missing Kind = "missing"
+	// Timestamp is a difference confined to dates, times or years,
+	// typically from .Now or a file modification time.
+	Timestamp Kind = "timestamp"
+	// Ordering is the same set of lines in a different order, typically
+	// from ranging over a map or an unstable sort of equal keys.
+	Ordering Kind = "ordering"
+	// FinalNewline is a difference only in the newlines at the end of
+	// the file, typically from a template that trims or adds one.
+	FinalNewline Kind = "final newline"
+	// Whitespace is a difference only in spaces, tabs, line endings or
+	// blank lines, typically from a template action not trimmed the same
+	// way in both builds.
+	Whitespace Kind = "whitespace"
+	// Content is any other difference.
+	Content Kind = "content"
+)
+
+// Difference is one file that is not byte for byte identical.
+type Difference struct {
+	Path string
+	Kind Kind
+	// Line is the first differing line, counting from 1, when known.
+	Line int
+	// A and B are the first differing lines of each build.
+	A, B string
+}
+
+func (d Difference) String() string {
+	switch d.Kind {
+	case Missing:
+		if d.A == "" {
+			return fmt.Sprintf("%s: only in the second build", d.Path)
+		}
+		return fmt.Sprintf("%s: only in the first build", d.Path)
+	case Timestamp:
+		return fmt.Sprintf("%s:%d: build time leaks into the output (.Now or a file time):\n\t- %s\n\t+ %s", d.Path, d.Line, d.A, d.B)
+	case Ordering:
+		return fmt.Sprintf("%s:%d: same lines in a different order (map iteration or unstable sort):\n\t- %s\n\t+ %s", d.Path, d.Line, d.A, d.B)
+	case FinalNewline:
+		return fmt.Sprintf("%s:%d: only the newlines at the end of the file differ:\n\t- %s\n\t+ %s", d.Path, d.Line, d.A, d.B)
+	case Whitespace:
+		return fmt.Sprintf("%s:%d: only whitespace differs:\n\t- %s\n\t+ %s", d.Path, d.Line, d.A, d.B)
+	}
+	return fmt.Sprintf("%s:%d: content differs:\n\t- %s\n\t+ %s", d.Path, d.Line, d.A, d.B)
+}
+
+// Compare returns the differences between the trees a and b, sorted by
+// path.
+func Compare(a, b string) ([]Difference, error) {
+	ma, err := deploy.Scan(a)
+	if err != nil {
+		return nil, err
+	}
+	mb, err := deploy.Scan(b)
+	if err != nil {
+		return nil, err
+	}
+	c := deploy.Diff(ma, mb)
+	var diffs []Difference
+	for _, p := range c.Added {
+		diffs = append(diffs, Difference{Path: p, Kind: Missing, B: p})
+	}
+	for _, p := range c.Removed {
+		diffs = append(diffs, Difference{Path: p, Kind: Missing, A: p})
+	}
+	for _, p := range c.Changed {
+		fa, err := os.ReadFile(filepath.Join(a, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		fb, err := os.ReadFile(filepath.Join(b, filepath.FromSlash(p)))
+		if err != nil {
+			return nil, err
+		}
+		d := Explain(fa, fb)
+		d.Path = p
+		diffs = append(diffs, d)
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs, nil
+}
+
+// timeRE matches the parts of a line that are dates or times.
+var timeRE = regexp.MustCompile(`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2}(\.\d+)?)?(Z|[+-]\d{2}:?\d{2})?)?|\d{2}:\d{2}:\d{2}|\b(19|20)\d{2}\b`)
+
+// Explain classifies the difference between two versions of a file.
+// The A and B of FinalNewline and Whitespace differences are quoted, so
+// that the whitespace shows.
+func Explain(a, b []byte) Difference {
+	if ta, tb := bytes.TrimRight(a, "\r\n"), bytes.TrimRight(b, "\r\n"); bytes.Equal(ta, tb) {
+		return Difference{
+			Kind: FinalNewline,
+			Line: bytes.Count(ta, []byte("\n")) + 1,
+			A:    strconv.Quote(tail(a)),
+			B:    strconv.Quote(tail(b)),
+		}
+	}
+	la, lb := lines(a), lines(b)
+	d := Difference{Kind: Content}
+	d.Line, d.A, d.B = firstDiff(la, lb)
+	switch {
+	case d.Line == 0:
+		// The lines are the same but the bytes are not: the files differ
+		// in their line endings, which lines drops.
+		d.Line, d.A, d.B = firstDiff(strings.SplitAfter(string(a), "\n"), strings.SplitAfter(string(b), "\n"))
+		d.Kind = Whitespace
+	case sameLines(la, lb):
+		d.Kind = Ordering
+	case sameWords(la, lb):
+		d.Kind = Whitespace
+	case len(la) == len(lb) && onlyTimes(la, lb):
+		d.Kind = Timestamp
+	}
+	if d.Kind == Whitespace {
+		d.A, d.B = strconv.Quote(d.A), strconv.Quote(d.B)
+	}
+	return d
+}
+
+// tail returns the last line of b that is not empty and the newlines
+// after it.
+func tail(b []byte) string {
+	i := bytes.LastIndexByte(bytes.TrimRight(b, "\r\n"), '\n')
+	return string(b[i+1:])
+}
+
+// firstDiff returns the first line, counting from 1, at which a and b
+// differ and the lines there, or 0 if they are the same. A line missing
+// from the shorter slice is empty.
+func firstDiff(a, b []string) (int, string, string) {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y string
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y || i >= len(a) || i >= len(b) {
+			return i + 1, x, y
+		}
+	}
+	return 0, "", ""
+}
+
+func lines(b []byte) []string {
+	var out []string
+	s := bufio.NewScanner(bytes.NewReader(b))
+	s.Buffer(nil, 1<<24)
+	for s.Scan() {
+		out = append(out, s.Text())
+	}
+	return out
+}
+
+// sameLines reports whether a and b hold the same lines, ignoring order.
+func sameLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int)
+	for _, l := range a {
+		count[l]++
+	}
+	for _, l := range b {
+		count[l]--
+		if count[l] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// sameWords reports whether a and b are the same once runs of spaces
+// and tabs are collapsed and blank lines are dropped.
+func sameWords(a, b []string) bool {
+	words := func(lines []string) []string {
+		var out []string
+		for _, l := range lines {
+			if f := strings.Fields(l); len(f) > 0 {
+				out = append(out, strings.Join(f, " "))
+			}
+		}
+		return out
+	}
+	wa, wb := words(a), words(b)
+	if len(wa) != len(wb) {
+		return false
+	}
+	for i := range wa {
+		if wa[i] != wb[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// onlyTimes reports whether every differing line pair becomes equal once
+// dates and times are masked.
+func onlyTimes(a, b []string) bool {
+	for i := range a {
+		if a[i] == b[i] {
+			continue
+		}
+		if timeRE.ReplaceAllString(a[i], "#") != timeRE.ReplaceAllString(b[i], "#") {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,27 @@
+package repro
+
+import "testing"
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		kind Kind
+		line int
+	}{
+		{"final newline", "<p>a</p>\n<p>b</p>\n", "<p>a</p>\n<p>b</p>", FinalNewline, 2},
+		{"extra final newline", "<p>a</p>\n", "<p>a</p>\n\n", FinalNewline, 1},
+		{"line endings", "<p>a</p>\n<p>b</p>\n", "<p>a</p>\r\n<p>b</p>\r\n", Whitespace, 1},
+		{"indentation", "<ul>\n  <li>a</li>\n</ul>\n", "<ul>\n<li>a</li>\n</ul>\n", Whitespace, 2},
+		{"blank line", "<ul>\n<li>a</li>\n</ul>\n", "<ul>\n\n<li>a</li>\n</ul>\n", Whitespace, 2},
+		{"ordering", "<li>a</li>\n<li>b</li>\n", "<li>b</li>\n<li>a</li>\n", Ordering, 1},
+		{"timestamp", "<p>x</p>\n<time>2016-01-02T15:04:05Z</time>\n", "<p>x</p>\n<time>2016-01-03T09:00:00Z</time>\n", Timestamp, 2},
+		{"content", "<p>a</p>\n", "<p>b</p>\n", Content, 1},
+	}
+	for _, tt := range tests {
+		d := Explain([]byte(tt.a), []byte(tt.b))
+		if d.Kind != tt.kind || d.Line != tt.line {
+			t.Errorf("%s: Explain = %s at line %d, want %s at line %d", tt.name, d.Kind, d.Line, tt.kind, tt.line)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package scholarship takes applications for the GopherCon scholarship
+// and lets reviewers score them.
+//
+// Applicants fill in a form with who they are, the conference they
+// want to attend and an essay, and may attach a few files. Reviewers
+// score each application against the rubric in data/scholarship.toml
+// without seeing the applicant's name or address: the review pages show
+// an application by its label, "Applicant 3f2a9c", and serve the
+// attachments under neutral names. The organizers then rank the
+// applications by their reviews, record a decision for each and mail
+// the decisions from the templates in the same file (see cmd/scholarship).
+//
+// Applications are personal data and are kept outside the repository,
+// one directory per application, so that the server and
+// cmd/scholarship can both write to them.
+package scholarship
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigFile is the program's configuration, relative to the repository
+// root.
+const ConfigFile = "data/scholarship.toml"
+
+// Decision outcomes.
+const (
+	Accepted   = "accepted"
+	Waitlisted = "waitlisted"
+	Declined   = "declined"
+)
+
+// Outcomes lists the decision outcomes.
+var Outcomes = []string{Accepted, Waitlisted, Declined}
+
+// Config describes the current round of the program.
+type Config struct {
+	Name    string `toml:"name"`
+	Contact string `toml:"contact"`
+	// Deadline is when the form closes.
+	Deadline time.Time `toml:"deadline"`
+	// Conferences are the events applicants can choose.
+	Conferences []string `toml:"conferences"`
+	// EssayWords is the longest essay accepted, in words.
+	EssayWords int `toml:"essay_words"`
+	// Rubric is what reviewers score.
+	Rubric []Criterion `toml:"criterion"`
+	// Mail holds the templates of the mails to applicants: "received"
+	// and one per outcome.
+	Mail map[string]MailTemplate `toml:"mail"`
+}
+
+// Criterion is one line of the rubric, scored from 0 to Max.
+type Criterion struct {
+	Key         string `toml:"key"`
+	Description string `toml:"description"`
+	Max         int    `toml:"max"`
+	// Weight is the criterion's share of the total; 0 counts as 1.
+	Weight float64 `toml:"weight"`
+}
+
+func (c Criterion) weight() float64 {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// MailTemplate is a mail to applicants. Subject and Body are
+// text/template templates executed with a Letter.
+type MailTemplate struct {
+	Subject string `toml:"subject"`
+	Body    string `toml:"body"`
+}
+
+// Letter is what the mail templates are executed with.
+type Letter struct {
+	Program    string
+	Contact    string
+	Name       string
+	Conference string
+	Outcome    string
+}
+
+// LoadConfig reads and checks the configuration in file.
+func LoadConfig(file string) (*Config, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{}
+	if _, err := toml.Decode(string(b), c); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if err := c.check(); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return c, nil
+}
+
+func (c *Config) check() error {
+	if len(c.Conferences) == 0 {
+		return fmt.Errorf("no conferences")
+	}
+	if len(c.Rubric) == 0 {
+		return fmt.Errorf("no criteria")
+	}
+	seen := make(map[string]bool)
+	for _, cr := range c.Rubric {
+		if cr.Key == "" || cr.Max <= 0 || cr.Weight < 0 {
+			return fmt.Errorf("criterion %q needs a key, a positive max and a weight of at least 0", cr.Key)
+		}
+		if seen[cr.Key] {
+			return fmt.Errorf("criterion %q appears twice", cr.Key)
+		}
+		seen[cr.Key] = true
+	}
+	for _, name := range append([]string{"received"}, Outcomes...) {
+		m, ok := c.Mail[name]
+		if !ok {
+			return fmt.Errorf("no %s mail", name)
+		}
+		if _, _, err := m.render(Letter{}); err != nil {
+			return fmt.Errorf("%s mail: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// Open reports whether the form accepts applications at now.
+func (c *Config) Open(now time.Time) bool {
+	return c.Deadline.IsZero() || now.Before(c.Deadline)
+}
+
+// Letter returns the subject and body of the named mail to the
+// applicant of a.
+func (c *Config) Letter(name string, a Application, outcome string) (subject string, body []byte, err error) {
+	m, ok := c.Mail[name]
+	if !ok {
+		return "", nil, fmt.Errorf("no %s mail", name)
+	}
+	return m.render(Letter{
+		Program:    c.Name,
+		Contact:    c.Contact,
+		Name:       a.Name,
+		Conference: a.Conference,
+		Outcome:    outcome,
+	})
+}
+
+func (m MailTemplate) render(l Letter) (string, []byte, error) {
+	var subject, body bytes.Buffer
+	for _, t := range []struct {
+		text string
+		out  *bytes.Buffer
+	}{{m.Subject, &subject}, {m.Body, &body}} {
+		tmpl, err := template.New("").Option("missingkey=error").Parse(t.text)
+		if err != nil {
+			return "", nil, err
+		}
+		if err := tmpl.Execute(t.out, l); err != nil {
+			return "", nil, err
+		}
+	}
+	return subject.String(), body.Bytes(), nil
+}
@@ -0,0 +1,518 @@
+package scholarship
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+// Prefix is the URL path the handler is served under.
+const Prefix = "/scholarship/"
+
+// Limits on attachments.
+const (
+	MaxFiles    = 3
+	MaxFileSize = 2 << 20
+)
+
+// fileTypes maps the extensions attachments may have to their content
+// types.
+var fileTypes = map[string]string{
+	".pdf": "application/pdf",
+	".txt": "text/plain; charset=utf-8",
+	".md":  "text/plain; charset=utf-8",
+}
+
+// Handler serves the application form at Prefix and the reviewers'
+// pages at Prefix+"review/".
+type Handler struct {
+	Store  *Store
+	Config *Config
+	// Mailer sends the receipt of an application.
+	Mailer subscribe.Mailer
+	// Reviewers maps each reviewer's name to the hash of their
+	// password, as adminauth.Hash stores it, for HTTP basic
+	// authentication.
+	Reviewers map[string]string
+
+	key []byte
+}
+
+// NewHandler returns a Handler for the round described by config.
+func NewHandler(store *Store, config *Config, mailer subscribe.Mailer, reviewers map[string]string) *Handler {
+	h := &Handler{Store: store, Config: config, Mailer: mailer, Reviewers: reviewers}
+	h.key = make([]byte, 32)
+	rand.Read(h.key)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	switch path := r.URL.Path; {
+	case path == Prefix:
+		if r.Method == "POST" {
+			h.apply(w, r)
+			return
+		}
+		h.form(w, http.StatusOK, "", nil)
+	case path == Prefix+"review/":
+		if reviewer := h.authenticate(w, r); reviewer != "" {
+			h.list(w, reviewer)
+		}
+	case strings.HasPrefix(path, Prefix+"review/"):
+		reviewer := h.authenticate(w, r)
+		if reviewer == "" {
+			return
+		}
+		id, n, _ := strings.Cut(strings.TrimPrefix(path, Prefix+"review/"), "/")
+		a, err := h.Store.Get(id)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				log.Printf("scholarship: %v", err)
+			}
+			http.NotFound(w, r)
+			return
+		}
+		if n != "" {
+			h.attachment(w, r, a, n)
+			return
+		}
+		h.review(w, r, reviewer, a)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) form(w http.ResponseWriter, status int, problem string, values map[string]string) {
+	if !h.Config.Open(time.Now()) {
+		h.message(w, http.StatusOK, h.Config.Name, "Applications for this round are closed. Thank you for your interest.")
+		return
+	}
+	h.render(w, status, "form", map[string]any{
+		"Title":    h.Config.Name,
+		"Config":   h.Config,
+		"Problem":  problem,
+		"Values":   values,
+		"MaxFiles": MaxFiles,
+		"MaxSize":  MaxFileSize >> 20,
+	})
+}
+
+func (h *Handler) message(w http.ResponseWriter, status int, title, msg string) {
+	h.render(w, status, "message", map[string]any{"Title": title, "Message": msg})
+}
+
+func (h *Handler) apply(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	if !h.Config.Open(now) {
+		h.form(w, http.StatusOK, "", nil)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, MaxFiles*MaxFileSize+1<<20)
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		h.form(w, http.StatusBadRequest, fmt.Sprintf("The form could not be read. Attachments may be at most %d MB each.", MaxFileSize>>20), nil)
+		return
+	}
+	values := map[string]string{}
+	for _, k := range []string{"name", "email", "country", "conference", "essay"} {
+		values[k] = strings.TrimSpace(r.FormValue(k))
+	}
+	problem := func(msg string) { h.form(w, http.StatusBadRequest, msg, values) }
+	addr, err := mail.ParseAddress(values["email"])
+	switch {
+	case values["name"] == "" || err != nil:
+		problem("Please enter your name and a valid email address.")
+		return
+	case !slices.Contains(h.Config.Conferences, values["conference"]):
+		problem("Please choose a conference.")
+		return
+	case values["essay"] == "":
+		problem("Please write your essay.")
+		return
+	case h.Config.EssayWords > 0 && len(strings.Fields(values["essay"])) > h.Config.EssayWords:
+		problem(fmt.Sprintf("Your essay has %d words; please keep it to %d.", len(strings.Fields(values["essay"])), h.Config.EssayWords))
+		return
+	}
+	files, msg := uploads(r)
+	if msg != "" {
+		problem(msg)
+		return
+	}
+	// The hidden field is left empty by people and filled in by bots.
+	if r.FormValue("website") != "" {
+		h.message(w, http.StatusOK, "Application received", "Thank you for applying.")
+		return
+	}
+	a := &Application{
+		Name:       values["name"],
+		Email:      addr.Address,
+		Country:    values["country"],
+		Conference: values["conference"],
+		Essay:      values["essay"],
+	}
+	switch err := h.Store.Submit(a, files, now); {
+	case errors.Is(err, ErrApplied):
+		h.message(w, http.StatusOK, "Already applied", "We already have an application from "+addr.Address+". To change it, write to "+h.Config.Contact+".")
+		return
+	case err != nil:
+		log.Printf("scholarship: %v", err)
+		http.Error(w, "Your application could not be saved; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	subject, body, err := h.Config.Letter("received", *a, "")
+	if err == nil {
+		err = h.Mailer.Send(a.Email, subject, body)
+	}
+	if err != nil {
+		// The application is saved; only the receipt is missing.
+		log.Printf("scholarship: mailing receipt to %s: %v", a.Email, err)
+	}
+	h.message(w, http.StatusOK, "Application received", "Thank you for applying. We will write to "+a.Email+" once the reviewers have made their decision.")
+}
+
+// uploads returns the files attached to the form, or a message saying
+// what is wrong with them.
+func uploads(r *http.Request) ([]Upload, string) {
+	var files []Upload
+	for _, fh := range r.MultipartForm.File["attachments"] {
+		if fh.Filename == "" || fh.Size == 0 {
+			continue
+		}
+		if len(files) == MaxFiles {
+			return nil, fmt.Sprintf("Please attach at most %d files.", MaxFiles)
+		}
+		ext := strings.ToLower(filepath.Ext(fh.Filename))
+		if _, ok := fileTypes[ext]; !ok {
+			return nil, fmt.Sprintf("%s: please attach PDF, text or Markdown files.", fh.Filename)
+		}
+		if fh.Size > MaxFileSize {
+			return nil, fmt.Sprintf("%s: attachments may be at most %d MB each.", fh.Filename, MaxFileSize>>20)
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "The form could not be read; please try again."
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return nil, "The form could not be read; please try again."
+		}
+		if ext == ".pdf" && !bytes.HasPrefix(data, []byte("%PDF-")) || ext != ".pdf" && !utf8.Valid(data) {
+			return nil, fmt.Sprintf("%s is not a valid %s file.", fh.Filename, strings.ToUpper(strings.TrimPrefix(ext, ".")))
+		}
+		files = append(files, Upload{Name: filepath.Base(fh.Filename), Data: data})
+	}
+	return files, ""
+}
+
+// authenticate returns the reviewer making r, or "" after asking for
+// credentials or refusing a request that did not arrive privately.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) string {
+	if !adminauth.Private(r) {
+		adminauth.Refuse(w)
+		return ""
+	}
+	name, password, ok := r.BasicAuth()
+	if ok {
+		if hash, found := h.Reviewers[name]; found && adminauth.Check(hash, password) {
+			return name
+		}
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="Scholarship review"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return ""
+}
+
+// csrf returns the token the review forms of reviewer carry. The key is
+// made anew when the server starts, so a form left open across a
+// restart has to be reloaded.
+func (h *Handler) csrf(reviewer string) string {
+	m := hmac.New(sha256.New, h.key)
+	m.Write([]byte("csrf\n" + reviewer))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+// listEntry is a line of the reviewer's list.
+type listEntry struct {
+	Application
+	Reviews int
+	// Mine is the reviewer's score, or -1.
+	Mine float64
+}
+
+// list shows the applications, those the reviewer has yet to score
+// first, then those with the fewest reviews.
+func (h *Handler) list(w http.ResponseWriter, reviewer string) {
+	apps, err := h.Store.Applications()
+	if err != nil {
+		log.Printf("scholarship: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var entries []listEntry
+	for _, a := range apps {
+		reviews, err := h.Store.Reviews(a.ID)
+		if err != nil {
+			log.Printf("scholarship: %v", err)
+		}
+		e := listEntry{Application: a, Reviews: len(reviews), Mine: -1}
+		for _, rev := range reviews {
+			if rev.Reviewer == reviewer {
+				e.Mine = h.Config.Score(rev)
+			}
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if (a.Mine < 0) != (b.Mine < 0) {
+			return a.Mine < 0
+		}
+		return a.Reviews < b.Reviews
+	})
+	h.render(w, http.StatusOK, "list", map[string]any{
+		"Title":    h.Config.Name + ": applications",
+		"Reviewer": reviewer,
+		"Entries":  entries,
+	})
+}
+
+// review shows an application with the reviewer's scores, and saves
+// them on POST.
+func (h *Handler) review(w http.ResponseWriter, r *http.Request, reviewer string, a Application) {
+	reviews, err := h.Store.Reviews(a.ID)
+	if err != nil {
+		log.Printf("scholarship: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	mine := Review{Reviewer: reviewer, Scores: map[string]int{}}
+	for _, rev := range reviews {
+		if rev.Reviewer == reviewer {
+			mine = rev
+		}
+	}
+	problem := ""
+	if r.Method == "POST" {
+		if !hmac.Equal([]byte(r.FormValue("csrf")), []byte(h.csrf(reviewer))) {
+			http.Error(w, "Forbidden; reload the page and try again.", http.StatusForbidden)
+			return
+		}
+		mine = Review{Reviewer: reviewer, Scores: map[string]int{}, Comment: strings.TrimSpace(r.FormValue("comment")), Updated: time.Now()}
+		for _, cr := range h.Config.Rubric {
+			n, err := strconv.Atoi(r.FormValue("score-" + cr.Key))
+			if err != nil || n < 0 || n > cr.Max {
+				problem = fmt.Sprintf("Please give %s a score from 0 to %d.", cr.Key, cr.Max)
+			}
+			mine.Scores[cr.Key] = n
+		}
+		if problem == "" {
+			if err := h.Store.SaveReview(a.ID, mine); err != nil {
+				log.Printf("scholarship: %v", err)
+				http.Error(w, "The review could not be saved; please try again.", http.StatusInternalServerError)
+				return
+			}
+			http.Redirect(w, r, Prefix+"review/", http.StatusSeeOther)
+			return
+		}
+	}
+	var paragraphs []string
+	for _, p := range strings.Split(strings.ReplaceAll(a.Essay, "\r\n", "\n"), "\n\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			paragraphs = append(paragraphs, p)
+		}
+	}
+	status := http.StatusOK
+	if problem != "" {
+		status = http.StatusBadRequest
+	}
+	h.render(w, status, "review", map[string]any{
+		"Title":    h.Config.Name + ": " + a.Label(),
+		"App":      a,
+		"Essay":    paragraphs,
+		"Rubric":   h.Config.Rubric,
+		"Review":   mine,
+		"Reviews":  len(reviews),
+		"Problem":  problem,
+		"CSRF":     h.csrf(reviewer),
+		"Reviewer": reviewer,
+	})
+}
+
+// attachment serves the nth attachment of a under a name that does not
+// reveal the applicant's.
+func (h *Handler) attachment(w http.ResponseWriter, r *http.Request, a Application, n string) {
+	i, err := strconv.Atoi(n)
+	if err != nil || i < 1 || i > len(a.Attach) {
+		http.NotFound(w, r)
+		return
+	}
+	att := a.Attach[i-1]
+	ext := filepath.Ext(att.File)
+	w.Header().Set("Content-Type", fileTypes[ext])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="applicant-%s-%d%s"`, a.ID[:6], i, ext))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeFile(w, r, h.Store.AttachmentFile(a.ID, att))
+}
+
+func (h *Handler) render(w http.ResponseWriter, status int, name string, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("scholarship: %v", err)
+	}
+}
+
+// upto returns 0 to n.
+func upto(n int) []int {
+	s := make([]int, n+1)
+	for i := range s {
+		s[i] = i
+	}
+	return s
+}
+
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"kb":    func(n int) int { return (n + 1023) / 1024 },
+	"ext":   filepath.Ext,
+	"score": func(f float64) string { return strconv.FormatFloat(f, 'f', 0, 64) },
+	"upto":  upto,
+	"add":   func(a, b int) int { return a + b },
+}).Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <meta name="robots" content="noindex">
+  <link href="/css/bootstrap.min.css" rel="stylesheet">
+  <link href="/css/hc.css" rel="stylesheet">
+</head>
+<body>
+  <div class="container">
+    <div id="article-body">
+      <div class="article-title">{{.Title}}</div>
+{{end}}
+
+{{define "foot"}}
+    </div>
+  </div>
+</body>
+</html>
+{{end}}
+
+{{define "message"}}{{template "head" .}}
+      <p>{{.Message}}</p>
+      <p><a href="/">Back to the home page</a></p>
+{{template "foot" .}}{{end}}
+
+{{define "form"}}{{template "head" .}}
+      <p>Tell us about yourself and why you would like to attend. Reviewers
+      do not see your name or address, so please leave them out of your essay
+      and attachments.{{if not .Config.Deadline.IsZero}} Applications close on
+      {{.Config.Deadline.Format "January 2, 2006"}}.{{end}}</p>
+      {{- with .Problem}}
+      <p class="alert alert-warning">{{.}}</p>
+      {{- end}}
+      <form method="post" action="/scholarship/" enctype="multipart/form-data">
+        <input type="text" name="name" class="form-control" placeholder="Your name" value="{{.Values.name}}" required>
+        <input type="email" name="email" class="form-control" placeholder="you@example.com" value="{{.Values.email}}" required>
+        <input type="text" name="country" class="form-control" placeholder="Country you would travel from" value="{{.Values.country}}">
+        <select name="conference" class="form-control" required>
+          {{- range .Config.Conferences}}
+          <option{{if eq . $.Values.conference}} selected{{end}}>{{.}}</option>
+          {{- end}}
+        </select>
+        <textarea name="essay" class="form-control" rows="12" required
+          placeholder="Why would you like to attend, and what would you do with what you learn?{{with .Config.EssayWords}} At most {{.}} words.{{end}}">{{.Values.essay}}</textarea>
+        <label>Attachments, such as a résumé or a talk you have given: up to {{.MaxFiles}} PDF, text or Markdown files of {{.MaxSize}} MB
+          <input type="file" name="attachments" accept=".pdf,.txt,.md" multiple></label>
+        <input type="text" name="website" class="subscribe-website" tabindex="-1" autocomplete="off">
+        <button class="btn btn-default" type="submit">Apply</button>
+      </form>
+      {{- with .Config.Contact}}
+      <p class="meta">Questions? Write to <a href="mailto:{{.}}">{{.}}</a>.</p>
+      {{- end}}
+{{template "foot" .}}{{end}}
+
+{{define "list"}}{{template "head" .}}
+      <p class="meta">Signed in as {{.Reviewer}}. Applicants are shown by label only.</p>
+      {{- if .Entries}}
+      <table class="table">
+        <tr><th>Application</th><th>Conference</th><th>Country</th><th>Attachments</th><th>Reviews</th><th>Your score</th></tr>
+        {{- range .Entries}}
+        <tr>
+          <td><a href="/scholarship/review/{{.ID}}">{{.Label}}</a></td>
+          <td>{{.Conference}}</td>
+          <td>{{.Country}}</td>
+          <td>{{len .Attach}}</td>
+          <td>{{.Reviews}}</td>
+          <td>{{if lt .Mine 0.0}}not reviewed{{else}}{{score .Mine}}{{end}}</td>
+        </tr>
+        {{- end}}
+      </table>
+      {{- else}}
+      <p>No applications yet.</p>
+      {{- end}}
+{{template "foot" .}}{{end}}
+
+{{define "review"}}{{template "head" .}}
+      <p class="meta">{{.App.Conference}}{{with .App.Country}}, from {{.}}{{end}}.
+      Submitted {{.App.Created.Format "2006-01-02"}}; {{.Reviews}} reviews so far.
+      <a href="/scholarship/review/">All applications</a></p>
+      {{- range .Essay}}
+      <p>{{.}}</p>
+      {{- end}}
+      {{- if .App.Attach}}
+      <ul>
+        {{- range $i, $a := .App.Attach}}
+        <li><a href="/scholarship/review/{{$.App.ID}}/{{add $i 1}}">Attachment {{add $i 1}}</a> ({{ext $a.File}}, {{kb $a.Size}} KB)</li>
+        {{- end}}
+      </ul>
+      {{- end}}
+      <h3>Your review</h3>
+      {{- with .Problem}}
+      <p class="alert alert-warning">{{.}}</p>
+      {{- end}}
+      <form method="post">
+        <input type="hidden" name="csrf" value="{{.CSRF}}">
+        <table class="table">
+          {{- range .Rubric}}
+          {{- $score := index $.Review.Scores .Key}}
+          <tr>
+            <td><strong>{{.Key}}</strong><br>{{.Description}}</td>
+            <td>
+              <select name="score-{{.Key}}" class="form-control input-sm">
+                {{- range upto .Max}}
+                <option{{if eq . $score}} selected{{end}}>{{.}}</option>
+                {{- end}}
+              </select>
+            </td>
+          </tr>
+          {{- end}}
+        </table>
+        <textarea name="comment" class="form-control" rows="4" placeholder="Notes for the organizers">{{.Review.Comment}}</textarea>
+        <button class="btn btn-primary" type="submit">Save review</button>
+      </form>
+{{template "foot" .}}{{end}}
+`))
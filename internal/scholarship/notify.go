@@ -0,0 +1,50 @@
+package scholarship
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+// Notify mails each applicant with a decision not yet mailed the
+// template for its outcome, and returns how many were mailed and how
+// many applications have no decision. If record is set, each decision
+// is marked notified once its mail is sent, so that the next run skips
+// it; a dry run leaves it out. Notify stops at the first mail that
+// cannot be sent.
+func Notify(c *Config, s *Store, m subscribe.Mailer, record bool) (sent, undecided int, err error) {
+	apps, err := s.Applications()
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, a := range apps {
+		d, err := s.Decision(a.ID)
+		if err != nil {
+			return sent, undecided, err
+		}
+		if d.Outcome == "" {
+			undecided++
+			continue
+		}
+		if !d.Notified.IsZero() {
+			continue
+		}
+		subject, body, err := c.Letter(d.Outcome, a, d.Outcome)
+		if err != nil {
+			return sent, undecided, err
+		}
+		if err := m.Send(a.Email, subject, body); err != nil {
+			return sent, undecided, fmt.Errorf("%s: %v", a.Label(), err)
+		}
+		sent++
+		if !record {
+			continue
+		}
+		d.Notified = time.Now()
+		if err := s.SaveDecision(a.ID, d); err != nil {
+			return sent, undecided, err
+		}
+	}
+	return sent, undecided, nil
+}
@@ -0,0 +1,134 @@
+package scholarship
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/smtpsink"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+func TestNotify(t *testing.T) {
+	c := loadConfig(t)
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := submit(t, s, []testApp{{"Ada Lovelace", nil}, {"Brian Kernighan", nil}, {"Carol Shaw", nil}, {"Dennis Ritchie", nil}})
+	for i, outcome := range []string{Accepted, Declined, "", Waitlisted} {
+		if outcome == "" {
+			continue
+		}
+		d := Decision{Outcome: outcome, Decided: time.Now()}
+		if i == 3 {
+			d.Notified = time.Now() // mailed by an earlier run
+		}
+		if err := s.SaveDecision(apps[i].ID, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink, err := smtpsink.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	m := &subscribe.SMTP{Addr: sink.Addr(), From: "GopherCon Scholarship <scholarship@example.com>"}
+
+	// A dry run sends the mails but does not record them.
+	sent, undecided, err := Notify(c, s, m, false)
+	if err != nil || sent != 2 || undecided != 1 {
+		t.Fatalf("dry run: %d sent, %d undecided, %v; want 2, 1", sent, undecided, err)
+	}
+	for _, a := range apps[:2] {
+		if d, _ := s.Decision(a.ID); !d.Notified.IsZero() {
+			t.Errorf("dry run marked %s notified", a.Name)
+		}
+	}
+
+	sent, undecided, err = Notify(c, s, m, true)
+	if err != nil || sent != 2 || undecided != 1 {
+		t.Fatalf("run: %d sent, %d undecided, %v; want 2, 1", sent, undecided, err)
+	}
+	msgs := sink.Messages()
+	if len(msgs) != 4 {
+		t.Fatalf("sink received %d messages, want 4", len(msgs))
+	}
+	tests := []struct {
+		to, subject, body string
+	}{
+		{"ada@example.com", "You have been awarded the GopherCon Scholarship", "Hello Ada Lovelace,\n\nCongratulations!"},
+		{"brian@example.com", "Your GopherCon Scholarship application", "we are sorry\nthat we cannot offer you one this time"},
+	}
+	for i, tt := range tests {
+		got := msgs[2+i]
+		if got.From != "scholarship@example.com" || !reflect.DeepEqual(got.To, []string{tt.to}) {
+			t.Errorf("message %d from %s to %v, want scholarship@example.com to %s", i, got.From, got.To, tt.to)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(got.Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		if err != nil || subject != tt.subject {
+			t.Errorf("message %d subject %q, %v; want %q", i, subject, err, tt.subject)
+		}
+		body, _ := io.ReadAll(msg.Body)
+		if text := strings.ReplaceAll(string(body), "\r\n", "\n"); !strings.Contains(text, tt.body) {
+			t.Errorf("message %d body does not contain %q:\n%s", i, tt.body, text)
+		}
+		if d, _ := s.Decision(apps[i].ID); d.Notified.IsZero() {
+			t.Errorf("%s not marked notified", apps[i].Name)
+		}
+	}
+
+	// The next run has nothing left to mail.
+	if sent, _, err = Notify(c, s, m, true); err != nil || sent != 0 {
+		t.Errorf("second run: %d sent, %v; want none", sent, err)
+	}
+	if n := len(sink.Messages()); n != 4 {
+		t.Errorf("sink received %d messages, want 4", n)
+	}
+}
+
+type failingMailer struct{ n int }
+
+func (m *failingMailer) Send(to, subject string, text []byte) error {
+	if m.n == 0 {
+		return errors.New("connection refused")
+	}
+	m.n--
+	return nil
+}
+
+func TestNotifyStopsOnError(t *testing.T) {
+	c := loadConfig(t)
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := submit(t, s, []testApp{{"Ada Lovelace", nil}, {"Brian Kernighan", nil}})
+	for _, a := range apps {
+		if err := s.SaveDecision(a.ID, Decision{Outcome: Accepted}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sent, _, err := Notify(c, s, &failingMailer{n: 1}, true)
+	if sent != 1 || err == nil || !strings.HasPrefix(err.Error(), apps[1].Label()+":") {
+		t.Fatalf("Notify = %d sent, %v; want 1 sent and an error for %s", sent, err, apps[1].Label())
+	}
+	// Only the mail that went out is recorded, so a rerun sends the other.
+	if d, _ := s.Decision(apps[0].ID); d.Notified.IsZero() {
+		t.Error("sent mail not recorded")
+	}
+	if d, _ := s.Decision(apps[1].ID); !d.Notified.IsZero() {
+		t.Error("failed mail recorded as sent")
+	}
+}
@@ -0,0 +1,92 @@
+package scholarship
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Score returns the weighted score of r against the rubric, from 0 to
+// 100. Criteria the review leaves out count as 0.
+func (c *Config) Score(r Review) float64 {
+	var got, total float64
+	for _, cr := range c.Rubric {
+		score := min(max(r.Scores[cr.Key], 0), cr.Max)
+		got += float64(score) * cr.weight()
+		total += float64(cr.Max) * cr.weight()
+	}
+	return 100 * got / total
+}
+
+// Ranked is an application with its standing.
+type Ranked struct {
+	Application
+	// Score is the mean score of the reviews.
+	Score    float64
+	Reviews  int
+	Decision Decision
+}
+
+// Rank returns the applications ordered by their mean score, best
+// first; applications without reviews come last, oldest first.
+func Rank(c *Config, s *Store) ([]Ranked, error) {
+	apps, err := s.Applications()
+	if err != nil {
+		return nil, err
+	}
+	ranked := make([]Ranked, len(apps))
+	for i, a := range apps {
+		reviews, err := s.Reviews(a.ID)
+		if err != nil {
+			return nil, err
+		}
+		d, err := s.Decision(a.ID)
+		if err != nil {
+			return nil, err
+		}
+		r := Ranked{Application: a, Reviews: len(reviews), Decision: d}
+		for _, rev := range reviews {
+			r.Score += c.Score(rev)
+		}
+		if len(reviews) > 0 {
+			r.Score /= float64(len(reviews))
+		}
+		ranked[i] = r
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if (a.Reviews == 0) != (b.Reviews == 0) {
+			return b.Reviews == 0
+		}
+		return a.Score > b.Score
+	})
+	return ranked, nil
+}
+
+// WriteCSV writes the ranking as CSV, one application per line under a
+// header. Applications without reviews have no rank or score. If
+// anonymous is set the names and addresses are left out, and the
+// applications are known only by their labels, for sharing with the
+// reviewers.
+func WriteCSV(w io.Writer, ranked []Ranked, anonymous bool) error {
+	cw := csv.NewWriter(w)
+	header := []string{"rank", "applicant", "conference", "country", "score", "reviews", "decision"}
+	if !anonymous {
+		header = append(header, "name", "email")
+	}
+	cw.Write(header)
+	for i, r := range ranked {
+		rank, score := strconv.Itoa(i+1), strconv.FormatFloat(r.Score, 'f', 1, 64)
+		if r.Reviews == 0 {
+			rank, score = "", ""
+		}
+		row := []string{rank, r.Label(), r.Conference, r.Country, score, strconv.Itoa(r.Reviews), r.Decision.Outcome}
+		if !anonymous {
+			row = append(row, r.Name, r.Email)
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
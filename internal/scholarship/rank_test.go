@@ -0,0 +1,183 @@
+package scholarship
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func loadConfig(t *testing.T) *Config {
+	t.Helper()
+	c, err := LoadConfig(filepath.Join("..", "..", ConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestScore(t *testing.T) {
+	// The rubric weighs motivation and impact twice, need and community
+	// once, each out of 5: 30 points in all.
+	c := loadConfig(t)
+	tests := []struct {
+		scores map[string]int
+		want   float64
+	}{
+		{map[string]int{"motivation": 5, "impact": 5, "need": 5, "community": 5}, 100},
+		{map[string]int{}, 0},
+		{map[string]int{"motivation": 5, "need": 5, "community": 5}, 100 * 20.0 / 30},
+		{map[string]int{"motivation": 3, "impact": 2, "need": 4, "community": 1}, 100 * 15.0 / 30},
+		// Scores out of range are clamped, and unknown criteria ignored.
+		{map[string]int{"motivation": 9, "impact": -3, "need": 5, "style": 5}, 100 * 15.0 / 30},
+	}
+	for _, tt := range tests {
+		if got := c.Score(Review{Scores: tt.scores}); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Score(%v) = %v, want %v", tt.scores, got, tt.want)
+		}
+	}
+}
+
+type testApp struct {
+	name string
+	// reviews are the scores of each reviewer, given to every
+	// criterion.
+	reviews []int
+}
+
+// submit stores the applications, a day apart, with their reviews.
+func submit(t *testing.T, s *Store, apps []testApp) []Application {
+	t.Helper()
+	var out []Application
+	start := time.Date(2014, 2, 1, 0, 0, 0, 0, time.UTC)
+	for i, ta := range apps {
+		a := &Application{
+			Name:       ta.name,
+			Email:      strings.ToLower(strings.Fields(ta.name)[0]) + "@example.com",
+			Country:    "NZ",
+			Conference: "GopherCon 2014",
+			Essay:      "I would like to attend.",
+		}
+		if err := s.Submit(a, nil, start.Add(time.Duration(i)*24*time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+		for j, score := range ta.reviews {
+			scores := map[string]int{"motivation": score, "impact": score, "need": score, "community": score}
+			if err := s.SaveReview(a.ID, Review{Reviewer: string(rune('a' + j)), Scores: scores}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out = append(out, *a)
+	}
+	return out
+}
+
+func TestRank(t *testing.T) {
+	c := loadConfig(t)
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := submit(t, s, []testApp{
+		{"Ada Lovelace", []int{3, 4}},
+		{"Brian Unreviewed", nil},
+		{"Carol Shaw", []int{5}},
+		{"Dennis Later", nil},
+		{"Edsger Low", []int{1, 2, 0}},
+	})
+	ranked, err := Rank(c, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range ranked {
+		got = append(got, r.Name)
+	}
+	// Reviewed applications by mean score, then the others oldest first.
+	want := []string{"Carol Shaw", "Ada Lovelace", "Edsger Low", "Brian Unreviewed", "Dennis Later"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ranking %v, want %v", got, want)
+	}
+	if r := ranked[1]; r.Reviews != 2 || math.Abs(r.Score-70) > 1e-9 {
+		t.Errorf("Ada: %d reviews, score %v; want 2, 70", r.Reviews, r.Score)
+	}
+	if err := s.SaveDecision(apps[2].ID, Decision{Outcome: Accepted}); err != nil {
+		t.Fatal(err)
+	}
+	if ranked, err = Rank(c, s); err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := WriteCSV(&b, ranked, false); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRows := [][]string{
+		{"rank", "applicant", "conference", "country", "score", "reviews", "decision", "name", "email"},
+		{"1", apps[2].Label(), "GopherCon 2014", "NZ", "100.0", "1", "accepted", "Carol Shaw", "carol@example.com"},
+		{"2", apps[0].Label(), "GopherCon 2014", "NZ", "70.0", "2", "", "Ada Lovelace", "ada@example.com"},
+		{"3", apps[4].Label(), "GopherCon 2014", "NZ", "20.0", "3", "", "Edsger Low", "edsger@example.com"},
+		{"", apps[1].Label(), "GopherCon 2014", "NZ", "", "0", "", "Brian Unreviewed", "brian@example.com"},
+		{"", apps[3].Label(), "GopherCon 2014", "NZ", "", "0", "", "Dennis Later", "dennis@example.com"},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("CSV:\n%q\nwant:\n%q", rows, wantRows)
+	}
+
+	// The anonymous export has the same rows without the last two
+	// columns, and names nobody.
+	b.Reset()
+	if err := WriteCSV(&b, ranked, true); err != nil {
+		t.Fatal(err)
+	}
+	if rows, err = csv.NewReader(bytes.NewReader(b.Bytes())).ReadAll(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range wantRows {
+		wantRows[i] = wantRows[i][:7]
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("anonymous CSV:\n%q\nwant:\n%q", rows, wantRows)
+	}
+	for _, a := range apps {
+		first := strings.Fields(a.Name)[0]
+		if bytes.Contains(b.Bytes(), []byte(first)) || bytes.Contains(b.Bytes(), []byte(a.Email)) {
+			t.Errorf("anonymous CSV names %s:\n%s", a.Name, b.Bytes())
+		}
+	}
+}
+
+func TestLabels(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	apps := submit(t, s, []testApp{{"Ada Lovelace", nil}, {"Brian Kernighan", nil}})
+	seen := make(map[string]bool)
+	for _, a := range apps {
+		label := a.Label()
+		if label != "Applicant "+a.ID[:6] || strings.Contains(label, "Ada") || strings.Contains(label, "Brian") {
+			t.Errorf("label %q of %s", label, a.ID)
+		}
+		if seen[label] {
+			t.Errorf("label %q given twice", label)
+		}
+		seen[label] = true
+		// Organizers can refer to an application by its label, with or
+		// without "Applicant", in any case.
+		for _, key := range []string{label, strings.ToUpper(label), a.ID[:6], a.Email} {
+			got, err := s.Find(key)
+			if err != nil || got.ID != a.ID {
+				t.Errorf("Find(%q) = %s, %v; want %s", key, got.ID, err, a.ID)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package scholarship
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrApplied is returned by Submit for an address that has already
+// applied.
+var ErrApplied = errors.New("scholarship: address has already applied")
+
+// Application is a submitted application.
+type Application struct {
+	ID         string       `json:"id"`
+	Name       string       `json:"name"`
+	Email      string       `json:"email"`
+	Country    string       `json:"country"`
+	Conference string       `json:"conference"`
+	Essay      string       `json:"essay"`
+	Attach     []Attachment `json:"attachments,omitempty"`
+	Created    time.Time    `json:"created"`
+}
+
+// Label is how reviewers see the application.
+func (a Application) Label() string {
+	return Label(a.ID)
+}
+
+// Label returns the anonymous label of the application with the given
+// id. Ids are random, so the label tells nothing about the applicant.
+func Label(id string) string {
+	return "Applicant " + id[:6]
+}
+
+// Attachment is a file attached to an application.
+type Attachment struct {
+	// Name is the file's name on the applicant's computer; it is not
+	// shown to reviewers.
+	Name string `json:"name"`
+	// File is the name it is stored under.
+	File string `json:"file"`
+	Size int    `json:"size"`
+}
+
+// Upload is a file sent with the form.
+type Upload struct {
+	Name string
+	Data []byte
+}
+
+// Review is one reviewer's scores for an application.
+type Review struct {
+	Reviewer string         `json:"reviewer"`
+	Scores   map[string]int `json:"scores"`
+	Comment  string         `json:"comment,omitempty"`
+	Updated  time.Time      `json:"updated"`
+}
+
+// Decision is the organizers' decision on an application.
+type Decision struct {
+	Outcome string    `json:"outcome"`
+	Decided time.Time `json:"decided"`
+	// Notified is when the applicant was mailed the decision.
+	Notified time.Time `json:"notified,omitzero"`
+}
+
+// Store keeps the applications in a directory with one subdirectory per
+// application:
+//
+//	<id>/application.json   the form as submitted
+//	<id>/files/             its attachments
+//	<id>/reviews.json       the reviews, written by the server
+//	<id>/decision.json      the decision, written by cmd/scholarship
+//
+// Every file is replaced atomically and read afresh on each call.
+type Store struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// Open returns the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &Store{Dir: dir}, nil
+}
+
+func validID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func (s *Store) path(id string, elem ...string) string {
+	return filepath.Join(append([]string{s.Dir, id}, elem...)...)
+}
+
+// readJSON decodes file into v. A missing file leaves v alone.
+func readJSON(file string, v any) error {
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	return nil
+}
+
+// writeJSON replaces file with the encoding of v.
+func writeJSON(file string, v any) error {
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// Submit stores a new application with its attachments, filling in its
+// ID, Attach and Created fields.
+func (s *Store) Submit(a *Application, files []Upload, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	apps, err := s.Applications()
+	if err != nil {
+		return err
+	}
+	for _, old := range apps {
+		if strings.EqualFold(old.Email, a.Email) {
+			return ErrApplied
+		}
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	a.ID = hex.EncodeToString(id[:])
+	a.Created = now
+	a.Attach = nil
+	if err := os.MkdirAll(s.path(a.ID, "files"), 0700); err != nil {
+		return err
+	}
+	for i, f := range files {
+		att := Attachment{
+			Name: f.Name,
+			File: fmt.Sprintf("%d%s", i+1, strings.ToLower(filepath.Ext(f.Name))),
+			Size: len(f.Data),
+		}
+		if err := os.WriteFile(s.path(a.ID, "files", att.File), f.Data, 0600); err != nil {
+			os.RemoveAll(s.path(a.ID))
+			return err
+		}
+		a.Attach = append(a.Attach, att)
+	}
+	if err := writeJSON(s.path(a.ID, "application.json"), a); err != nil {
+		os.RemoveAll(s.path(a.ID))
+		return err
+	}
+	return nil
+}
+
+// Applications returns every application, oldest first.
+func (s *Store) Applications() ([]Application, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	var apps []Application
+	for _, e := range entries {
+		if !e.IsDir() || !validID(e.Name()) {
+			continue
+		}
+		a, err := s.Get(e.Name())
+		if errors.Is(err, os.ErrNotExist) {
+			continue // being submitted or deleted
+		}
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, a)
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Created.Before(apps[j].Created) })
+	return apps, nil
+}
+
+// Get returns the application with the given id, or an error wrapping
+// os.ErrNotExist.
+func (s *Store) Get(id string) (Application, error) {
+	var a Application
+	if !validID(id) {
+		return a, fmt.Errorf("scholarship: no application %q: %w", id, os.ErrNotExist)
+	}
+	b, err := os.ReadFile(s.path(id, "application.json"))
+	if err != nil {
+		return a, err
+	}
+	if err := json.Unmarshal(b, &a); err != nil {
+		return a, fmt.Errorf("%s: %v", id, err)
+	}
+	return a, nil
+}
+
+// Find returns the application whose id, label or email address is key.
+// The "Applicant " of a label can be left out.
+func (s *Store) Find(key string) (Application, error) {
+	key = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(key)), "applicant ")
+	if validID(key) {
+		return s.Get(key)
+	}
+	apps, err := s.Applications()
+	if err != nil {
+		return Application{}, err
+	}
+	var found []Application
+	for _, a := range apps {
+		if strings.EqualFold(a.Email, key) || len(key) >= 6 && strings.HasPrefix(a.ID, key) {
+			found = append(found, a)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Application{}, fmt.Errorf("scholarship: no application %q: %w", key, os.ErrNotExist)
+	case 1:
+		return found[0], nil
+	}
+	return Application{}, fmt.Errorf("scholarship: %q matches %d applications", key, len(found))
+}
+
+// AttachmentFile returns the path of an attachment of the application.
+func (s *Store) AttachmentFile(id string, att Attachment) string {
+	return s.path(id, "files", filepath.Base(att.File))
+}
+
+// Reviews returns the reviews of the application, by reviewer.
+func (s *Store) Reviews(id string) ([]Review, error) {
+	if !validID(id) {
+		return nil, fmt.Errorf("scholarship: no application %q: %w", id, os.ErrNotExist)
+	}
+	var reviews []Review
+	if err := readJSON(s.path(id, "reviews.json"), &reviews); err != nil {
+		return nil, err
+	}
+	return reviews, nil
+}
+
+// SaveReview records r, replacing the reviewer's earlier review of the
+// application.
+func (s *Store) SaveReview(id string, r Review) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	reviews, err := s.Reviews(id)
+	if err != nil {
+		return err
+	}
+	out := []Review{r}
+	for _, old := range reviews {
+		if old.Reviewer != r.Reviewer {
+			out = append(out, old)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Reviewer < out[j].Reviewer })
+	return writeJSON(s.path(id, "reviews.json"), out)
+}
+
+// Decision returns the decision on the application; its Outcome is ""
+// if none has been made.
+func (s *Store) Decision(id string) (Decision, error) {
+	var d Decision
+	if !validID(id) {
+		return d, fmt.Errorf("scholarship: no application %q: %w", id, os.ErrNotExist)
+	}
+	return d, readJSON(s.path(id, "decision.json"), &d)
+}
+
+// SaveDecision records the decision on the application.
+func (s *Store) SaveDecision(id string, d Decision) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return writeJSON(s.path(id, "decision.json"), d)
+}
+
+// Delete removes the application, its attachments, reviews and
+// decision.
+func (s *Store) Delete(id string) error {
+	if _, err := s.Get(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.path(id))
+}
@@ -0,0 +1,119 @@
+package shortlink
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Handler serves /s/<code> redirects and the QR codes of each link at
+// /s/<code>.png and /s/<code>.svg, counting the redirects it serves.
+type Handler struct {
+	Store *Store
+	// BaseURL is the site URL encoded into QR codes.
+	BaseURL string
+
+	mu   sync.Mutex
+	hits map[string]int
+}
+
+// NewHandler returns a Handler for the links in s.
+func NewHandler(s *Store, baseURL string) *Handler {
+	return &Handler{Store: s, BaseURL: strings.TrimSuffix(baseURL, "/"), hits: make(map[string]int)}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code := strings.TrimPrefix(r.URL.Path, Prefix)
+	ext := ""
+	if i := strings.LastIndex(code, "."); i >= 0 {
+		code, ext = code[:i], code[i:]
+	}
+	l, ok := h.Store.Lookup(code)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if l.Retired {
+		http.Error(w, "This article has been removed.", http.StatusGone)
+		return
+	}
+	switch ext {
+	case "":
+		h.hit(code)
+		// Not a permanent redirect: browsers would cache it and the
+		// hit would go uncounted.
+		http.Redirect(w, r, l.Path, http.StatusFound)
+	case ".png":
+		b, err := PNG(h.BaseURL + Prefix + code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(b)
+	case ".svg":
+		b, err := SVG(h.BaseURL + Prefix + code)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(b)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) hit(code string) {
+	h.mu.Lock()
+	h.hits[code]++
+	h.mu.Unlock()
+}
+
+// Hits returns the number of redirects served per code.
+func (h *Handler) Hits() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := make(map[string]int, len(h.hits))
+	for k, v := range h.hits {
+		out[k] = v
+	}
+	return out
+}
+
+// LoadHits adds the counts saved in file by SaveHits. A missing file is
+// not an error.
+func (h *Handler) LoadHits(file string) error {
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved map[string]int
+	if err := json.Unmarshal(b, &saved); err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for k, v := range saved {
+		h.hits[k] += v
+	}
+	return nil
+}
+
+// SaveHits writes the counts to file, replacing it atomically.
+func (h *Handler) SaveHits(file string) error {
+	b, err := json.MarshalIndent(h.Hits(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, append(b, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
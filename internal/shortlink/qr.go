@@ -0,0 +1,44 @@
+package shortlink
+
+import (
+	"bytes"
+	"fmt"
+
+	"rsc.io/qr"
+)
+
+// quiet is the width, in modules, of the blank border the QR
+// specification requires around a code.
+const quiet = 4
+
+// PNG returns a QR code for url as a PNG image.
+func PNG(url string) ([]byte, error) {
+	c, err := qr.Encode(url, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	c.Scale = 8
+	return c.PNG(), nil
+}
+
+// SVG returns a QR code for url as an SVG image, one square per dark
+// module, so that it scales cleanly for print.
+func SVG(url string) ([]byte, error) {
+	c, err := qr.Encode(url, qr.M)
+	if err != nil {
+		return nil, err
+	}
+	size := c.Size + 2*quiet
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, size, size)
+	for y := 0; y < c.Size; y++ {
+		for x := 0; x < c.Size; x++ {
+			if c.Black(x, y) {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x+quiet, y+quiet)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>` + "\n")
+	return buf.Bytes(), nil
+}
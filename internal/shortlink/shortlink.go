@@ -0,0 +1,137 @@
+// Package shortlink assigns permanent short codes to published articles
+// and serves the /s/<code> redirects.
+//
+// Codes are recorded in data/shortlinks.toml, which is committed with the
+// content. A code is handed out when an article is first seen in content/
+// and is never changed or given to another article, even after the
+// article it names is deleted.
+package shortlink
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Prefix is the URL path short links are served under.
+const Prefix = "/s/"
+
+// Link maps a short code to an article.
+type Link struct {
+	Code string `toml:"code"`
+	// Path is the article URL, e.g. "/advent-2014/delve/".
+	Path    string    `toml:"path"`
+	Created time.Time `toml:"created"`
+	// Retired is set once the article has been removed from content/.
+	// The code stays reserved and answers with 410 Gone.
+	Retired bool `toml:"retired,omitempty"`
+}
+
+// Store is the set of assigned links.
+type Store struct {
+	// Next is the number encoded by the next code to be assigned.
+	Next  int    `toml:"next"`
+	Links []Link `toml:"link"`
+}
+
+// Load reads the store from file. A missing file is an empty store.
+func Load(file string) (*Store, error) {
+	s := &Store{Next: 1}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(b), s); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return s, s.check()
+}
+
+// check verifies that no code or path appears twice and that Next is
+// past every assigned code.
+func (s *Store) check() error {
+	codes := make(map[string]bool)
+	paths := make(map[string]bool)
+	for _, l := range s.Links {
+		if codes[l.Code] {
+			return fmt.Errorf("shortlink: code %q assigned twice", l.Code)
+		}
+		if paths[l.Path] && !l.Retired {
+			return fmt.Errorf("shortlink: path %q has two codes", l.Path)
+		}
+		codes[l.Code] = true
+		paths[l.Path] = true
+		n, err := strconv.ParseInt(l.Code, 36, 64)
+		if err != nil {
+			return fmt.Errorf("shortlink: bad code %q", l.Code)
+		}
+		if int(n) >= s.Next {
+			return fmt.Errorf("shortlink: code %q is not below next (%d)", l.Code, s.Next)
+		}
+	}
+	return nil
+}
+
+// Save writes the store to file.
+func (s *Store) Save(file string) error {
+	var buf bytes.Buffer
+	buf.WriteString("# Generated by cmd/shortlink. Codes are permanent: never edit,\n# remove or reuse an entry.\n\n")
+	if err := toml.NewEncoder(&buf).Encode(s); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// Lookup returns the link with the given code.
+func (s *Store) Lookup(code string) (Link, bool) {
+	for _, l := range s.Links {
+		if l.Code == code {
+			return l, true
+		}
+	}
+	return Link{}, false
+}
+
+// Code returns the live code of the article at path.
+func (s *Store) Code(path string) (string, bool) {
+	for _, l := range s.Links {
+		if l.Path == path && !l.Retired {
+			return l.Code, true
+		}
+	}
+	return "", false
+}
+
+// Sync assigns codes to the published article paths that do not have one
+// yet, in the order given, and retires the codes of paths no longer
+// published. An article that comes back after being removed gets a new
+// code. Sync returns the links it added and retired.
+func (s *Store) Sync(paths []string, now time.Time) (added, retired []Link) {
+	live := make(map[string]bool)
+	for _, p := range paths {
+		live[p] = true
+	}
+	for i, l := range s.Links {
+		if !l.Retired && !live[l.Path] {
+			s.Links[i].Retired = true
+			retired = append(retired, s.Links[i])
+		}
+	}
+	for _, p := range paths {
+		if _, ok := s.Code(p); ok {
+			continue
+		}
+		l := Link{Code: strconv.FormatInt(int64(s.Next), 36), Path: p, Created: now.UTC().Truncate(time.Second)}
+		s.Next++
+		s.Links = append(s.Links, l)
+		added = append(added, l)
+	}
+	return added, retired
+}
@@ -0,0 +1,160 @@
+// Package site reads the rendered output of a Hugo build, the tree
+// found under public/ or public-main/.
+package site
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Taxonomies are the top level sections generated from the
+// [taxonomies] block of the Hugo configs rather than from content.
+var Taxonomies = []string{"authors", "series", "tags", "categories"}
+
+// Page is a single rendered HTML page.
+type Page struct {
+	// URL is the path the page is served at, e.g. "/advent-2014/delve/".
+	URL string
+	// File is the path of the page relative to the publish directory.
+	File  string
+	Title string
+	// Article is true for pages rendered from a content file, as opposed
+	// to the home page, section lists and taxonomy pages.
+	Article bool
+}
+
+// Slug returns the last element of the page URL.
+func (p Page) Slug() string {
+	return path.Base(strings.TrimSuffix(p.URL, "/"))
+}
+
+// Manifest lists every page of a rendered site, sorted by URL.
+type Manifest struct {
+	Pages []Page
+}
+
+// Articles returns the pages rendered from content files.
+func (m *Manifest) Articles() []Page {
+	var out []Page
+	for _, p := range m.Pages {
+		if p.Article {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Lookup returns the page served at url.
+func (m *Manifest) Lookup(url string) (Page, bool) {
+	i := sort.Search(len(m.Pages), func(i int) bool { return m.Pages[i].URL >= url })
+	if i < len(m.Pages) && m.Pages[i].URL == url {
+		return m.Pages[i], true
+	}
+	return Page{}, false
+}
+
+// Load builds the manifest of the site published in dir.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(p) != ".html" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		title, err := readTitle(p)
+		if err != nil {
+			return err
+		}
+		m.Pages = append(m.Pages, Page{URL: URL(rel), File: rel, Title: title})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(m.Pages, func(i, j int) bool { return m.Pages[i].URL < m.Pages[j].URL })
+	classify(m.Pages)
+	return m, nil
+}
+
+// URL returns the URL a file relative to the publish directory is
+// served at.
+func URL(rel string) string {
+	rel = "/" + strings.TrimPrefix(rel, "/")
+	if path.Base(rel) != "index.html" {
+		return rel
+	}
+	if dir := path.Dir(rel); dir != "/" {
+		return dir + "/"
+	}
+	return "/"
+}
+
+// paginationRE matches the URLs of the second and later pages of a
+// paginated list, like /page/2/ or /advent-2014/page/3/.
+var paginationRE = regexp.MustCompile(`(^|/)page/[0-9]+/$`)
+
+// classify marks the article pages. A page is an article unless it is
+// the home page, lives under a taxonomy, is a page of a paginated list,
+// or has pages nested below it, which makes it a section list.
+func classify(pages []Page) {
+	for i := range pages {
+		u := pages[i].URL
+		if u == "/" || !strings.HasSuffix(u, "/") || isTaxonomy(u) || paginationRE.MatchString(u) {
+			continue
+		}
+		nested := i+1 < len(pages) && strings.HasPrefix(pages[i+1].URL, u)
+		pages[i].Article = !nested
+	}
+}
+
+func isTaxonomy(u string) bool {
+	first := strings.SplitN(strings.TrimPrefix(u, "/"), "/", 2)[0]
+	for _, t := range Taxonomies {
+		if first == t {
+			return true
+		}
+	}
+	return false
+}
+
+// readTitle returns the text of the first <title> element of the file.
+// The tokenizer unescapes the text, so it must not be unescaped again.
+func readTitle(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	z := html.NewTokenizer(f)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return "", nil
+			}
+			return "", z.Err()
+		case html.StartTagToken:
+			if name, _ := z.TagName(); string(name) == "title" {
+				if z.Next() == html.TextToken {
+					return strings.TrimSpace(string(z.Text())), nil
+				}
+				return "", nil
+			}
+		}
+	}
+}
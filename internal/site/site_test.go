@@ -0,0 +1,68 @@
+package site
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestArticles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{
+		"index.html",
+		"page/2/index.html",
+		"advent-2014/index.html",
+		"advent-2014/page/2/index.html",
+		"advent-2014/delve/index.html",
+		"advent-2014/page-objects/index.html",
+		"tags/go/index.html",
+		"tags/go/page/2/index.html",
+		"moving-to-go/index.html",
+		"404.html",
+	} {
+		file := filepath.Join(dir, filepath.FromSlash(f))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("<title>"+f+"</title>"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range m.Articles() {
+		got = append(got, p.URL)
+	}
+	want := []string{"/advent-2014/delve/", "/advent-2014/page-objects/", "/moving-to-go/"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Articles() = %q, want %q", got, want)
+	}
+}
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		html, want string
+	}{
+		{"<title> Go &amp; Vim </title>", "Go & Vim"},
+		// An escaped entity stays one: the title is unescaped once.
+		{"<title>The &amp;lt;template&amp;gt; element</title>", "The &lt;template&gt; element"},
+		{"<title>&lt;-chan int</title>", "<-chan int"},
+		{"<html><head><title></title></head></html>", ""},
+		{"<p>no title</p>", ""},
+	}
+	dir := t.TempDir()
+	for i, tt := range tests {
+		file := filepath.Join(dir, "page.html")
+		if err := os.WriteFile(file, []byte(tt.html), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readTitle(file)
+		if err != nil || got != tt.want {
+			t.Errorf("%d: readTitle(%q) = %q, %v; want %q", i, tt.html, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package sites generates the Hugo configs of the blog and the main site
+// from sites.toml, which declares the settings both share once and each
+// site's own settings beside them.
+//
+// The generated configs are committed, since Hugo reads them directly.
+// Check reports when one of them no longer matches the manifest, which
+// catches hand edits that would otherwise drift apart silently.
+package sites
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// ManifestFile is the default manifest name.
+const ManifestFile = "sites.toml"
+
+// Manifest is the decoded sites.toml.
+type Manifest struct {
+	// DefaultLanguage is the language of articles that do not name one
+	// in front matter.
+	DefaultLanguage string `toml:"defaultLanguage"`
+	// Languages are the languages articles are written in, by the code
+	// their front matter names them with.
+	Languages map[string]Language `toml:"languages"`
+	// Shared holds the settings of every site.
+	Shared content.Config `toml:"shared"`
+	Sites  []Site         `toml:"site"`
+}
+
+// Language is a language articles are written in.
+type Language struct {
+	// Name is the language's name in itself, for the language switcher.
+	Name string `toml:"name"`
+	// LanguageCode is the language tag pages in the language carry, as
+	// in the lang and hreflang attributes.
+	LanguageCode string `toml:"languageCode"`
+}
+
+// Site is one site's config file and the settings it overrides.
+type Site struct {
+	Name string `toml:"name"`
+	// Output is the Hugo config file generated for the site.
+	Output string `toml:"config"`
+	// Jobs is set for the site cmd/build publishes the job board with.
+	Jobs bool `toml:"jobs"`
+	content.Config
+}
+
+// Load reads a manifest.
+func Load(file string) (*Manifest, error) {
+	m := &Manifest{}
+	md, err := toml.DecodeFile(file, m)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	var unknown []toml.Key
+	for _, k := range md.Undecoded() {
+		// Params are free-form, and tables in them are left undecoded.
+		if len(k) < 3 || k[1] != "params" {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%s: unknown keys %v", file, unknown)
+	}
+	if len(m.Languages) > 0 {
+		def, ok := m.Languages[m.DefaultLanguage]
+		if !ok {
+			return nil, fmt.Errorf("%s: defaultLanguage %q is not one of the languages", file, m.DefaultLanguage)
+		}
+		if m.Shared.LanguageCode != "" && m.Shared.LanguageCode != def.LanguageCode {
+			return nil, fmt.Errorf("%s: languageCode %q is not the default language's", file, m.Shared.LanguageCode)
+		}
+		for code, l := range m.Languages {
+			if l.Name == "" || l.LanguageCode == "" {
+				return nil, fmt.Errorf("%s: language %s needs a name and a languageCode", file, code)
+			}
+		}
+	}
+	seen := make(map[string]bool)
+	for _, s := range m.Sites {
+		if s.Name == "" || s.Output == "" {
+			return nil, fmt.Errorf("%s: every site needs a name and a config", file)
+		}
+		if seen[s.Output] {
+			return nil, fmt.Errorf("%s: %s generated twice", file, s.Output)
+		}
+		seen[s.Output] = true
+	}
+	return m, nil
+}
+
+// Resolve returns the config of s: the shared settings with the site's
+// own settings laid over them. The languages go into the params, where
+// the templates find them as .Site.Params.languages, with the keys in
+// lower case as Hugo gives them to templates, and the default
+// language's code becomes the site's languageCode. So do the base URLs
+// of every site, by name, so that one site can link to another as
+// .Site.Params.sites.blog.
+func (m *Manifest) Resolve(s Site) content.Config {
+	c := m.Shared
+	c.File = s.Output
+	params := make(map[string]any)
+	if len(m.Languages) > 0 {
+		c.LanguageCode = m.Languages[m.DefaultLanguage].LanguageCode
+		languages := make(map[string]any)
+		for code, l := range m.Languages {
+			languages[code] = map[string]any{"name": l.Name, "languagecode": l.LanguageCode}
+		}
+		params["defaultlanguage"] = m.DefaultLanguage
+		params["languages"] = languages
+	}
+	urls := make(map[string]any)
+	for _, o := range m.Sites {
+		url := o.BaseURL
+		if url == "" {
+			url = m.Shared.BaseURL
+		}
+		if url != "" {
+			urls[o.Name] = url
+		}
+	}
+	if len(urls) > 0 {
+		params["sites"] = urls
+	}
+	if len(params) > 0 {
+		for k, v := range c.Params {
+			params[k] = v
+		}
+		c.Params = params
+	}
+	over := reflect.ValueOf(s.Config)
+	dst := reflect.ValueOf(&c).Elem()
+	for i := 0; i < over.NumField(); i++ {
+		f := over.Field(i)
+		switch f.Kind() {
+		case reflect.String:
+			if f.String() != "" {
+				dst.Field(i).Set(f)
+			}
+		case reflect.Map:
+			if f.Len() == 0 {
+				continue
+			}
+			merged := reflect.MakeMap(f.Type())
+			if base := dst.Field(i); !base.IsNil() {
+				for _, k := range base.MapKeys() {
+					merged.SetMapIndex(k, base.MapIndex(k))
+				}
+			}
+			for _, k := range f.MapKeys() {
+				merged.SetMapIndex(k, f.MapIndex(k))
+			}
+			dst.Field(i).Set(merged)
+		}
+	}
+	return c
+}
+
+// header starts every generated config.
+const header = "# Generated from %s by `go run ./cmd/siteconfig generate`.\n# Do not edit: change %s and regenerate.\n"
+
+// Generate returns the contents of every site's config, by file name.
+func (m *Manifest) Generate(manifestFile string) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	for _, s := range m.Sites {
+		b, err := Encode(m.Resolve(s), filepath.Base(manifestFile))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Output, err)
+		}
+		out[s.Output] = b
+	}
+	return out, nil
+}
+
+// Encode formats c as a Hugo config, with keys in a fixed order.
+func Encode(c content.Config, source string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, header, source, source)
+	for _, kv := range []struct{ k, v string }{
+		{"baseurl", c.BaseURL},
+		{"languageCode", c.LanguageCode},
+		{"title", c.Title},
+		{"contentdir", c.ContentDir},
+		{"layoutdir", c.LayoutDir},
+		{"publishdir", c.PublishDir},
+		{"disqusShortname", c.DisqusShortname},
+	} {
+		if kv.v != "" {
+			fmt.Fprintf(&buf, "%s = %s\n", kv.k, strconv.Quote(kv.v))
+		}
+	}
+	if len(c.Taxonomies) > 0 {
+		buf.WriteString("[taxonomies]\n")
+		keys := make([]string, 0, len(c.Taxonomies))
+		for k := range c.Taxonomies {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&buf, "   %s = %s\n", k, strconv.Quote(c.Taxonomies[k]))
+		}
+	}
+	if len(c.Params) > 0 {
+		params := struct {
+			Params map[string]any `toml:"params"`
+		}{c.Params}
+		if err := toml.NewEncoder(&buf).Encode(params); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Mismatch describes a generated config that disagrees with the manifest.
+type Mismatch struct {
+	File string
+	// Keys lists the settings whose values differ. It is empty when only
+	// formatting or comments differ.
+	Keys []string
+}
+
+func (m Mismatch) String() string {
+	if len(m.Keys) == 0 {
+		return fmt.Sprintf("%s: differs from the generated config in formatting only", m.File)
+	}
+	return fmt.Sprintf("%s: %v disagree with %s", m.File, m.Keys, ManifestFile)
+}
+
+// Check compares the configs under root with the ones the manifest
+// generates.
+func (m *Manifest) Check(root, manifestFile string) ([]Mismatch, error) {
+	want, err := m.Generate(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	var out []Mismatch
+	for _, s := range m.Sites {
+		got, err := os.ReadFile(filepath.Join(root, s.Output))
+		if os.IsNotExist(err) {
+			out = append(out, Mismatch{File: s.Output, Keys: []string{"(missing file)"}})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Equal(got, want[s.Output]) {
+			continue
+		}
+		keys, err := diffKeys(got, want[s.Output])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", s.Output, err)
+		}
+		out = append(out, Mismatch{File: s.Output, Keys: keys})
+	}
+	return out, nil
+}
+
+// diffKeys returns the dotted keys whose values differ between two
+// TOML documents.
+func diffKeys(a, b []byte) ([]string, error) {
+	var ma, mb map[string]any
+	if _, err := toml.Decode(string(a), &ma); err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(b), &mb); err != nil {
+		return nil, err
+	}
+	var keys []string
+	var walk func(prefix string, x, y map[string]any)
+	walk = func(prefix string, x, y map[string]any) {
+		names := make(map[string]bool)
+		for k := range x {
+			names[k] = true
+		}
+		for k := range y {
+			names[k] = true
+		}
+		for k := range names {
+			sx, okx := x[k].(map[string]any)
+			sy, oky := y[k].(map[string]any)
+			if okx && oky {
+				walk(prefix+k+".", sx, sy)
+				continue
+			}
+			if !reflect.DeepEqual(x[k], y[k]) {
+				keys = append(keys, prefix+k)
+			}
+		}
+	}
+	walk("", ma, mb)
+	sort.Strings(keys)
+	return keys, nil
+}
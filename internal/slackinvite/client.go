@@ -0,0 +1,96 @@
+package slackinvite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client sends invites through a chat platform.
+type Client interface {
+	Invite(ctx context.Context, email, name string) error
+}
+
+// Slack invites through Slack's web API.
+type Slack struct {
+	// BaseURL is the API root, "https://<team>.slack.com/api"; a fake
+	// API can be put in its place.
+	BaseURL string
+	// Token is an admin token allowed to invite users.
+	Token  string
+	Client *http.Client
+}
+
+// NewSlack returns a client for the Slack team with the given name.
+func NewSlack(team, token string) *Slack {
+	return &Slack{
+		BaseURL: "https://" + team + ".slack.com/api",
+		Token:   token,
+		Client:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Invite invites email. An address that is already invited or already a
+// member is not an error.
+func (s *Slack) Invite(ctx context.Context, email, name string) error {
+	first, last, _ := strings.Cut(strings.TrimSpace(name), " ")
+	form := url.Values{
+		"token":      {s.Token},
+		"email":      {email},
+		"first_name": {first},
+		"last_name":  {last},
+		"set_active": {"true"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(s.BaseURL, "/")+"/users.admin.invite", strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack: %s", resp.Status)
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("slack: %v", err)
+	}
+	switch {
+	case result.OK, result.Error == "already_invited", result.Error == "already_in_team":
+		return nil
+	}
+	return fmt.Errorf("slack: %s", result.Error)
+}
+
+// Fake is a Client that records and logs invites instead of sending
+// them, for running the service locally.
+type Fake struct {
+	mu      sync.Mutex
+	Invited []string
+	// Err, if set, is returned by every invite.
+	Err error
+}
+
+// Invite records email.
+func (f *Fake) Invite(ctx context.Context, email, name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	log.Printf("slackinvite: fake invite of %s <%s>", name, email)
+	f.Invited = append(f.Invited, email)
+	return nil
+}
@@ -0,0 +1,68 @@
+package slackinvite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeAPI serves users.admin.invite, answering with the body, or the
+// status, set for each address.
+func fakeAPI(t *testing.T, bodies map[string]string, statuses map[string]int) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/users.admin.invite" {
+			t.Errorf("%s %s, want POST /api/users.admin.invite", r.Method, r.URL.Path)
+			http.NotFound(w, r)
+			return
+		}
+		if got := r.PostFormValue("token"); got != "xoxp-test" {
+			t.Errorf("token = %q", got)
+		}
+		email := r.PostFormValue("email")
+		if email == "ada@example.com" && (r.PostFormValue("first_name") != "Ada" || r.PostFormValue("last_name") != "King Lovelace") {
+			t.Errorf("name = %q %q, want Ada, King Lovelace", r.PostFormValue("first_name"), r.PostFormValue("last_name"))
+		}
+		if status, ok := statuses[email]; ok {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(bodies[email]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSlackInvite(t *testing.T) {
+	srv := fakeAPI(t, map[string]string{
+		"ada@example.com":     `{"ok":true}`,
+		"invited@example.com": `{"ok":false,"error":"already_invited"}`,
+		"member@example.com":  `{"ok":false,"error":"already_in_team"}`,
+		"bad@example.com":     `{"ok":false,"error":"invalid_email"}`,
+		"garbled@example.com": `<html>`,
+	}, map[string]int{
+		"down@example.com": http.StatusServiceUnavailable,
+	})
+	s := &Slack{BaseURL: srv.URL + "/api/", Token: "xoxp-test", Client: srv.Client()}
+	tests := []struct {
+		email, err string
+	}{
+		{"ada@example.com", ""},
+		{"invited@example.com", ""},
+		{"member@example.com", ""},
+		{"bad@example.com", "slack: invalid_email"},
+		{"garbled@example.com", "slack: invalid character"},
+		{"down@example.com", "slack: 503 Service Unavailable"},
+	}
+	for _, tt := range tests {
+		err := s.Invite(context.Background(), tt.email, " Ada King Lovelace ")
+		switch {
+		case tt.err == "" && err != nil:
+			t.Errorf("Invite(%s) = %v", tt.email, err)
+		case tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)):
+			t.Errorf("Invite(%s) = %v, want %s", tt.email, err, tt.err)
+		}
+	}
+}
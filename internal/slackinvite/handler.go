@@ -0,0 +1,328 @@
+package slackinvite
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+	"github.com/gopheracademy/gopheracademy-web/internal/subscribe"
+)
+
+// Prefix is the URL path the handler is served under.
+const Prefix = "/slack/"
+
+// verifyAction is the action of the address verification tokens.
+const verifyAction = "slack-verify"
+
+// CodeOfConduct is the code of conduct members agree to.
+const CodeOfConduct = "https://docs.google.com/document/d/1YO_xIZPhD1OsquKdCuAq-fFECs8b37wfhVRfnx3DjzM"
+
+// Handler serves the request form at Prefix, the verification links, and
+// the admin queue at Prefix+"admin/".
+type Handler struct {
+	Store     *Store
+	Client    Client
+	Mailer    subscribe.Mailer
+	Signer    subscribe.Signer
+	Challenge *Challenge
+	Limiter   *Limiter
+	// Base is the site URL verification links are made under.
+	Base string
+	// Admins maps each administrator's name to the hash of their
+	// password, as adminauth.Hash stores it, for HTTP basic
+	// authentication of the admin queue.
+	Admins map[string]string
+	// TTL is how long verification links stay valid.
+	TTL time.Duration
+}
+
+// NewHandler returns a Handler whose tokens and challenges are signed
+// with key.
+func NewHandler(store *Store, client Client, mailer subscribe.Mailer, key []byte, base string, admins map[string]string) *Handler {
+	return &Handler{
+		Store:     store,
+		Client:    client,
+		Mailer:    mailer,
+		Signer:    subscribe.Signer{Key: key},
+		Challenge: &Challenge{Key: key, Bits: 16, TTL: 30 * time.Minute},
+		Limiter:   &Limiter{N: 5, Window: time.Hour},
+		Base:      strings.TrimSuffix(base, "/"),
+		Admins:    admins,
+		TTL:       48 * time.Hour,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case Prefix:
+		if r.Method == "POST" {
+			h.request(w, r)
+			return
+		}
+		h.form(w, http.StatusOK, "")
+	case Prefix + "verify":
+		h.verify(w, r)
+	case Prefix + "admin/":
+		h.admin(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) form(w http.ResponseWriter, status int, problem string) {
+	h.render(w, status, "form", map[string]any{
+		"Title":         "Join the Gophers Slack",
+		"Problem":       problem,
+		"Challenge":     h.Challenge.New(time.Now()),
+		"Bits":          h.Challenge.Bits,
+		"CodeOfConduct": CodeOfConduct,
+	})
+}
+
+func (h *Handler) message(w http.ResponseWriter, status int, title, msg string) {
+	h.render(w, status, "message", map[string]any{"Title": title, "Message": msg})
+}
+
+func (h *Handler) request(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	if !h.Limiter.Allow(ClientIP(r), now) {
+		h.message(w, http.StatusTooManyRequests, "Too many requests", "Please wait an hour before trying again.")
+		return
+	}
+	if err := h.Challenge.Verify(r.FormValue("challenge"), r.FormValue("counter"), now); err != nil {
+		h.form(w, http.StatusBadRequest, "The form expired or your browser did not finish its check; please send it again.")
+		return
+	}
+	name := strings.TrimSpace(r.FormValue("name"))
+	addr, err := mail.ParseAddress(r.FormValue("email"))
+	switch {
+	case name == "" || err != nil:
+		h.form(w, http.StatusBadRequest, "Please enter your name and a valid email address.")
+		return
+	case r.FormValue("conduct") == "":
+		h.form(w, http.StatusBadRequest, "Please agree to the code of conduct.")
+		return
+	}
+	if _, err := h.Store.Expire(now, h.TTL); err != nil {
+		log.Printf("slackinvite: %v", err)
+	}
+	req, added, err := h.Store.Add(name, addr.Address, now)
+	if err != nil {
+		log.Printf("slackinvite: %v", err)
+		http.Error(w, "Your request could not be saved; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if added {
+		link := h.Base + Prefix + "verify?t=" + h.Signer.Token(verifyAction, req.Email, req.ID, now.Add(h.TTL))
+		text := fmt.Sprintf("Hello %s,\n\nTo confirm your request to join the Gophers Slack, follow this link within %d hours:\n\n%s\n\n"+
+			"An administrator will then send you an invite.\n\nIf you did not ask to join, ignore this mail.\n", req.Name, int(h.TTL.Hours()), link)
+		if err := h.Mailer.Send(req.Email, "Confirm your Gophers Slack request", []byte(text)); err != nil {
+			log.Printf("slackinvite: mailing %s: %v", req.Email, err)
+			http.Error(w, "The confirmation mail could not be sent; please try again later.", http.StatusInternalServerError)
+			return
+		}
+	}
+	h.message(w, http.StatusOK, "Check your inbox", "We have sent a link to "+addr.Address+". Follow it to confirm your address, and an administrator will invite you.")
+}
+
+func (h *Handler) verify(w http.ResponseWriter, r *http.Request) {
+	_, id, err := h.Signer.Verify(verifyAction, r.FormValue("t"), time.Now())
+	if err != nil {
+		h.message(w, http.StatusBadRequest, "Link expired", "This link is invalid or has expired. Please send the form again.")
+		return
+	}
+	ok, err := h.Store.Update(id, func(req *Request) {
+		if req.State == Unverified {
+			req.State = Pending
+			req.Verified = time.Now()
+		}
+	})
+	if err != nil {
+		log.Printf("slackinvite: %v", err)
+		http.Error(w, "Your request could not be saved; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		h.message(w, http.StatusNotFound, "Link expired", "This request has expired. Please send the form again.")
+		return
+	}
+	h.message(w, http.StatusOK, "Address confirmed", "Thank you. An administrator will send your invite soon.")
+}
+
+// authenticate returns the administrator making r, or "" after asking
+// for credentials or refusing a request that did not arrive privately.
+func (h *Handler) authenticate(w http.ResponseWriter, r *http.Request) string {
+	if !adminauth.Private(r) {
+		adminauth.Refuse(w)
+		return ""
+	}
+	name, password, ok := r.BasicAuth()
+	if ok {
+		if hash, found := h.Admins[name]; found && adminauth.Check(hash, password) {
+			return name
+		}
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="Gophers Slack admin"`)
+	http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	return ""
+}
+
+// csrf returns the token the admin forms of admin carry, so that other
+// sites cannot make an administrator's browser approve requests.
+func (h *Handler) csrf(admin string) string {
+	m := hmac.New(sha256.New, h.Signer.Key)
+	m.Write([]byte("csrf\n" + admin))
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func (h *Handler) admin(w http.ResponseWriter, r *http.Request) {
+	admin := h.authenticate(w, r)
+	if admin == "" {
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method == "POST" {
+		if !hmac.Equal([]byte(r.FormValue("csrf")), []byte(h.csrf(admin))) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		if err := h.decide(r.Context(), admin, r.FormValue("id"), r.FormValue("action"), r.FormValue("note")); err != nil {
+			log.Printf("slackinvite: %v", err)
+		}
+		http.Redirect(w, r, Prefix+"admin/", http.StatusSeeOther)
+		return
+	}
+	var recent []Request
+	done := h.Store.List(Invited, Rejected)
+	for i := len(done) - 1; i >= 0 && len(recent) < 20; i-- {
+		recent = append(recent, done[i])
+	}
+	h.render(w, http.StatusOK, "admin", map[string]any{
+		"Title":      "Gophers Slack requests",
+		"Admin":      admin,
+		"CSRF":       h.csrf(admin),
+		"Queue":      h.Store.List(Pending, Failed),
+		"Unverified": len(h.Store.List(Unverified)),
+		"Recent":     recent,
+	})
+}
+
+// decide approves or rejects a request. Approving sends the invite; if
+// that fails the request is marked failed and stays in the queue.
+func (h *Handler) decide(ctx context.Context, admin, id, action, note string) error {
+	req, ok := h.Store.Get(id)
+	if !ok || (req.State != Pending && req.State != Failed) {
+		return fmt.Errorf("request %s is not waiting for a decision", id)
+	}
+	state := Rejected
+	if action == "approve" {
+		state = Invited
+		if err := h.Client.Invite(ctx, req.Email, req.Name); err != nil {
+			state, note = Failed, err.Error()
+		}
+	}
+	_, err := h.Store.Update(id, func(r *Request) {
+		r.State, r.Note, r.Admin, r.Decided = state, note, admin, time.Now()
+	})
+	return err
+}
+
+func (h *Handler) render(w http.ResponseWriter, status int, name string, data map[string]any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := pages.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("slackinvite: %v", err)
+	}
+}
+
+var pages = template.Must(template.New("").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <meta name="robots" content="noindex">
+  <link href="/css/bootstrap.min.css" rel="stylesheet">
+  <link href="/css/hc.css" rel="stylesheet">
+</head>
+<body>
+  <div class="container">
+    <div id="article-body">
+      <div class="article-title">{{.Title}}</div>
+{{end}}
+
+{{define "foot"}}
+    </div>
+  </div>
+</body>
+</html>
+{{end}}
+
+{{define "message"}}{{template "head" .}}
+      <p>{{.Message}}</p>
+      <p><a href="/">Back to the home page</a></p>
+{{template "foot" .}}{{end}}
+
+{{define "form"}}{{template "head" .}}
+      <p>The Gophers Slack is open to everyone who follows the
+      <a href="{{.CodeOfConduct}}">code of conduct</a>. Tell us who you are and
+      we will send you an invite once you have confirmed your address.</p>
+      {{- with .Problem}}
+      <p class="alert alert-warning">{{.}}</p>
+      {{- end}}
+      <form id="slack-invite" method="post" action="/slack/" data-bits="{{.Bits}}">
+        <input type="text" name="name" class="form-control" placeholder="Your name" required>
+        <input type="email" name="email" class="form-control" placeholder="you@example.com" required>
+        <label><input type="checkbox" name="conduct" value="yes" required> I will follow the code of conduct</label>
+        <input type="hidden" name="challenge" value="{{.Challenge}}">
+        <input type="hidden" name="counter" value="">
+        <button class="btn btn-default" type="submit">Request an invite</button>
+        <span class="slack-invite-status"></span>
+      </form>
+      <script src="/js/slack-invite.js"></script>
+{{template "foot" .}}{{end}}
+
+{{define "admin"}}{{template "head" .}}
+      <p class="meta">Signed in as {{.Admin}}. {{.Unverified}} requests are waiting for their address to be confirmed.</p>
+      {{- if .Queue}}
+      <table class="table">
+        <tr><th>Requested</th><th>Name</th><th>Email</th><th></th></tr>
+        {{- range .Queue}}
+        <tr>
+          <td>{{.Created.Format "2006-01-02 15:04"}}</td>
+          <td>{{.Name}}</td>
+          <td>{{.Email}}{{if eq .State "failed"}}<br><small>invite failed: {{.Note}}</small>{{end}}</td>
+          <td>
+            <form method="post" class="form-inline">
+              <input type="hidden" name="csrf" value="{{$.CSRF}}">
+              <input type="hidden" name="id" value="{{.ID}}">
+              <input type="text" name="note" class="form-control input-sm" placeholder="Reason, if rejecting">
+              <button class="btn btn-sm btn-primary" name="action" value="approve">Approve</button>
+              <button class="btn btn-sm btn-default" name="action" value="reject">Reject</button>
+            </form>
+          </td>
+        </tr>
+        {{- end}}
+      </table>
+      {{- else}}
+      <p>No requests are waiting.</p>
+      {{- end}}
+      {{- if .Recent}}
+      <h3>Recent decisions</h3>
+      <ul>
+        {{- range .Recent}}
+        <li>{{.Decided.Format "2006-01-02"}}: {{.Name}} &lt;{{.Email}}&gt; {{.State}} by {{.Admin}}{{with .Note}} ({{.}}){{end}}</li>
+        {{- end}}
+      </ul>
+      {{- end}}
+{{template "foot" .}}{{end}}
+`))
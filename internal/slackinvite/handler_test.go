@@ -0,0 +1,262 @@
+package slackinvite
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/adminauth"
+)
+
+// mailbox is a Mailer keeping the mails it is given.
+type mailbox struct {
+	to    []string
+	texts []string
+}
+
+func (m *mailbox) Send(to, subject string, text []byte) error {
+	m.to = append(m.to, to)
+	m.texts = append(m.texts, string(text))
+	return nil
+}
+
+func newTestHandler(t *testing.T) (*Handler, *mailbox, *Fake) {
+	store, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := adminauth.Hash("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mb, client := &mailbox{}, &Fake{}
+	h := NewHandler(store, client, mb, []byte("test key"), "https://gopheracademy.com/", map[string]string{"rob": hash})
+	// A few bits keep the search below quick.
+	h.Challenge.Bits = 4
+	return h, mb, client
+}
+
+// solve returns a counter proving the work of challenge.
+func solve(challenge string, bits int) string {
+	for i := 0; ; i++ {
+		c := strconv.Itoa(i)
+		if leadingZeros(sha256.Sum256([]byte(challenge+":"+c))) >= bits {
+			return c
+		}
+	}
+}
+
+func post(h *Handler, path string, form url.Values, admin string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if admin != "" {
+		r.RemoteAddr = "127.0.0.1:1234"
+		r.SetBasicAuth(admin, "hunter2")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func get(h *Handler, path string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+	return w
+}
+
+// getAdmin requests the admin queue from remote, with X-Real-IP set to
+// realIP unless it is empty, as rob with password.
+func getAdmin(h *Handler, remote, realIP, password string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest("GET", Prefix+"admin/", nil)
+	r.RemoteAddr = remote
+	if realIP != "" {
+		r.Header.Set("X-Real-IP", realIP)
+	}
+	if password != "" {
+		r.SetBasicAuth("rob", password)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func requestForm(name, email, challenge, counter string) url.Values {
+	return url.Values{"name": {name}, "email": {email}, "conduct": {"yes"}, "challenge": {challenge}, "counter": {counter}}
+}
+
+func TestProofOfWork(t *testing.T) {
+	h, mb, _ := newTestHandler(t)
+	h.Limiter.N = 100
+	now := time.Now()
+	// With a few bits, an empty counter solves one challenge in
+	// sixteen; take one it does not.
+	c := h.Challenge.New(now)
+	for leadingZeros(sha256.Sum256([]byte(c+":"))) >= h.Challenge.Bits {
+		c = h.Challenge.New(now)
+	}
+	good := solve(c, h.Challenge.Bits)
+	bad := "x"
+	for leadingZeros(sha256.Sum256([]byte(c+":"+bad))) >= h.Challenge.Bits {
+		bad += "x"
+	}
+	stale := h.Challenge.New(now.Add(-time.Hour))
+	tests := []struct {
+		name               string
+		challenge, counter string
+		status             int
+	}{
+		{"no counter", c, "", http.StatusBadRequest},
+		{"wrong counter", c, bad, http.StatusBadRequest},
+		{"forged", strings.Replace(c, ".", "0.", 1), good, http.StatusBadRequest},
+		{"expired", stale, solve(stale, h.Challenge.Bits), http.StatusBadRequest},
+		{"solved", c, good, http.StatusOK},
+		{"reused", c, good, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		w := post(h, Prefix, requestForm("Ada Lovelace", "ada@example.com", tt.challenge, tt.counter), "")
+		if w.Code != tt.status {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, tt.status)
+		}
+	}
+	if len(mb.to) != 1 || mb.to[0] != "ada@example.com" {
+		t.Errorf("mailed %v, want ada@example.com once", mb.to)
+	}
+	if got := h.Store.List(Unverified); len(got) != 1 {
+		t.Errorf("%d unverified requests, want 1", len(got))
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	h, _, _ := newTestHandler(t)
+	h.Limiter.N = 2
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		c := h.Challenge.New(time.Now())
+		w := post(h, Prefix, requestForm("Ada Lovelace", "ada@example.com", c, solve(c, h.Challenge.Bits)), "")
+		if w.Code != want {
+			t.Errorf("request %d: status %d, want %d", i+1, w.Code, want)
+		}
+	}
+}
+
+var linkRE = regexp.MustCompile(`https://gopheracademy\.com/slack/verify\?t=(\S+)`)
+
+// verified sends the form for name and email and follows the link
+// mailed back, returning the request.
+func verified(t *testing.T, h *Handler, mb *mailbox, name, email string) Request {
+	t.Helper()
+	c := h.Challenge.New(time.Now())
+	if w := post(h, Prefix, requestForm(name, email, c, solve(c, h.Challenge.Bits)), ""); w.Code != http.StatusOK {
+		t.Fatalf("request for %s: status %d", email, w.Code)
+	}
+	m := linkRE.FindStringSubmatch(mb.texts[len(mb.texts)-1])
+	if m == nil {
+		t.Fatalf("no verification link in %q", mb.texts[len(mb.texts)-1])
+	}
+	if w := get(h, Prefix+"verify?t="+m[1]); w.Code != http.StatusOK {
+		t.Fatalf("verifying %s: status %d", email, w.Code)
+	}
+	for _, r := range h.Store.List(Pending) {
+		if r.Email == email {
+			return r
+		}
+	}
+	t.Fatalf("%s not pending after verification", email)
+	return Request{}
+}
+
+func TestVerify(t *testing.T) {
+	h, mb, _ := newTestHandler(t)
+	if w := get(h, Prefix+"verify?t=forged"); w.Code != http.StatusBadRequest {
+		t.Errorf("forged link: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	expired := h.Signer.Token(verifyAction, "ada@example.com", "0123", time.Now().Add(-time.Minute))
+	if w := get(h, Prefix+"verify?t="+expired); w.Code != http.StatusBadRequest {
+		t.Errorf("expired link: status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	unknown := h.Signer.Token(verifyAction, "ada@example.com", "0123", time.Now().Add(time.Hour))
+	if w := get(h, Prefix+"verify?t="+unknown); w.Code != http.StatusNotFound {
+		t.Errorf("link of an unknown request: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	r := verified(t, h, mb, "Ada Lovelace", "ada@example.com")
+	if r.Verified.IsZero() {
+		t.Error("verification time not recorded")
+	}
+	if got := h.Store.List(Unverified); len(got) != 0 {
+		t.Errorf("unverified after verification: %v", got)
+	}
+}
+
+func TestAdminApproval(t *testing.T) {
+	h, mb, client := newTestHandler(t)
+	ada := verified(t, h, mb, "Ada Lovelace", "ada@example.com")
+	bob := verified(t, h, mb, "Bob Spammer", "bob@example.com")
+	csrf := h.csrf("rob")
+
+	// The password is never taken over plain HTTP from elsewhere,
+	// including through nginx.
+	if w := getAdmin(h, "192.0.2.1:1234", "", "hunter2"); w.Code != http.StatusForbidden {
+		t.Errorf("admin queue from a remote address: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := getAdmin(h, "127.0.0.1:1234", "192.0.2.1", "hunter2"); w.Code != http.StatusForbidden {
+		t.Errorf("admin queue through nginx: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if w := getAdmin(h, "127.0.0.1:1234", "", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("admin queue without credentials: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if w := getAdmin(h, "127.0.0.1:1234", "", "wrong"); w.Code != http.StatusUnauthorized {
+		t.Errorf("admin queue with a wrong password: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	w := getAdmin(h, "127.0.0.1:1234", "", "hunter2")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ada@example.com") || !strings.Contains(w.Body.String(), csrf) {
+		t.Errorf("admin queue: status %d, body %s", w.Code, w.Body)
+	}
+
+	approve := url.Values{"csrf": {"forged"}, "id": {ada.ID}, "action": {"approve"}}
+	if w := post(h, Prefix+"admin/", approve, "rob"); w.Code != http.StatusForbidden {
+		t.Errorf("approval with a forged token: status %d, want %d", w.Code, http.StatusForbidden)
+	}
+	if len(client.Invited) > 0 {
+		t.Fatalf("invited %v without a valid token", client.Invited)
+	}
+
+	// An invite that fails leaves the request in the queue with the
+	// error; approving it again sends it.
+	client.Err = errors.New("slack: ratelimited")
+	approve.Set("csrf", csrf)
+	if w := post(h, Prefix+"admin/", approve, "rob"); w.Code != http.StatusSeeOther {
+		t.Errorf("approval: status %d, want %d", w.Code, http.StatusSeeOther)
+	}
+	if got, _ := h.Store.Get(ada.ID); got.State != Failed || got.Note != "slack: ratelimited" {
+		t.Errorf("after a failed invite: state %s, note %q", got.State, got.Note)
+	}
+	client.Err = nil
+	post(h, Prefix+"admin/", approve, "rob")
+	if got, _ := h.Store.Get(ada.ID); got.State != Invited || got.Admin != "rob" {
+		t.Errorf("after approval: state %s by %q, want invited by rob", got.State, got.Admin)
+	}
+	if len(client.Invited) != 1 || client.Invited[0] != "ada@example.com" {
+		t.Errorf("invited %v, want ada@example.com", client.Invited)
+	}
+
+	reject := url.Values{"csrf": {csrf}, "id": {bob.ID}, "action": {"reject"}, "note": {"spam"}}
+	post(h, Prefix+"admin/", reject, "rob")
+	if got, _ := h.Store.Get(bob.ID); got.State != Rejected || got.Note != "spam" {
+		t.Errorf("after rejection: state %s, note %q", got.State, got.Note)
+	}
+	// Decided requests cannot be decided again.
+	reject.Set("id", ada.ID)
+	post(h, Prefix+"admin/", reject, "rob")
+	if got, _ := h.Store.Get(ada.ID); got.State != Invited {
+		t.Errorf("invited request rejected afterwards: state %s", got.State)
+	}
+	if len(client.Invited) != 1 {
+		t.Errorf("invited %v, want one invite", client.Invited)
+	}
+}
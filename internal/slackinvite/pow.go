@@ -0,0 +1,148 @@
+package slackinvite
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/bits"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrWork is returned for missing, forged, stale, reused or
+// insufficient proofs of work.
+var ErrWork = errors.New("slackinvite: invalid proof of work")
+
+// Challenge issues and checks proofs of work. The form is served with a
+// challenge, "<unix time>.<nonce>.<mac>", and the browser searches for a
+// counter such that the SHA-256 of "<challenge>:<counter>" starts with
+// Bits zero bits, which takes it a moment but costs a bot sending many
+// requests much more. Challenges are signed, so the server keeps no
+// state for them until one is used, and expire after TTL.
+type Challenge struct {
+	Key  []byte
+	Bits int
+	TTL  time.Duration
+
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// New returns a fresh challenge.
+func (c *Challenge) New(now time.Time) string {
+	var nonce [12]byte
+	rand.Read(nonce[:])
+	s := strconv.FormatInt(now.Unix(), 10) + "." + hex.EncodeToString(nonce[:])
+	return s + "." + hex.EncodeToString(c.mac(s))
+}
+
+func (c *Challenge) mac(s string) []byte {
+	m := hmac.New(sha256.New, c.Key)
+	m.Write([]byte(s))
+	return m.Sum(nil)
+}
+
+// Verify checks a solved challenge and marks it used.
+func (c *Challenge) Verify(challenge, counter string, now time.Time) error {
+	i := strings.LastIndex(challenge, ".")
+	if i < 0 {
+		return ErrWork
+	}
+	mac, err := hex.DecodeString(challenge[i+1:])
+	if err != nil || !hmac.Equal(mac, c.mac(challenge[:i])) {
+		return ErrWork
+	}
+	ts, _, _ := strings.Cut(challenge, ".")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || now.Sub(time.Unix(sec, 0)) > c.TTL {
+		return ErrWork
+	}
+	if leadingZeros(sha256.Sum256([]byte(challenge+":"+counter))) < c.Bits {
+		return ErrWork
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.used == nil {
+		c.used = make(map[string]time.Time)
+	}
+	for k, t := range c.used {
+		if now.Sub(t) > c.TTL {
+			delete(c.used, k)
+		}
+	}
+	if _, ok := c.used[challenge]; ok {
+		return ErrWork
+	}
+	c.used[challenge] = now
+	return nil
+}
+
+func leadingZeros(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+// Limiter allows each client at most N requests per Window.
+type Limiter struct {
+	N      int
+	Window time.Duration
+
+	mu   sync.Mutex
+	hits map[string][]time.Time
+}
+
+// Allow records a request from client and reports whether it is within
+// the limit.
+func (l *Limiter) Allow(client string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.hits == nil {
+		l.hits = make(map[string][]time.Time)
+	}
+	for k, ts := range l.hits {
+		var recent []time.Time
+		for _, t := range ts {
+			if now.Sub(t) < l.Window {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			delete(l.hits, k)
+		} else {
+			l.hits[k] = recent
+		}
+	}
+	if len(l.hits[client]) >= l.N {
+		return false
+	}
+	l.hits[client] = append(l.hits[client], now)
+	return true
+}
+
+// ClientIP returns the address of the client that made r: the X-Real-IP
+// nginx sets when the request comes through it, or else the peer
+// address.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		if real := r.Header.Get("X-Real-IP"); real != "" {
+			return real
+		}
+	}
+	return host
+}
@@ -0,0 +1,28 @@
+package slackinvite
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	l := &Limiter{N: 2, Window: time.Minute}
+	now := time.Now()
+	for i, want := range []bool{true, true, false} {
+		if got := l.Allow("192.0.2.1", now); got != want {
+			t.Errorf("request %d: Allow = %v, want %v", i+1, got, want)
+		}
+	}
+	if !l.Allow("192.0.2.2", now) {
+		t.Error("another client limited")
+	}
+	if !l.Allow("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("client still limited after the window")
+	}
+
+	// Clients that have gone quiet are forgotten when anyone is checked.
+	l.Allow("192.0.2.3", now.Add(3*time.Minute))
+	if len(l.hits) != 1 {
+		t.Errorf("after the window, hits kept for %d clients, want 1", len(l.hits))
+	}
+}
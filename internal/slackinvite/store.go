@@ -0,0 +1,192 @@
+// Package slackinvite takes requests to join the Gophers Slack and
+// queues them for the community's administrators.
+//
+// A request goes through three steps. The form asks for a name and an
+// email address and, instead of a CAPTCHA, for a small proof of work
+// that the browser computes (see Challenge), on top of a per-address
+// rate limit. The address is then verified with a signed link mailed to
+// it. Verified requests wait in the admin queue, where an administrator
+// approves them, which sends the invite through the chat platform's
+// Client, or rejects them.
+package slackinvite
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StoreFile is the name of the request list in its directory.
+const StoreFile = "requests.json"
+
+// Request states.
+const (
+	// Unverified requests wait for the address to be verified.
+	Unverified = "unverified"
+	// Pending requests wait for an administrator.
+	Pending  = "pending"
+	Invited  = "invited"
+	Rejected = "rejected"
+	// Failed requests were approved but the invite could not be sent;
+	// they stay in the queue.
+	Failed = "failed"
+)
+
+// Request is a request to join.
+type Request struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Email   string    `json:"email"`
+	State   string    `json:"state"`
+	Created time.Time `json:"created"`
+	// Verified is when the address was verified.
+	Verified time.Time `json:"verified,omitzero"`
+	// Decided is when, and Admin who, approved or rejected it.
+	Decided time.Time `json:"decided,omitzero"`
+	Admin   string    `json:"admin,omitempty"`
+	// Note is the reason for a rejection, or the error of a failed
+	// invite.
+	Note string `json:"note,omitempty"`
+}
+
+// Store is the list of requests. It is safe for concurrent use.
+type Store struct {
+	Dir string
+
+	mu   sync.Mutex
+	reqs map[string]*Request
+}
+
+// Open reads the requests in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{Dir: dir, reqs: make(map[string]*Request)}
+	b, err := os.ReadFile(filepath.Join(dir, StoreFile))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Request
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("%s: %v", StoreFile, err)
+	}
+	for _, r := range list {
+		s.reqs[r.ID] = r
+	}
+	return s, nil
+}
+
+// save writes the requests, replacing the old list atomically. s.mu is
+// held.
+func (s *Store) save() error {
+	b, err := json.MarshalIndent(s.list(), "", "\t")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(s.Dir, StoreFile)
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// list returns copies of the requests, oldest first. s.mu is held.
+func (s *Store) list() []Request {
+	out := make([]Request, 0, len(s.reqs))
+	for _, r := range s.reqs {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out
+}
+
+// List returns the requests in one of the states, or all of them if no
+// state is given, oldest first.
+func (s *Store) List(states ...string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Request
+	for _, r := range s.list() {
+		if len(states) == 0 || contains(states, r.State) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func contains(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Get returns the request with the given id.
+func (s *Store) Get(id string) (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reqs[id]
+	if !ok {
+		return Request{}, false
+	}
+	return *r, true
+}
+
+// Add records a new unverified request. If the address already has a
+// request that is not rejected, Add returns that one instead, with
+// false.
+func (s *Store) Add(name, email string, now time.Time) (Request, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.reqs {
+		if strings.EqualFold(r.Email, email) && r.State != Rejected {
+			return *r, false, nil
+		}
+	}
+	var id [8]byte
+	rand.Read(id[:])
+	r := &Request{ID: hex.EncodeToString(id[:]), Name: name, Email: email, State: Unverified, Created: now}
+	s.reqs[r.ID] = r
+	return *r, true, s.save()
+}
+
+// Update applies f to the request with the given id and saves the
+// result. It returns false if there is no such request.
+func (s *Store) Update(id string, f func(*Request)) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.reqs[id]
+	if !ok {
+		return false, nil
+	}
+	f(r)
+	return true, s.save()
+}
+
+// Expire removes the requests left unverified for longer than age.
+func (s *Store) Expire(now time.Time, age time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, r := range s.reqs {
+		if r.State == Unverified && now.Sub(r.Created) > age {
+			delete(s.reqs, id)
+			n++
+		}
+	}
+	return n, s.save()
+}
@@ -0,0 +1,188 @@
+// Package smtpsink is an SMTP server that keeps the mails it is sent
+// instead of delivering them.
+//
+// It speaks just enough SMTP for net/smtp, without TLS or
+// authentication, and listens on the loopback interface, so that a
+// command can be pointed at it to see what it would mail, and tests can
+// check the mails a mailer sends. Tests can also script the replies to
+// RCPT TO, to see how a mailer handles bounces and servers that hang up.
+package smtpsink
+
+import (
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+)
+
+// Message is a mail the sink accepted.
+type Message struct {
+	From string
+	To   []string
+	// Data is the message as sent, headers and body, with the
+	// dot-stuffing undone and LF line endings.
+	Data []byte
+	// Conn numbers the connection the message came over, from 0 in the
+	// order the connections were made.
+	Conn int
+}
+
+// Sink is a running SMTP server.
+type Sink struct {
+	ln net.Listener
+	wg sync.WaitGroup
+
+	mu      sync.Mutex
+	msgs    []Message
+	conns   int
+	replies map[string][]string
+}
+
+// Start starts a sink on a free loopback port.
+func Start() (*Sink, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Sink{ln: ln, replies: make(map[string][]string)}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			n := s.conns
+			s.conns++
+			s.mu.Unlock()
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.serve(c, n)
+			}()
+		}
+	}()
+	return s, nil
+}
+
+// Addr returns the host:port the sink listens on.
+func (s *Sink) Addr() string {
+	return s.ln.Addr().String()
+}
+
+// Messages returns the messages accepted so far, oldest first.
+func (s *Sink) Messages() []Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Message(nil), s.msgs...)
+}
+
+// Connections returns the number of connections made to the sink.
+func (s *Sink) Connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+// Reply scripts the replies to the next RCPT TO commands naming addr,
+// which are used up in order; once they run out, addr is accepted
+// again. A reply other than 2xx rejects the recipient, and a 421 reply
+// closes the connection after it is sent, as a server shutting down
+// does.
+func (s *Sink) Reply(addr string, replies ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies[addr] = append(s.replies[addr], replies...)
+}
+
+// Close stops the sink and waits for the connections to it to end.
+func (s *Sink) Close() error {
+	err := s.ln.Close()
+	s.wg.Wait()
+	return err
+}
+
+// path returns the address of a MAIL FROM or RCPT TO argument.
+func path(arg, prefix string) (string, bool) {
+	if len(arg) < len(prefix) || !strings.EqualFold(arg[:len(prefix)], prefix) {
+		return "", false
+	}
+	addr, _, _ := strings.Cut(strings.TrimSpace(arg[len(prefix):]), " ")
+	return strings.Trim(addr, "<>"), true
+}
+
+func (s *Sink) serve(c net.Conn, conn int) {
+	defer c.Close()
+	tc := textproto.NewConn(c)
+	tc.PrintfLine("220 smtpsink ESMTP")
+	var m Message
+	for {
+		line, err := tc.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "HELO":
+			tc.PrintfLine("250 smtpsink")
+		case "EHLO":
+			tc.PrintfLine("250-smtpsink")
+			tc.PrintfLine("250 8BITMIME")
+		case "MAIL":
+			from, ok := path(arg, "FROM:")
+			if !ok {
+				tc.PrintfLine("501 syntax: MAIL FROM:<address>")
+				continue
+			}
+			m = Message{From: from, Conn: conn}
+			tc.PrintfLine("250 ok")
+		case "RCPT":
+			to, ok := path(arg, "TO:")
+			if !ok || to == "" {
+				tc.PrintfLine("501 syntax: RCPT TO:<address>")
+				continue
+			}
+			s.mu.Lock()
+			reply := "250 ok"
+			if q := s.replies[to]; len(q) > 0 {
+				reply, s.replies[to] = q[0], q[1:]
+			}
+			s.mu.Unlock()
+			tc.PrintfLine("%s", reply)
+			if strings.HasPrefix(reply, "421") {
+				return
+			}
+			if strings.HasPrefix(reply, "2") {
+				m.To = append(m.To, to)
+			}
+		case "DATA":
+			if len(m.To) == 0 {
+				tc.PrintfLine("503 no recipients")
+				continue
+			}
+			tc.PrintfLine("354 end with a line holding a single dot")
+			data, err := tc.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			m.Data = data
+			s.mu.Lock()
+			s.msgs = append(s.msgs, m)
+			s.mu.Unlock()
+			m = Message{Conn: conn}
+			tc.PrintfLine("250 kept")
+		case "RSET":
+			m = Message{Conn: conn}
+			tc.PrintfLine("250 ok")
+		case "NOOP":
+			tc.PrintfLine("250 ok")
+		case "QUIT":
+			tc.PrintfLine("221 bye")
+			return
+		default:
+			tc.PrintfLine("502 unknown command")
+		}
+	}
+}
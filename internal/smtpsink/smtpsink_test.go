@@ -0,0 +1,48 @@
+package smtpsink
+
+import (
+	"errors"
+	"net/smtp"
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+func TestSink(t *testing.T) {
+	s, err := Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Reply("gone@example.com", "550 5.1.1 no such user")
+	s.Reply("busy@example.com", "421 4.7.0 try again later")
+
+	msg := []byte("Subject: hi\r\n\r\n.leading dot\r\nbye\r\n")
+	if err := smtp.SendMail(s.Addr(), nil, "from@example.com", []string{"a@example.com", "b@example.com"}, msg); err != nil {
+		t.Fatal(err)
+	}
+	var te *textproto.Error
+	err = smtp.SendMail(s.Addr(), nil, "from@example.com", []string{"gone@example.com"}, msg)
+	if !errors.As(err, &te) || te.Code != 550 {
+		t.Errorf("scripted 550: %v", err)
+	}
+	err = smtp.SendMail(s.Addr(), nil, "from@example.com", []string{"busy@example.com"}, msg)
+	if !errors.As(err, &te) || te.Code != 421 {
+		t.Errorf("scripted 421: %v", err)
+	}
+	// The script is used up.
+	if err := smtp.SendMail(s.Addr(), nil, "from@example.com", []string{"gone@example.com"}, msg); err != nil {
+		t.Errorf("after the scripted reply: %v", err)
+	}
+
+	want := []Message{
+		{From: "from@example.com", To: []string{"a@example.com", "b@example.com"}, Data: []byte("Subject: hi\n\n.leading dot\nbye\n"), Conn: 0},
+		{From: "from@example.com", To: []string{"gone@example.com"}, Data: []byte("Subject: hi\n\n.leading dot\nbye\n"), Conn: 3},
+	}
+	if got := s.Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages = %+v, want %+v", got, want)
+	}
+	if n := s.Connections(); n != 4 {
+		t.Errorf("Connections = %d, want 4", n)
+	}
+}
@@ -0,0 +1,231 @@
+package subscribe
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+	"github.com/gopheracademy/gopheracademy-web/internal/newsletter"
+)
+
+// Prefix is the URL path the handler is served under; the footer form
+// posts to it.
+const Prefix = newsletter.SubscribePath
+
+// Mailer sends the confirmation mails.
+type Mailer interface {
+	Send(to, subject string, text []byte) error
+}
+
+// SMTP is a Mailer sending through an SMTP server.
+type SMTP struct {
+	Addr string
+	From string
+	Auth smtp.Auth
+}
+
+// Send mails a plain-text message.
+func (m *SMTP) Send(to, subject string, text []byte) error {
+	from := m.From
+	if a, err := mail.ParseAddress(m.From); err == nil {
+		from = a.Address
+	}
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\n", m.From, to, mime.QEncoding.Encode("utf-8", subject), time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.Write(bytes.ReplaceAll(text, []byte("\n"), []byte("\r\n")))
+	return smtp.SendMail(m.Addr, m.Auth, from, []string{to}, msg.Bytes())
+}
+
+// Handler serves the signup form's endpoint, the confirmation links and
+// the unsubscribe links.
+type Handler struct {
+	Store  *Store
+	Signer Signer
+	Mailer Mailer
+	// Base is the site URL links are made under.
+	Base string
+	// Scopes maps each scope that can be chosen to its description.
+	Scopes map[string]string
+	// Wait is the least time between two confirmation mails to an
+	// address, and TTL how long a confirmation link stays valid.
+	Wait, TTL time.Duration
+}
+
+// NewHandler returns a Handler offering every article, the main site's
+// events, and each series of lib.
+func NewHandler(store *Store, signer Signer, mailer Mailer, base string, lib *content.Library) *Handler {
+	scopes := map[string]string{
+		All:    "all new articles",
+		Events: "Gopher Academy events",
+	}
+	for _, s := range lib.Series {
+		scopes[SeriesScope(s.Slug)] = "new articles in " + s.Name
+	}
+	return &Handler{
+		Store:  store,
+		Signer: signer,
+		Mailer: mailer,
+		Base:   strings.TrimSuffix(base, "/"),
+		Scopes: scopes,
+		Wait:   10 * time.Minute,
+		TTL:    48 * time.Hour,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case Prefix:
+		if r.Method == "POST" {
+			h.subscribe(w, r)
+			return
+		}
+		h.page(w, http.StatusOK, "Subscribe", "Get new articles by email. You can unsubscribe at any time with the link at the bottom of every mail.", "", true)
+	case Prefix + "confirm":
+		h.confirm(w, r)
+	case Prefix + "unsubscribe":
+		h.unsubscribe(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) subscribe(w http.ResponseWriter, r *http.Request) {
+	addr, err := mail.ParseAddress(r.FormValue("email"))
+	scope := r.FormValue("scope")
+	if scope == "" {
+		scope = All
+	}
+	if _, ok := h.Scopes[scope]; err != nil || !ok {
+		h.page(w, http.StatusBadRequest, "Subscribe", "Please enter a valid email address.", "", true)
+		return
+	}
+	sent := "We have sent a confirmation link to " + addr.Address + ". Follow it to start receiving " + h.Scopes[scope] + "."
+	// The hidden field is left empty by people and filled in by bots.
+	if r.FormValue("website") != "" {
+		h.page(w, http.StatusOK, "Check your inbox", sent, "", false)
+		return
+	}
+	now := time.Now()
+	if _, err := h.Store.Expire(now, h.TTL); err != nil {
+		log.Printf("subscribe: %v", err)
+	}
+	send, err := h.Store.Request(addr.Address, scope, now, h.Wait)
+	if err != nil {
+		log.Printf("subscribe: %v", err)
+		http.Error(w, "Subscription failed; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if send {
+		link := h.Base + Prefix + "confirm?t=" + h.Signer.Token(ConfirmAction, addr.Address, scope, now.Add(h.TTL))
+		text := fmt.Sprintf("Someone, hopefully you, asked to receive %s from Gopher Academy at this address.\n\n"+
+			"To confirm, follow this link within %d hours:\n\n%s\n\nIf it was not you, ignore this mail and you will not hear from us again.\n",
+			h.Scopes[scope], int(h.TTL.Hours()), link)
+		if err := h.Mailer.Send(addr.Address, "Confirm your Gopher Academy subscription", []byte(text)); err != nil {
+			log.Printf("subscribe: mailing %s: %v", addr.Address, err)
+			http.Error(w, "Subscription failed; please try again later.", http.StatusInternalServerError)
+			return
+		}
+	}
+	// The answer is the same whether or not the address was already
+	// subscribed, so the form does not reveal who is.
+	h.page(w, http.StatusOK, "Check your inbox", sent, "", false)
+}
+
+func (h *Handler) confirm(w http.ResponseWriter, r *http.Request) {
+	email, scope, err := h.Signer.Verify(ConfirmAction, r.FormValue("t"), time.Now())
+	if err != nil {
+		h.page(w, http.StatusBadRequest, "Link expired", "This confirmation link is invalid or has expired. Please sign up again.", "", true)
+		return
+	}
+	ok, err := h.Store.Confirm(email, scope, time.Now())
+	if err != nil {
+		log.Printf("subscribe: %v", err)
+		http.Error(w, "Confirmation failed; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		h.page(w, http.StatusNotFound, "Link expired", "This subscription request has expired. Please sign up again.", "", true)
+		return
+	}
+	h.page(w, http.StatusOK, "Subscribed", "You will receive "+h.Scopes[scope]+" at "+email+".", "", false)
+}
+
+// unsubscribe shows a button on GET, so that link scanners following the
+// link do not unsubscribe anyone, and unsubscribes on POST, including
+// the one-click POST mail clients send.
+func (h *Handler) unsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.FormValue("t")
+	email, scope, err := h.Signer.Verify(UnsubscribeAction, token, time.Now())
+	if err != nil {
+		h.page(w, http.StatusBadRequest, "Invalid link", "This unsubscribe link is invalid.", "", false)
+		return
+	}
+	if r.Method != "POST" {
+		h.page(w, http.StatusOK, "Unsubscribe", "Stop sending "+h.Scopes[scope]+" to "+email+"?", token, false)
+		return
+	}
+	if err := h.Store.Unsubscribe(email, scope); err != nil {
+		log.Printf("subscribe: %v", err)
+		http.Error(w, "Unsubscribing failed; please try again later.", http.StatusInternalServerError)
+		return
+	}
+	h.page(w, http.StatusOK, "Unsubscribed", email+" will no longer receive "+h.Scopes[scope]+".", "", false)
+}
+
+func (h *Handler) page(w http.ResponseWriter, status int, title, message, unsubscribe string, form bool) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	page.Execute(w, struct {
+		Title, Message, Unsubscribe string
+		Form                        bool
+		Scopes                      map[string]string
+	}{title, message, unsubscribe, form, h.Scopes})
+}
+
+var page = template.Must(template.New("subscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>{{.Title}}</title>
+  <meta name="robots" content="noindex">
+  <link href="/css/bootstrap.min.css" rel="stylesheet">
+  <link href="/css/hc.css" rel="stylesheet">
+</head>
+<body>
+  <div class="container">
+    <div id="article-body">
+      <div class="article-title">{{.Title}}</div>
+      <p>{{.Message}}</p>
+      {{- if .Unsubscribe}}
+      <form method="post" action="unsubscribe?t={{.Unsubscribe}}">
+        <button class="btn btn-default" type="submit">Unsubscribe</button>
+      </form>
+      {{- end}}
+      {{- if .Form}}
+      <form class="subscribe-form" method="post" action="/subscribe/">
+        <input type="email" name="email" class="form-control" placeholder="you@example.com" required>
+        <select name="scope" class="form-control">
+          {{- range $scope, $desc := .Scopes}}
+          <option value="{{$scope}}"{{if eq $scope "all"}} selected{{end}}>{{$desc}}</option>
+          {{- end}}
+        </select>
+        <input type="text" name="website" class="subscribe-website" tabindex="-1" autocomplete="off">
+        <button class="btn btn-default" type="submit">Subscribe</button>
+      </form>
+      {{- end}}
+      <p><a href="/">Back to the home page</a></p>
+    </div>
+  </div>
+</body>
+</html>
+`))
@@ -0,0 +1,141 @@
+package subscribe
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// mailbox is a Mailer keeping the mails it is given.
+type mailbox struct {
+	to    []string
+	texts []string
+}
+
+func (m *mailbox) Send(to, subject string, text []byte) error {
+	m.to = append(m.to, to)
+	m.texts = append(m.texts, string(text))
+	return nil
+}
+
+func newTestHandler(t *testing.T) (*Handler, *mailbox) {
+	mb := &mailbox{}
+	lib := &content.Library{Series: []*content.Series{{Name: "Advent 2014", Slug: "advent-2014"}}}
+	h := NewHandler(openStore(t), Signer{Key: []byte("test key")}, mb, "https://blog.gopheracademy.com/", lib)
+	return h, mb
+}
+
+func serve(h *Handler, method, target string, form url.Values) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+	if form != nil {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+var confirmRE = regexp.MustCompile(`https://blog\.gopheracademy\.com(/subscribe/confirm\?t=\S+)`)
+
+func TestSubscribe(t *testing.T) {
+	h, mb := newTestHandler(t)
+	form := url.Values{"email": {"Ada Lovelace <ada@example.com>"}, "scope": {"series/advent-2014"}}
+
+	if w := serve(h, "POST", Prefix, url.Values{"email": {"not an address"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("bad address: status %d", w.Code)
+	}
+	if w := serve(h, "POST", Prefix, url.Values{"email": {"ada@example.com"}, "scope": {"series/unknown"}}); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown scope: status %d", w.Code)
+	}
+	bot := url.Values{"email": {"bob@example.com"}, "website": {"http://spam.example.com/"}}
+	if w := serve(h, "POST", Prefix, bot); w.Code != http.StatusOK || len(mb.to) > 0 {
+		t.Errorf("honeypot filled: status %d, mailed %v", w.Code, mb.to)
+	}
+
+	// Signing up twice within the wait sends one mail, and answers the
+	// same both times.
+	first := serve(h, "POST", Prefix, form)
+	second := serve(h, "POST", Prefix, form)
+	if first.Code != http.StatusOK || first.Body.String() != second.Body.String() {
+		t.Errorf("signups: status %d, answers differ:\n%s\n%s", first.Code, first.Body, second.Body)
+	}
+	if len(mb.to) != 1 || mb.to[0] != "ada@example.com" {
+		t.Fatalf("mailed %v, want ada@example.com once", mb.to)
+	}
+	m := confirmRE.FindStringSubmatch(mb.texts[0])
+	if m == nil {
+		t.Fatalf("no confirmation link in %q", mb.texts[0])
+	}
+	if got := h.Store.Confirmed(SeriesScope("advent-2014")); len(got) != 0 {
+		t.Errorf("confirmed before following the link: %v", got)
+	}
+	if w := serve(h, "GET", m[1], nil); w.Code != http.StatusOK {
+		t.Errorf("confirm: status %d", w.Code)
+	}
+	if got := h.Store.Confirmed(SeriesScope("advent-2014")); len(got) != 1 {
+		t.Errorf("confirmed %v after following the link", got)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	h, _ := newTestHandler(t)
+	now := time.Now()
+	scope := SeriesScope("advent-2014")
+	h.Store.Request("ada@example.com", scope, now, h.Wait)
+	confirm := h.Signer.Token(ConfirmAction, "ada@example.com", scope, now.Add(h.TTL))
+	expired := h.Signer.Token(ConfirmAction, "ada@example.com", scope, now.Add(-time.Minute))
+	unsub := h.Signer.Token(UnsubscribeAction, "ada@example.com", scope, time.Time{})
+	forged := Signer{Key: []byte("other key")}.Token(ConfirmAction, "ada@example.com", scope, now.Add(h.TTL))
+
+	for _, tt := range []struct {
+		name, target string
+	}{
+		{"forged confirmation", Prefix + "confirm?t=" + forged},
+		{"expired confirmation", Prefix + "confirm?t=" + expired},
+		{"unsubscribe token used to confirm", Prefix + "confirm?t=" + unsub},
+		{"confirmation token used to unsubscribe", Prefix + "unsubscribe?t=" + confirm},
+	} {
+		if w := serve(h, "POST", tt.target, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", tt.name, w.Code, http.StatusBadRequest)
+		}
+	}
+	if got := h.Store.All(); len(got) != 1 || !got[0].Confirmed.IsZero() {
+		t.Fatalf("after bad links: %+v", got)
+	}
+
+	if w := serve(h, "GET", Prefix+"confirm?t="+confirm, nil); w.Code != http.StatusOK {
+		t.Fatalf("confirm: status %d", w.Code)
+	}
+
+	// Opening the unsubscribe link, as link scanners do, only shows the
+	// button.
+	w := serve(h, "GET", Prefix+"unsubscribe?t="+unsub, nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `action="unsubscribe?t=`+unsub+`"`) {
+		t.Errorf("unsubscribe page: status %d, body %s", w.Code, w.Body)
+	}
+	if got := h.Store.Confirmed(scope); len(got) != 1 {
+		t.Fatalf("GET of the unsubscribe link unsubscribed: %v", got)
+	}
+	// The one-click POST of RFC 8058 unsubscribes.
+	one := url.Values{"List-Unsubscribe": {"One-Click"}}
+	if w := serve(h, "POST", Prefix+"unsubscribe?t="+unsub, one); w.Code != http.StatusOK {
+		t.Errorf("unsubscribe: status %d", w.Code)
+	}
+	if got := h.Store.All(); len(got) != 0 {
+		t.Errorf("still subscribed after unsubscribing: %v", got)
+	}
+
+	// The confirmation link, still valid, does not resubscribe.
+	if w := serve(h, "GET", Prefix+"confirm?t="+confirm, nil); w.Code != http.StatusNotFound {
+		t.Errorf("confirm after unsubscribe: status %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if got := h.Store.All(); len(got) != 0 {
+		t.Errorf("confirm after unsubscribe resubscribed: %v", got)
+	}
+}
@@ -0,0 +1,235 @@
+// Package subscribe keeps the newsletter's subscribers and serves the
+// signup form's endpoint.
+//
+// Signing up is double opt-in: the form records a pending subscription
+// and mails a confirmation link, and only confirmed subscribers are
+// mailed issues. Confirmation and unsubscribe links carry a token signed
+// with a secret key, so the links work without storing per-subscriber
+// tokens and cannot be forged for someone else's address. Unsubscribe
+// links accept the one-click POST of RFC 8058, which mail clients send
+// for the List-Unsubscribe header.
+//
+// The subscriber list is personal data. It is kept outside the
+// repository, can be exported, and Delete removes an address entirely.
+package subscribe
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StoreFile is the name of the subscriber list in its directory.
+const StoreFile = "subscribers.json"
+
+// Scopes a subscription can have besides series.
+const (
+	// All is every new article of the blog.
+	All = "all"
+	// Events is the main site's event announcements.
+	Events = "events"
+	// SeriesPrefix starts the scope of one series, e.g.
+	// "series/advent-2014".
+	SeriesPrefix = "series/"
+)
+
+// SeriesScope returns the scope of the series with the given slug.
+func SeriesScope(slug string) string {
+	return SeriesPrefix + slug
+}
+
+// Subscriber is a subscription of an address to a scope.
+type Subscriber struct {
+	Email   string    `json:"email"`
+	Scope   string    `json:"scope"`
+	Created time.Time `json:"created"`
+	// Confirmed is zero until the address has followed the
+	// confirmation link.
+	Confirmed time.Time `json:"confirmed,omitzero"`
+	// Mailed is when the last confirmation mail was sent.
+	Mailed time.Time `json:"mailed,omitzero"`
+}
+
+// Store is the subscriber list. It is safe for concurrent use.
+type Store struct {
+	Dir string
+
+	mu   sync.Mutex
+	subs map[string]*Subscriber
+}
+
+func key(email, scope string) string {
+	return strings.ToLower(email) + " " + scope
+}
+
+// Open reads the subscriber list in dir, creating the directory if
+// needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	s := &Store{Dir: dir, subs: make(map[string]*Subscriber)}
+	b, err := os.ReadFile(filepath.Join(dir, StoreFile))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Subscriber
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("%s: %v", StoreFile, err)
+	}
+	for _, sub := range list {
+		s.subs[key(sub.Email, sub.Scope)] = sub
+	}
+	return s, nil
+}
+
+// save writes the list, replacing the old one atomically. s.mu is held.
+func (s *Store) save() error {
+	b, err := json.MarshalIndent(s.list(), "", "\t")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(s.Dir, StoreFile)
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, b, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+// list returns copies of the subscriptions sorted by address, then
+// scope. s.mu is held.
+func (s *Store) list() []Subscriber {
+	out := make([]Subscriber, 0, len(s.subs))
+	for _, sub := range s.subs {
+		out = append(out, *sub)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Email != out[j].Email {
+			return out[i].Email < out[j].Email
+		}
+		return out[i].Scope < out[j].Scope
+	})
+	return out
+}
+
+// All returns every subscription, confirmed or not.
+func (s *Store) All() []Subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list()
+}
+
+// Export writes every subscription as CSV, or only those of email if it
+// is not empty, as when someone asks for the data held about them.
+func (s *Store) Export(w io.Writer, email string) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"email", "scope", "created", "confirmed"})
+	for _, sub := range s.All() {
+		if email != "" && !strings.EqualFold(sub.Email, email) {
+			continue
+		}
+		confirmed := ""
+		if !sub.Confirmed.IsZero() {
+			confirmed = sub.Confirmed.UTC().Format(time.RFC3339)
+		}
+		cw.Write([]string{sub.Email, sub.Scope, sub.Created.UTC().Format(time.RFC3339), confirmed})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Confirmed returns the confirmed subscriptions to scope.
+func (s *Store) Confirmed(scope string) []Subscriber {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []Subscriber
+	for _, sub := range s.list() {
+		if sub.Scope == scope && !sub.Confirmed.IsZero() {
+			out = append(out, sub)
+		}
+	}
+	return out
+}
+
+// Request records a pending subscription, or finds the existing one, and
+// reports whether a confirmation mail should be sent: not for confirmed
+// subscriptions, nor within wait of the last one, so that the form
+// cannot be used to flood an inbox.
+func (s *Store) Request(email, scope string, now time.Time, wait time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := key(email, scope)
+	sub := s.subs[k]
+	if sub == nil {
+		sub = &Subscriber{Email: email, Scope: scope, Created: now}
+		s.subs[k] = sub
+	}
+	if !sub.Confirmed.IsZero() || now.Sub(sub.Mailed) < wait {
+		return false, s.save()
+	}
+	sub.Mailed = now
+	return true, s.save()
+}
+
+// Confirm confirms a pending subscription. It reports false if there is
+// no such subscription, as after an unsubscribe.
+func (s *Store) Confirm(email, scope string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sub := s.subs[key(email, scope)]
+	if sub == nil {
+		return false, nil
+	}
+	if sub.Confirmed.IsZero() {
+		sub.Confirmed = now
+	}
+	return true, s.save()
+}
+
+// Unsubscribe removes the subscription of email to scope.
+func (s *Store) Unsubscribe(email, scope string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subs, key(email, scope))
+	return s.save()
+}
+
+// Delete removes every subscription of email and returns how many there
+// were.
+func (s *Store) Delete(email string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for k, sub := range s.subs {
+		if strings.EqualFold(sub.Email, email) {
+			delete(s.subs, k)
+			n++
+		}
+	}
+	return n, s.save()
+}
+
+// Expire removes the subscriptions left unconfirmed for longer than age.
+func (s *Store) Expire(now time.Time, age time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for k, sub := range s.subs {
+		if sub.Confirmed.IsZero() && now.Sub(sub.Created) > age {
+			delete(s.subs, k)
+			n++
+		}
+	}
+	return n, s.save()
+}
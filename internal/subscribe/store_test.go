@@ -0,0 +1,124 @@
+package subscribe
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func openStore(t *testing.T) *Store {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestRequestWait(t *testing.T) {
+	s := openStore(t)
+	now := time.Date(2014, 12, 1, 12, 0, 0, 0, time.UTC)
+	for _, tt := range []struct {
+		after time.Duration
+		send  bool
+	}{
+		{0, true},
+		{time.Minute, false},
+		{9 * time.Minute, false},
+		{10 * time.Minute, true},
+		{11 * time.Minute, false},
+	} {
+		send, err := s.Request("ada@example.com", All, now.Add(tt.after), 10*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if send != tt.send {
+			t.Errorf("request after %v: send %v, want %v", tt.after, send, tt.send)
+		}
+	}
+	if ok, err := s.Confirm("ADA@example.com", All, now); !ok || err != nil {
+		t.Fatalf("Confirm = %v, %v", ok, err)
+	}
+	if send, _ := s.Request("ada@example.com", All, now.Add(time.Hour), 10*time.Minute); send {
+		t.Error("confirmation mailed to a confirmed subscriber")
+	}
+	if got := s.Confirmed(All); len(got) != 1 || got[0].Email != "ada@example.com" {
+		t.Errorf("Confirmed = %v", got)
+	}
+}
+
+func TestConfirmAfterUnsubscribe(t *testing.T) {
+	s := openStore(t)
+	now := time.Now()
+	s.Request("ada@example.com", Events, now, time.Minute)
+	if err := s.Unsubscribe("ada@example.com", Events); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := s.Confirm("ada@example.com", Events, now)
+	if ok || err != nil {
+		t.Errorf("Confirm after unsubscribe = %v, %v; want false", ok, err)
+	}
+	if got := s.All(); len(got) != 0 {
+		t.Errorf("subscriptions left: %v", got)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	s := openStore(t)
+	now := time.Now()
+	s.Request("old@example.com", All, now.Add(-72*time.Hour), time.Minute)
+	s.Request("kept@example.com", All, now.Add(-72*time.Hour), time.Minute)
+	s.Confirm("kept@example.com", All, now.Add(-71*time.Hour))
+	s.Request("new@example.com", All, now, time.Minute)
+	n, err := s.Expire(now, 48*time.Hour)
+	if err != nil || n != 1 {
+		t.Errorf("Expire = %d, %v; want 1", n, err)
+	}
+	var left []string
+	for _, sub := range s.All() {
+		left = append(left, sub.Email)
+	}
+	if len(left) != 2 || left[0] != "kept@example.com" || left[1] != "new@example.com" {
+		t.Errorf("left %v", left)
+	}
+}
+
+func TestDeleteExport(t *testing.T) {
+	s := openStore(t)
+	created := time.Date(2014, 12, 1, 12, 0, 0, 0, time.UTC)
+	s.Request("Ada@example.com", All, created, time.Minute)
+	s.Request("ada@example.com", SeriesScope("advent-2014"), created, time.Minute)
+	s.Confirm("ada@example.com", All, created.Add(time.Hour))
+	s.Request("bob@example.com", Events, created, time.Minute)
+
+	// The list survives a restart.
+	s, err := Open(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := s.Export(&buf, "ADA@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	want := "email,scope,created,confirmed\n" +
+		"Ada@example.com,all,2014-12-01T12:00:00Z,2014-12-01T13:00:00Z\n" +
+		"ada@example.com,series/advent-2014,2014-12-01T12:00:00Z,\n"
+	if buf.String() != want {
+		t.Errorf("export of ada:\n%s\nwant:\n%s", &buf, want)
+	}
+
+	n, err := s.Delete("ada@EXAMPLE.com")
+	if err != nil || n != 2 {
+		t.Errorf("Delete = %d, %v; want 2", n, err)
+	}
+	s, err = Open(s.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	s.Export(&buf, "")
+	want = "email,scope,created,confirmed\n" +
+		"bob@example.com,events,2014-12-01T12:00:00Z,\n"
+	if buf.String() != want {
+		t.Errorf("export after delete:\n%s\nwant:\n%s", &buf, want)
+	}
+}
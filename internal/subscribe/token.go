@@ -0,0 +1,78 @@
+package subscribe
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Token actions.
+const (
+	ConfirmAction     = "confirm"
+	UnsubscribeAction = "unsubscribe"
+)
+
+// ErrBadToken is returned for tokens that are malformed, forged, for
+// another action, or expired.
+var ErrBadToken = errors.New("subscribe: invalid or expired link")
+
+// Signer makes and checks the tokens of confirmation and unsubscribe
+// links.
+type Signer struct {
+	Key []byte
+}
+
+// Token returns a token allowing action on the subscription of email to
+// scope until expires, or for ever if expires is zero.
+func (s Signer) Token(action, email, scope string, expires time.Time) string {
+	var exp int64
+	if !expires.IsZero() {
+		exp = expires.Unix()
+	}
+	payload := strings.Join([]string{action, email, scope, strconv.FormatInt(exp, 10)}, "\n")
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(payload)) + "." + enc.EncodeToString(s.mac(payload))
+}
+
+func (s Signer) mac(payload string) []byte {
+	m := hmac.New(sha256.New, s.Key)
+	m.Write([]byte(payload))
+	return m.Sum(nil)
+}
+
+// Verify checks a token for action and returns the address and scope it
+// was made for.
+func (s Signer) Verify(action, token string, now time.Time) (email, scope string, err error) {
+	p, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", "", ErrBadToken
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return "", "", ErrBadToken
+	}
+	mac, err := enc.DecodeString(sig)
+	if err != nil || !hmac.Equal(mac, s.mac(string(payload))) {
+		return "", "", ErrBadToken
+	}
+	f := strings.Split(string(payload), "\n")
+	if len(f) != 4 || f[0] != action {
+		return "", "", ErrBadToken
+	}
+	exp, err := strconv.ParseInt(f[3], 10, 64)
+	if err != nil || exp != 0 && now.Unix() > exp {
+		return "", "", ErrBadToken
+	}
+	return f[1], f[2], nil
+}
+
+// UnsubscribeURL returns the one-click unsubscribe link of a
+// subscription on the site at base.
+func (s Signer) UnsubscribeURL(base, email, scope string) string {
+	return strings.TrimSuffix(base, "/") + Prefix + "unsubscribe?t=" + s.Token(UnsubscribeAction, email, scope, time.Time{})
+}
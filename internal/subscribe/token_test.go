@@ -0,0 +1,52 @@
+package subscribe
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	s := Signer{Key: []byte("test key")}
+	now := time.Now()
+	tok := s.Token(ConfirmAction, "ada@example.com", "series/advent-2014", now.Add(time.Hour))
+	email, scope, err := s.Verify(ConfirmAction, tok, now)
+	if err != nil || email != "ada@example.com" || scope != "series/advent-2014" {
+		t.Fatalf("Verify = %q, %q, %v", email, scope, err)
+	}
+
+	// A payload naming another address, signed with the original MAC.
+	p, sig, _ := strings.Cut(tok, ".")
+	payload, _ := base64.RawURLEncoding.DecodeString(p)
+	other := strings.Replace(string(payload), "ada@", "bob@", 1)
+	forged := base64.RawURLEncoding.EncodeToString([]byte(other)) + "." + sig
+
+	bad := []struct {
+		name, action, token string
+		now                 time.Time
+	}{
+		{"empty", ConfirmAction, "", now},
+		{"no signature", ConfirmAction, p, now},
+		{"garbled", ConfirmAction, "!!." + sig, now},
+		{"forged address", ConfirmAction, forged, now},
+		{"other key", ConfirmAction, Signer{Key: []byte("other key")}.Token(ConfirmAction, "ada@example.com", "all", now.Add(time.Hour)), now},
+		{"expired", ConfirmAction, tok, now.Add(time.Hour + time.Second)},
+		{"wrong action", UnsubscribeAction, tok, now},
+	}
+	for _, tt := range bad {
+		if _, _, err := s.Verify(tt.action, tt.token, tt.now); err != ErrBadToken {
+			t.Errorf("%s: Verify error %v, want ErrBadToken", tt.name, err)
+		}
+	}
+
+	// Unsubscribe links do not expire.
+	forever := s.Token(UnsubscribeAction, "ada@example.com", All, time.Time{})
+	if _, _, err := s.Verify(UnsubscribeAction, forever, now.AddDate(10, 0, 0)); err != nil {
+		t.Errorf("token without expiry: %v", err)
+	}
+	u := s.UnsubscribeURL("https://blog.gopheracademy.com/", "ada@example.com", All)
+	if want := "https://blog.gopheracademy.com/subscribe/unsubscribe?t=" + forever; u != want {
+		t.Errorf("UnsubscribeURL = %s, want %s", u, want)
+	}
+}
@@ -0,0 +1,137 @@
+// Package translations matches translated articles with their originals
+// and tells which translations are stale.
+//
+// An original and its translations share a translationKey in front
+// matter; each translation also names its language, one of the
+// languages of sites.toml, and records the hash of the original it was
+// translated from:
+//
+//	translationKey = "go-awesome"
+//	language = "ja"
+//	originalHash = "3f2a9c0d81b4"
+//
+// The original names no language: it is in the default language. When
+// the original's text changes its hash no longer matches, and the
+// translation is stale until it is updated and stamped again.
+package translations
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// Front matter keys.
+const (
+	KeyParam      = "translationKey"
+	LanguageParam = "language"
+	HashParam     = "originalHash"
+)
+
+// Hash returns the hash of the article's text that translations of it
+// record. Front matter is left out, so retagging the original does not
+// make its translations stale.
+func Hash(a *content.Article) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(string(a.Body()))))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Translation is a translated article.
+type Translation struct {
+	Article  *content.Article
+	Language string
+	// Stale is set if the original changed since the translation was
+	// stamped, or it never was.
+	Stale bool
+}
+
+// Group is an original and its translations, by language.
+type Group struct {
+	Key          string
+	Original     *content.Article
+	Translations []Translation
+}
+
+// Problem is a translation that cannot be matched up.
+type Problem struct {
+	File    string
+	Rule    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.File, p.Rule, p.Message)
+}
+
+// Groups returns the articles with translations, or with a
+// translationKey, by key, and the problems in their front matter.
+// languages are the language codes of sites.toml, and def the default.
+func Groups(lib *content.Library, def string, languages map[string]bool) ([]Group, []Problem) {
+	var problems []Problem
+	report := func(a *content.Article, rule, format string, args ...any) {
+		problems = append(problems, Problem{File: a.File, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	byKey := make(map[string]*Group)
+	var keys []string
+	var translations []*content.Article
+	for _, a := range lib.Articles {
+		key, lang := a.Meta.String(KeyParam), a.Meta.String(LanguageParam)
+		switch {
+		case key == "" && lang == "":
+			continue
+		case key == "":
+			report(a, "key", "%s without %s", LanguageParam, KeyParam)
+			continue
+		case lang != "" && !languages[lang]:
+			report(a, "language", "%q is not a language of %s", lang, "sites.toml")
+			continue
+		}
+		if byKey[key] == nil {
+			byKey[key] = &Group{Key: key}
+			keys = append(keys, key)
+		}
+		if lang != "" && lang != def {
+			translations = append(translations, a)
+			continue
+		}
+		g := byKey[key]
+		if g.Original != nil {
+			report(a, "original", "%s is also the original of %q", g.Original.File, key)
+			continue
+		}
+		g.Original = a
+	}
+	for _, a := range translations {
+		g := byKey[a.Meta.String(KeyParam)]
+		lang := a.Meta.String(LanguageParam)
+		if g.Original == nil {
+			report(a, "original", "no article in the default language has %s %q", KeyParam, g.Key)
+			continue
+		}
+		if i := sort.Search(len(g.Translations), func(i int) bool { return g.Translations[i].Language >= lang }); i < len(g.Translations) && g.Translations[i].Language == lang {
+			report(a, "language", "%s is also the %s translation", g.Translations[i].Article.File, lang)
+			continue
+		}
+		t := Translation{Article: a, Language: lang, Stale: a.Meta.String(HashParam) != Hash(g.Original)}
+		g.Translations = append(g.Translations, t)
+		sort.Slice(g.Translations, func(i, j int) bool { return g.Translations[i].Language < g.Translations[j].Language })
+	}
+	sort.Strings(keys)
+	var out []Group
+	for _, key := range keys {
+		if g := byKey[key]; g.Original != nil {
+			out = append(out, *g)
+		}
+	}
+	return out, problems
+}
+
+// Stamp records in the translation the hash of the original as it is
+// now, marking the translation up to date.
+func Stamp(g Group, t Translation) error {
+	return t.Article.Meta.Set(HashParam, Hash(g.Original))
+}
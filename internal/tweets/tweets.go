@@ -0,0 +1,237 @@
+// Package tweets replaces embedded tweets in articles with static quote
+// cards.
+//
+// Twitter's embed code is a blockquote holding the tweet, followed by a
+// script from platform.twitter.com that swaps it for a widget. The
+// script tracks readers and no longer renders reliably, so the site
+// renders tweets itself from data/tweets.toml, a cache of the text,
+// author and date of every tweet an article quotes. The cache is filled
+// from the embed blockquotes, which carry the tweet as it was when the
+// article was written, and can be edited by hand.
+package tweets
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/content"
+)
+
+// ScriptHost serves the embed script.
+const ScriptHost = "platform.twitter.com"
+
+// Tweet is a cached tweet.
+type Tweet struct {
+	ID     string `toml:"id"`
+	Name   string `toml:"name"`
+	Handle string `toml:"handle"`
+	// Date is the day the tweet was posted, as YYYY-MM-DD.
+	Date string `toml:"date"`
+	// Text is the tweet as HTML, with its links.
+	Text string `toml:"text"`
+}
+
+// URL returns the permalink of the tweet.
+func (t Tweet) URL() string {
+	return "https://twitter.com/" + t.Handle + "/status/" + t.ID
+}
+
+// Cache is the set of cached tweets.
+type Cache struct {
+	Tweets []Tweet `toml:"tweet"`
+}
+
+// Load reads the cache from file. A missing file is an empty cache.
+func Load(file string) (*Cache, error) {
+	c := &Cache{}
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := toml.Decode(string(b), c); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return c, nil
+}
+
+// Save writes the cache to file, sorted by id.
+func (c *Cache) Save(file string) error {
+	sort.Slice(c.Tweets, func(i, j int) bool {
+		a, b := c.Tweets[i].ID, c.Tweets[j].ID
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	var buf bytes.Buffer
+	buf.WriteString("# Tweets quoted in articles, rendered as static cards by cmd/tweetcards.\n\n")
+	if err := toml.NewEncoder(&buf).Encode(c); err != nil {
+		return err
+	}
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// Lookup returns the tweet with the given id.
+func (c *Cache) Lookup(id string) (Tweet, bool) {
+	for _, t := range c.Tweets {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Tweet{}, false
+}
+
+// Add caches t unless a tweet with its id is cached already, and reports
+// whether it did.
+func (c *Cache) Add(t Tweet) bool {
+	if _, ok := c.Lookup(t.ID); ok {
+		return false
+	}
+	c.Tweets = append(c.Tweets, t)
+	return true
+}
+
+// Embed is a tweet embedded in an article body.
+type Embed struct {
+	// Start and End are the offsets of the blockquote in the body,
+	// including the embed script that follows it, if any.
+	Start, End int
+	// Tweet is read from the blockquote.
+	Tweet Tweet
+}
+
+var (
+	embedRE  = regexp.MustCompile(`(?s)<blockquote[^>]*class="[^"]*\btwitter-tweet\b[^"]*"[^>]*>.*?</blockquote>`)
+	scriptRE = regexp.MustCompile(`^\s*<script[^>]*platform\.twitter\.com/widgets\.js[^>]*>\s*</script>`)
+	statusRE = regexp.MustCompile(`twitter\.com/([A-Za-z0-9_]+)/status(?:es)?/([0-9]+)`)
+	bylineRE = regexp.MustCompile(`^\s*(?:—|&mdash;)?\s*(.*?)\s*\(@([A-Za-z0-9_]+)\)\s*$`)
+)
+
+// Find returns the tweets embedded in a markdown body, outside code.
+func Find(body []byte) ([]Embed, error) {
+	masked := content.MaskCode(body)
+	var out []Embed
+	for _, loc := range embedRE.FindAllIndex(masked, -1) {
+		e := Embed{Start: loc[0], End: loc[1]}
+		if m := scriptRE.FindIndex(masked[e.End:]); m != nil {
+			e.End += m[1]
+		}
+		t, err := parse(body[loc[0]:loc[1]])
+		if err != nil {
+			line := 1 + bytes.Count(body[:loc[0]], []byte("\n"))
+			return nil, fmt.Errorf("tweet embed at body line %d: %v", line, err)
+		}
+		e.Tweet = t
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+// parse reads a tweet from its embed blockquote: the text is the
+// paragraph, followed by "&mdash; Name (@handle)" and a link to the
+// status whose text is the date.
+func parse(b []byte) (Tweet, error) {
+	nodes, err := html.ParseFragment(bytes.NewReader(b), &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div})
+	if err != nil || len(nodes) == 0 {
+		return Tweet{}, fmt.Errorf("cannot parse blockquote")
+	}
+	var t Tweet
+	var byline strings.Builder
+	for n := nodes[0].FirstChild; n != nil; n = n.NextSibling {
+		switch {
+		case n.Type == html.ElementNode && n.DataAtom == atom.P:
+			var buf bytes.Buffer
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if err := html.Render(&buf, c); err != nil {
+					return Tweet{}, err
+				}
+			}
+			t.Text = strings.TrimSpace(buf.String())
+		case n.Type == html.TextNode:
+			byline.WriteString(n.Data)
+		case n.Type == html.ElementNode && n.DataAtom == atom.A:
+			for _, a := range n.Attr {
+				if m := statusRE.FindStringSubmatch(a.Val); a.Key == "href" && m != nil {
+					t.ID = m[2]
+				}
+			}
+			if n.FirstChild != nil {
+				if d, err := time.Parse("January 2, 2006", strings.TrimSpace(n.FirstChild.Data)); err == nil {
+					t.Date = d.Format("2006-01-02")
+				}
+			}
+		}
+	}
+	if m := bylineRE.FindStringSubmatch(byline.String()); m != nil {
+		t.Name, t.Handle = m[1], m[2]
+	}
+	if t.ID == "" || t.Handle == "" {
+		return Tweet{}, fmt.Errorf("no status link or author")
+	}
+	return t, nil
+}
+
+var card = template.Must(template.New("card").Funcs(template.FuncMap{
+	"day": func(s string) string {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			return s
+		}
+		return d.Format("January 2, 2006")
+	},
+}).Parse(`<blockquote class="tweet-card" cite="{{.URL}}">
+<p>{{.Text}}</p>
+<footer>&mdash; {{.Name}} (<a href="https://twitter.com/{{.Handle}}">@{{.Handle}}</a>) <a href="{{.URL}}"><time datetime="{{.Date}}">{{day .Date}}</time></a></footer>
+</blockquote>`))
+
+// Card returns the static quote card of t.
+func Card(t Tweet) ([]byte, error) {
+	var buf bytes.Buffer
+	err := card.Execute(&buf, struct {
+		Tweet
+		Text template.HTML
+	}{t, template.HTML(t.Text)})
+	return buf.Bytes(), err
+}
+
+// Render replaces the tweet embeds of a markdown body with cards of the
+// cached tweets. It returns the ids of embedded tweets missing from the
+// cache, whose embeds are left as they are.
+func Render(body []byte, c *Cache) ([]byte, []string, error) {
+	embeds, err := Find(body)
+	if err != nil {
+		return nil, nil, err
+	}
+	var buf bytes.Buffer
+	var missing []string
+	off := 0
+	for _, e := range embeds {
+		t, ok := c.Lookup(e.Tweet.ID)
+		if !ok {
+			missing = append(missing, e.Tweet.ID)
+			continue
+		}
+		b, err := Card(t)
+		if err != nil {
+			return nil, nil, err
+		}
+		buf.Write(body[off:e.Start])
+		buf.Write(b)
+		off = e.End
+	}
+	buf.Write(body[off:])
+	return buf.Bytes(), missing, nil
+}
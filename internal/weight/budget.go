@@ -0,0 +1,71 @@
+package weight
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Budget caps the cost of a page. Zero fields are not checked. Sizes are
+// in bytes and cover local resources only.
+type Budget struct {
+	Total    int64 `toml:"total"`
+	HTML     int64 `toml:"html"`
+	CSS      int64 `toml:"css"`
+	JS       int64 `toml:"js"`
+	Image    int64 `toml:"image"`
+	Font     int64 `toml:"font"`
+	Requests int   `toml:"requests"`
+	Blocking int   `toml:"blocking"`
+	External int   `toml:"external"`
+}
+
+// Budgets holds a Budget per page type, with "default" applying to
+// types that have none of their own.
+type Budgets map[string]Budget
+
+// LoadBudgets reads budgets from a TOML file with a table per page type.
+func LoadBudgets(file string) (Budgets, error) {
+	b := make(Budgets)
+	if _, err := toml.DecodeFile(file, &b); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return b, nil
+}
+
+// For returns the budget for a page type.
+func (b Budgets) For(pageType string) Budget {
+	if bud, ok := b[pageType]; ok {
+		return bud
+	}
+	return b["default"]
+}
+
+// Check returns a message for each limit r exceeds.
+func (b Budget) Check(r *Report) []string {
+	var out []string
+	size := func(name string, got, max int64) {
+		if max > 0 && got > max {
+			out = append(out, fmt.Sprintf("%s is %s, budget %s", name, kb(got), kb(max)))
+		}
+	}
+	count := func(name string, got, max int) {
+		if max > 0 && got > max {
+			out = append(out, fmt.Sprintf("%d %s, budget %d", got, name, max))
+		}
+	}
+	size("total", r.Bytes(""), b.Total)
+	size("html", r.Bytes(HTML), b.HTML)
+	size("css", r.Bytes(CSS), b.CSS)
+	size("js", r.Bytes(JS), b.JS)
+	size("image", r.Bytes(Image), b.Image)
+	size("font", r.Bytes(Font), b.Font)
+	count("requests", r.Requests(), b.Requests)
+	count("render-blocking resources", len(r.Blocking()), b.Blocking)
+	count("external resources", len(r.External()), b.External)
+	return out
+}
+
+func kb(n int64) string {
+	return fmt.Sprintf("%.1f KB", float64(n)/1024)
+}
@@ -0,0 +1,345 @@
+// Package weight measures what a rendered page costs to load: the bytes
+// of every resource it references, the number of requests and the
+// resources that block rendering.
+//
+// Local resources are measured on disk. External ones cannot be, so they
+// are counted as requests and listed, including scripts that inline
+// loaders such as the analytics and widget snippets inject.
+package weight
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+
+	"github.com/gopheracademy/gopheracademy-web/internal/site"
+)
+
+// Kind is a resource category.
+type Kind string
+
+const (
+	HTML  Kind = "html"
+	CSS   Kind = "css"
+	JS    Kind = "js"
+	Image Kind = "image"
+	Font  Kind = "font"
+)
+
+// Kinds lists the categories in report order.
+var Kinds = []Kind{HTML, CSS, JS, Image, Font}
+
+// Resource is one file a page loads.
+type Resource struct {
+	URL  string
+	Kind Kind
+	// Size is the size on disk, or -1 for external resources.
+	Size     int64
+	External bool
+	// Blocking is set for stylesheets and synchronous scripts in the
+	// document head, which delay the first render.
+	Blocking bool
+}
+
+// Report is the weight of one page.
+type Report struct {
+	Page site.Page
+	// Type is the page type budgets are chosen by: "home", "article",
+	// "section" or "taxonomy".
+	Type      string
+	Resources []Resource
+	// Duplicates lists libraries loaded more than once, such as a
+	// minified and an unminified copy of the same script.
+	Duplicates []string
+}
+
+// Bytes returns the total size of the local resources of kind k, or of
+// all kinds if k is empty.
+func (r *Report) Bytes(k Kind) int64 {
+	var n int64
+	for _, res := range r.Resources {
+		if res.Size > 0 && (k == "" || res.Kind == k) {
+			n += res.Size
+		}
+	}
+	return n
+}
+
+// Requests returns the number of resources loaded, the page included.
+func (r *Report) Requests() int {
+	return len(r.Resources)
+}
+
+// Blocking returns the render-blocking resources.
+func (r *Report) Blocking() []Resource {
+	var out []Resource
+	for _, res := range r.Resources {
+		if res.Blocking {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// External returns the resources loaded from other hosts.
+func (r *Report) External() []Resource {
+	var out []Resource
+	for _, res := range r.Resources {
+		if res.External {
+			out = append(out, res)
+		}
+	}
+	return out
+}
+
+// PageType classifies a page for budgeting.
+func PageType(p site.Page) string {
+	switch {
+	case p.URL == "/":
+		return "home"
+	case p.Article:
+		return "article"
+	}
+	first := strings.SplitN(strings.TrimPrefix(p.URL, "/"), "/", 2)[0]
+	for _, t := range site.Taxonomies {
+		if first == t {
+			return "taxonomy"
+		}
+	}
+	return "section"
+}
+
+// Analyzer measures pages of the site published in Dir.
+type Analyzer struct {
+	Dir string
+	// fonts caches the fonts referenced by each local stylesheet.
+	fonts map[string][]string
+}
+
+// Analyze returns the weight of page p.
+func (a *Analyzer) Analyze(p site.Page) (*Report, error) {
+	name := filepath.Join(a.Dir, filepath.FromSlash(p.File))
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{Page: p, Type: PageType(p)}
+	r.Resources = append(r.Resources, Resource{URL: p.URL, Kind: HTML, Size: int64(len(b))})
+
+	seen := make(map[string]bool)
+	var add func(ref string, k Kind, blocking bool)
+	add = func(ref string, k Kind, blocking bool) {
+		u, ok := resolve(p.URL, ref)
+		if !ok || seen[u] {
+			return
+		}
+		seen[u] = true
+		res := Resource{URL: u, Kind: k, Size: -1, Blocking: blocking}
+		if isExternal(u) {
+			res.External = true
+		} else if fi, err := os.Stat(filepath.Join(a.Dir, filepath.FromSlash(u))); err == nil {
+			res.Size = fi.Size()
+		}
+		r.Resources = append(r.Resources, res)
+		if k == CSS && !res.External {
+			for _, f := range a.stylesheetFonts(u) {
+				add(f, Font, false)
+			}
+		}
+	}
+
+	z := html.NewTokenizer(bytes.NewReader(b))
+	inHead, inScript := false, false
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			if z.Err() != io.EOF {
+				return nil, z.Err()
+			}
+			break
+		}
+		tok := z.Token()
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
+			attr := attrMap(tok.Attr)
+			switch tok.Data {
+			case "head":
+				inHead = true
+			case "body":
+				inHead = false
+			case "link":
+				rel := strings.ToLower(attr["rel"])
+				switch {
+				case rel == "stylesheet":
+					media := attr["media"]
+					add(attr["href"], CSS, inHead && (media == "" || media == "all" || media == "screen"))
+				case strings.Contains(rel, "icon"):
+					add(attr["href"], Image, false)
+				}
+			case "script":
+				inScript = true
+				if src, ok := attr["src"]; ok {
+					_, async := attr["async"]
+					_, deferred := attr["defer"]
+					add(src, JS, inHead && !async && !deferred)
+				}
+			case "img":
+				add(attr["src"], Image, false)
+			case "iframe":
+				add(attr["src"], HTML, false)
+			}
+		case html.EndTagToken:
+			switch tok.Data {
+			case "head":
+				inHead = false
+			case "script":
+				inScript = false
+			}
+		case html.TextToken:
+			if inScript {
+				for _, m := range injectedRE.FindAllStringSubmatch(tok.Data, -1) {
+					add(m[1], JS, false)
+				}
+				if strings.Contains(tok.Data, ".disqus.com/embed.js") {
+					add("//disqus.com/embed.js", JS, false)
+				}
+			}
+		}
+	}
+	r.Duplicates = duplicates(r.Resources)
+	return r, nil
+}
+
+// injectedRE finds script URLs in inline loader snippets, such as
+// '//www.google-analytics.com/analytics.js' or the twitter widget
+// loader's '://platform.twitter.com/widgets.js'.
+var injectedRE = regexp.MustCompile(`['"](?:https?:)?:?(//[a-zA-Z0-9.-]+\.[a-z]+/[^'"\s]*\.js)['"]`)
+
+// fontURLRE finds the url() references in a stylesheet.
+var fontURLRE = regexp.MustCompile(`url\(\s*['"]?([^'")]+?)['"]?\s*\)`)
+
+// stylesheetFonts returns the font files a local stylesheet makes the
+// browser download: for each @font-face rule, the first source in a
+// format every current browser reads.
+func (a *Analyzer) stylesheetFonts(u string) []string {
+	if fonts, ok := a.fonts[u]; ok {
+		return fonts
+	}
+	if a.fonts == nil {
+		a.fonts = make(map[string][]string)
+	}
+	b, err := os.ReadFile(filepath.Join(a.Dir, filepath.FromSlash(u)))
+	if err != nil {
+		return nil
+	}
+	var fonts []string
+	css := string(b)
+	for {
+		i := strings.Index(css, "@font-face")
+		if i < 0 {
+			break
+		}
+		css = css[i:]
+		end := strings.Index(css, "}")
+		if end < 0 {
+			break
+		}
+		rule := css[:end]
+		css = css[end:]
+		var urls []string
+		for _, m := range fontURLRE.FindAllStringSubmatch(rule, -1) {
+			if ref, ok := resolve(u, m[1]); ok {
+				urls = append(urls, ref)
+			}
+		}
+		if f := preferredFont(urls); f != "" {
+			fonts = append(fonts, f)
+		}
+	}
+	a.fonts[u] = fonts
+	return fonts
+}
+
+func preferredFont(urls []string) string {
+	for _, ext := range []string{".woff2", ".woff", ".ttf", ".otf"} {
+		for _, u := range urls {
+			if path.Ext(strings.SplitN(u, "?", 2)[0]) == ext {
+				return u
+			}
+		}
+	}
+	if len(urls) > 0 {
+		return urls[0]
+	}
+	return ""
+}
+
+// duplicates finds scripts and stylesheets loaded under two names that
+// differ only by a ".min" suffix or a directory.
+func duplicates(res []Resource) []string {
+	byName := make(map[string][]string)
+	for _, r := range res {
+		if r.Kind != JS && r.Kind != CSS {
+			continue
+		}
+		base := path.Base(strings.SplitN(r.URL, "?", 2)[0])
+		base = strings.Replace(base, ".min.", ".", 1)
+		byName[base] = append(byName[base], r.URL)
+	}
+	var out []string
+	for _, urls := range byName {
+		if len(urls) > 1 {
+			out = append(out, strings.Join(urls, ", "))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func attrMap(attrs []html.Attribute) map[string]string {
+	m := make(map[string]string, len(attrs))
+	for _, a := range attrs {
+		m[a.Key] = a.Val
+	}
+	return m
+}
+
+// resolve returns ref relative to the page or stylesheet at base, as a
+// site path for local resources or an absolute URL for external ones.
+// Data URIs and empty references are skipped.
+func resolve(base, ref string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "data:") || strings.HasPrefix(ref, "#") {
+		return "", false
+	}
+	if strings.HasPrefix(ref, "//") {
+		return "http:" + ref, true
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" {
+		return u.String(), u.Scheme == "http" || u.Scheme == "https"
+	}
+	if !strings.HasPrefix(u.Path, "/") {
+		dir := base
+		if !strings.HasSuffix(dir, "/") {
+			dir = path.Dir(dir)
+		}
+		u.Path = path.Join(dir, u.Path)
+	}
+	return path.Clean(u.Path), true
+}
+
+func isExternal(u string) bool {
+	return strings.HasPrefix(u, "http:") || strings.HasPrefix(u, "https:")
+}
@@ -47,4 +47,33 @@ $(document).ready(function () {
         return false;
     });
 
-});
\ No newline at end of file
+});
+
+// Media facades load the player only when clicked.
+$(function() {
+    $('.media-facade').on('click', 'a', function(e) {
+        var facade = $(this).closest('.media-facade');
+        var player = facade.data('player');
+        if (!player) {
+            return;
+        }
+        e.preventDefault();
+        facade.html($('<iframe allowfullscreen frameborder="0">').attr('src', player));
+    });
+});
+
+
+// Links the web archive knows to be dead get a link to the archived copy.
+$(function() {
+    if (!$('.post a[href^="http"]').length) {
+        return;
+    }
+    $.getJSON('/archive/dead.json', function(dead) {
+        $('.post a[href^="http"]').each(function() {
+            var copy = dead[this.href] || dead[$(this).attr('href')];
+            if (copy) {
+                $(this).after(' <a class="archived-copy" href="' + copy + '" title="The page is gone; view the copy archived when this was published">(archived copy)</a>');
+            }
+        });
+    });
+});
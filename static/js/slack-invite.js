@@ -0,0 +1,50 @@
+// Proof of work for the Gophers Slack invite form: find a counter such
+// that SHA-256("<challenge>:<counter>") starts with data-bits zero bits,
+// then send the form. See internal/slackinvite.
+(function() {
+  var form = document.getElementById('slack-invite');
+  if (!form || !window.crypto || !window.crypto.subtle) {
+    return;
+  }
+  var bits = parseInt(form.getAttribute('data-bits'), 10);
+  var status = form.querySelector('.slack-invite-status');
+
+  function zeros(buf) {
+    var bytes = new Uint8Array(buf), n = 0;
+    for (var i = 0; i < bytes.length; i++) {
+      if (bytes[i] === 0) {
+        n += 8;
+        continue;
+      }
+      for (var b = bytes[i]; (b & 0x80) === 0; b <<= 1) {
+        n++;
+      }
+      break;
+    }
+    return n;
+  }
+
+  function search(challenge, counter) {
+    var data = new TextEncoder().encode(challenge + ':' + counter);
+    return window.crypto.subtle.digest('SHA-256', data).then(function(sum) {
+      return zeros(sum) >= bits ? counter : search(challenge, counter + 1);
+    });
+  }
+
+  var solving = false;
+  form.addEventListener('submit', function(e) {
+    if (form.elements.counter.value !== '') {
+      return;
+    }
+    e.preventDefault();
+    if (solving) {
+      return;
+    }
+    solving = true;
+    status.textContent = 'Checking your browser…';
+    search(form.elements.challenge.value, 0).then(function(counter) {
+      form.elements.counter.value = counter;
+      form.submit();
+    });
+  });
+})();